@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"employee-management/internal/analyzer"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	opts := analyzer.DefaultOptions()
+
+	output := flag.String("o", "", "файл отчета (по умолчанию stdout)")
+	format := flag.String("format", "", "формат отчета: md или html (по умолчанию по расширению файла отчета)")
+	include := flag.String("include", "", "регулярное выражение для отбора метрик")
+	exclude := flag.String("exclude", opts.Exclude.String(), "регулярное выражение для исключения метрик")
+	timeline := flag.String("timeline", opts.TimelineMetric, "счетчик для таблицы динамики")
+	quantiles := flag.String("quantiles", "0.5,0.9,0.95,0.99", "оцениваемые квантили через запятую")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Использование: %s [флаги] metrics/metrics.log\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		return fmt.Errorf("нужно указать один файл метрик")
+	}
+	source := flag.Arg(0)
+
+	var err error
+	if opts.Include, err = compileOptional(*include); err != nil {
+		return fmt.Errorf("неверный -include: %w", err)
+	}
+	if opts.Exclude, err = compileOptional(*exclude); err != nil {
+		return fmt.Errorf("неверный -exclude: %w", err)
+	}
+	opts.TimelineMetric = *timeline
+	if opts.Quantiles, err = parseQuantiles(*quantiles); err != nil {
+		return err
+	}
+
+	reportFormat := *format
+	if reportFormat == "" {
+		reportFormat = "md"
+		if strings.HasSuffix(*output, ".html") || strings.HasSuffix(*output, ".htm") {
+			reportFormat = "html"
+		}
+	}
+
+	file, err := analyzer.ParseFile(source)
+	if err != nil {
+		return err
+	}
+	report := analyzer.Analyze(source, file, opts)
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("не удалось создать файл отчета: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	switch reportFormat {
+	case "md", "markdown":
+		err = analyzer.RenderMarkdown(w, report)
+	case "html":
+		err = analyzer.RenderHTML(w, report)
+	default:
+		return fmt.Errorf("неизвестный формат отчета: %s", reportFormat)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка формирования отчета: %w", err)
+	}
+	return w.Flush()
+}
+
+func compileOptional(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
+
+func parseQuantiles(s string) ([]float64, error) {
+	var quantiles []float64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		q, err := strconv.ParseFloat(part, 64)
+		if err != nil || q < 0 || q > 1 {
+			return nil, fmt.Errorf("неверный квантиль: %s", part)
+		}
+		quantiles = append(quantiles, q)
+	}
+	return quantiles, nil
+}
@@ -0,0 +1,89 @@
+// Command migrate manages the schema of the sql storage driver:
+//
+//	migrate up         apply all pending migrations
+//	migrate down [N]   roll back the last N migrations (default 1)
+//	migrate to V       migrate up or down to version V
+//	migrate version    print the current and latest version
+//
+// The database is taken from the storage.sql section of CONFIG_FILE.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"employee-management/internal/config"
+	"employee-management/internal/repository"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("использование: migrate up | down [N] | to V | version")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки конфигурации: %w", err)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	ctx := context.Background()
+	if err := os.MkdirAll(cfg.Storage.Dir, 0755); err != nil {
+		return fmt.Errorf("не удалось создать каталог хранилища: %w", err)
+	}
+	db, err := repository.OpenSQLDB(ctx, cfg.Storage.SQL.Driver, cfg.Storage.SQL.DSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("неверное число миграций: %s", args[1])
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("не указана версия")
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil || version < 0 {
+			return fmt.Errorf("неверная версия: %s", args[1])
+		}
+		err = migrator.To(ctx, version)
+	case "version":
+	default:
+		return fmt.Errorf("неизвестная команда: %s", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Версия схемы: %d (последняя %d)\n", version, migrator.Latest())
+	return nil
+}
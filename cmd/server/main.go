@@ -11,6 +11,7 @@ import (
 	"syscall"
 	"time"
 
+	"employee-management/internal/config"
 	"employee-management/internal/handler"
 	"employee-management/internal/logger"
 	"employee-management/internal/repository"
@@ -21,12 +22,10 @@ import (
 //go:embed static/*
 var staticFiles embed.FS
 
-const (
-	LogFile     = "app.log"
-	MetricsFile = "metrics.log"
-	LogDir      = "logs"
-	MetricsDir  = "metrics"
-	ServerPort  = ":8080"
+// Set at link time with -ldflags "-X main.version=... -X main.commit=..."
+var (
+	version = ""
+	commit  = ""
 )
 
 func main() {
@@ -37,36 +36,228 @@ func main() {
 }
 
 func run() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки конфигурации: %w", err)
+	}
+
+	rotation := logger.RotationOptions{
+		MaxSizeMB:  cfg.Rotation.MaxSizeMB,
+		MaxBackups: cfg.Rotation.MaxBackups,
+		MaxAgeDays: cfg.Rotation.MaxAgeDays,
+	}
+
 	// Setup logger
-	logFile, err := logger.Setup(LogDir, LogFile)
+	logFile, err := logger.Setup(cfg.Log.Dir, cfg.Log.File, rotation, cfg.Log.SpanEvents)
 	if err != nil {
 		return fmt.Errorf("ошибка настройки логгера: %w", err)
 	}
 	defer logFile.Close()
 
-	slog.Info("Логгер инициализирован", "log_file", LogDir+"/"+LogFile)
+	slog.Info("Логгер инициализирован", "log_file", logFile.Path())
+
+	build := telemetry.NewBuildInfo(version, commit)
+	metrics := telemetry.NewMetrics(build)
+	metrics.LimitCardinality(telemetry.CardinalityLimits{
+		Default:   cfg.Cardinality.DefaultLimit,
+		PerMetric: cfg.Cardinality.Limits,
+	})
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
 
 	// Setup metrics writer
-	metricsFile, err := telemetry.SetupMetricsWriter(MetricsDir, MetricsFile)
+	metricsFormat, err := telemetry.ParseMetricsFormat(cfg.Metrics.Format)
+	if err != nil {
+		return fmt.Errorf("ошибка настройки записи метрик: %w", err)
+	}
+	metricsFile, err := telemetry.SetupMetricsWriter(metrics.Registry, cfg.Metrics.Dir, cfg.Metrics.File, metricsFormat, rotation)
 	if err != nil {
 		slog.Error("Ошибка настройки записи метрик", "error", err)
 	} else {
 		defer metricsFile.Close()
-		slog.Info("Запись метрик в файл инициализирована", "metrics_file", MetricsDir+"/"+MetricsFile)
-		go telemetry.StartMetricsWriter()
+		slog.Info("Запись метрик в файл инициализирована",
+			"metrics_file", metricsFile.Path(),
+			"format", metricsFormat,
+			"interval", cfg.Metrics.Interval.Std().String(),
+		)
+		go telemetry.StartMetricsWriter(ctx, cfg.Metrics.Interval.Std())
+	}
+
+	// Setup tracing
+	if err := telemetry.SetupPropagators(cfg.Tracing.Propagators); err != nil {
+		return fmt.Errorf("ошибка настройки трассировки: %w", err)
+	}
+	var traceStore *telemetry.TraceStore
+	if cfg.Tracing.Enabled {
+		if cfg.Tracing.Viewer.Enabled {
+			traceStore = telemetry.NewTraceStore(cfg.Tracing.Viewer.MaxTraces, cfg.Tracing.Viewer.MaxSpansPerTrace)
+		}
+		sampling := cfg.Tracing.Sampling
+		sampler, err := telemetry.NewHeadSampler(sampling.Head, sampling.Ratio)
+		if err != nil {
+			return fmt.Errorf("ошибка настройки трассировки: %w", err)
+		}
+		var tail *telemetry.TailSamplingOptions
+		if sampling.Tail.Enabled {
+			tail = &telemetry.TailSamplingOptions{
+				LatencyThreshold: sampling.Tail.LatencyThreshold.Std(),
+				BaselineRatio:    sampling.Tail.BaselineRatio,
+				MaxTraces:        sampling.Tail.MaxTraces,
+				MaxSpansPerTrace: sampling.Tail.MaxSpansPerTrace,
+				DecisionWait:     sampling.Tail.DecisionWait.Std(),
+				DecidedTTL:       sampling.Tail.DecidedTTL.Std(),
+			}
+		}
+
+		tp, err := telemetry.InitTracer(ctx, telemetry.TracingOptions{
+			ServiceName:    cfg.Tracing.ServiceName,
+			ServiceVersion: build.Version,
+			Exporter:       cfg.Tracing.Exporter,
+			Endpoint:       cfg.Tracing.Endpoint,
+			Insecure:       cfg.Tracing.Insecure,
+			Headers:        cfg.Tracing.Headers,
+			Timeout:        cfg.Tracing.Timeout.Std(),
+			Fallback:       cfg.Tracing.Fallback,
+			FileDir:        cfg.Tracing.Dir,
+			FileName:       cfg.Tracing.File,
+			Rotation:       rotation,
+			Sampler:        sampler,
+			TailSampling:   tail,
+			Metrics:        metrics,
+			Store:          traceStore,
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка настройки трассировки: %w", err)
+		}
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := tp.Shutdown(shutdownCtx); err != nil {
+				slog.Error("Ошибка остановки трассировки", "error", err)
+			}
+		}()
+		slog.Info("Трассировка включена", "exporter", cfg.Tracing.Exporter, "fallback", cfg.Tracing.Fallback)
 	}
 
-	slog.Info("Трассировка отключена - Jaeger не запущен")
-	telemetry.InitMetrics()
+	// Setup StatsD mirror
+	var statsd *telemetry.StatsD
+	if cfg.StatsD.Enabled {
+		statsd, err = telemetry.NewStatsD(cfg.StatsD.Addr, telemetry.StatsDOptions{
+			Flavor:        cfg.StatsD.Flavor,
+			Prefix:        cfg.StatsD.Prefix,
+			Tags:          cfg.StatsD.Tags,
+			SampleRate:    cfg.StatsD.SampleRate,
+			MaxPacketSize: cfg.StatsD.MaxPacketSize,
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка настройки StatsD: %w", err)
+		}
+		metrics.MirrorToStatsD(statsd)
+		go statsd.Run(ctx, cfg.StatsD.FlushInterval.Std())
+		slog.Info("Отправка метрик в StatsD включена", "addr", cfg.StatsD.Addr, "flavor", cfg.StatsD.Flavor)
+	}
+
+	// Setup storage
+	var storage idStore
+	switch cfg.Storage.Driver {
+	case "memory":
+		storage = repository.NewMemoryRepository()
+	case "sql":
+		sqlRepo, closeDB, err := openSQLRepository(ctx, cfg.Storage)
+		if err != nil {
+			return err
+		}
+		defer closeDB()
+		storage = sqlRepo
+		slog.Info("SQL-хранилище открыто", "driver", cfg.Storage.SQL.Driver)
+	case "file":
+		fileRepo, err := repository.OpenFileRepository(repository.FileRepositoryOptions{
+			Dir:              cfg.Storage.Dir,
+			Sync:             cfg.Storage.Sync,
+			SyncInterval:     cfg.Storage.SyncInterval.Std(),
+			SnapshotInterval: cfg.Storage.SnapshotInterval.Std(),
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка открытия хранилища: %w", err)
+		}
+		defer func() {
+			if err := fileRepo.Close(); err != nil {
+				slog.Error("Ошибка закрытия хранилища", "error", err)
+			}
+		}()
+		go fileRepo.Run(ctx)
+		storage = fileRepo
+		slog.Info("Файловое хранилище открыто", "dir", cfg.Storage.Dir, "sync", cfg.Storage.Sync)
+	default:
+		return fmt.Errorf("неизвестный драйвер хранилища: %s", cfg.Storage.Driver)
+	}
+	ids, err := repository.NewIDGenerator(cfg.Storage.IDGenerator, storage)
+	if err != nil {
+		return fmt.Errorf("ошибка настройки идентификаторов сотрудников: %w", err)
+	}
+	storage.SetIDGenerator(ids)
 
 	// Initialize dependencies
-	repo := repository.NewMemoryRepository()
-	svc := service.NewEmployeeService(repo)
-	h := handler.NewHandler(svc, staticFiles)
+	repo := repository.NewInstrumentedRepository(storage, metrics)
+	svc := service.NewEmployeeService(repo, metrics)
+	svc.RefreshMetrics(ctx)
+
+	// Setup metrics history
+	var history *telemetry.HistoryStore
+	if cfg.History.Enabled {
+		history = telemetry.NewHistoryStore(metrics.Registry, cfg.History.Resolution.Std(), cfg.History.Retention.Std())
+		if err := history.Load(cfg.History.SnapshotFile); err != nil {
+			slog.Error("Ошибка загрузки истории метрик", "error", err)
+		}
+		go history.Run(ctx, cfg.History.SnapshotFile, cfg.History.SnapshotInterval.Std())
+	}
+
+	// Setup SLO tracking
+	var sloTracker *telemetry.SLOTracker
+	if len(cfg.SLO.Objectives) > 0 {
+		slos := make([]telemetry.SLO, 0, len(cfg.SLO.Objectives))
+		for _, o := range cfg.SLO.Objectives {
+			slos = append(slos, telemetry.SLO{
+				Name:      o.Name,
+				Type:      o.Type,
+				Method:    o.Method,
+				Path:      o.Path,
+				Objective: o.Objective,
+				Threshold: o.Threshold.Std(),
+				Window:    o.Window.Std(),
+			})
+		}
+		sloTracker, err = telemetry.NewSLOTracker(metrics, slos)
+		if err != nil {
+			return fmt.Errorf("ошибка настройки SLO: %w", err)
+		}
+		go sloTracker.Run(ctx, cfg.SLO.Interval.Std())
+	}
+
+	// Setup push export
+	pushOpts := telemetry.PushOptions{Job: cfg.Push.Job, Grouping: cfg.Push.Grouping}
+	pushExporter := telemetry.NewPushExporter(cfg.Push.Timeout.Std(), telemetry.PushRetry{
+		Attempts: cfg.Push.Retries,
+		Backoff:  cfg.Push.RetryBackoff.Std(),
+	})
+	if cfg.Push.Pushgateway.Enabled {
+		pushExporter.Add(telemetry.NewPushgatewayPusher(metrics.Registry, cfg.Push.Pushgateway.URL, pushOpts))
+		slog.Info("Отправка метрик в Pushgateway включена", "url", cfg.Push.Pushgateway.URL)
+	}
+	if cfg.Push.OTLP.Enabled {
+		pushExporter.Add(telemetry.NewOTLPPusher(metrics.Registry, cfg.Push.OTLP.Endpoint, cfg.Push.OTLP.Headers, pushOpts))
+		slog.Info("Отправка метрик по OTLP включена", "endpoint", cfg.Push.OTLP.Endpoint)
+	}
+	if cfg.Push.Pushgateway.Enabled || cfg.Push.OTLP.Enabled {
+		go pushExporter.Run(ctx, cfg.Push.Interval.Std())
+	}
+
+	h := handler.NewHandler(svc, metrics, history, sloTracker, traceStore, staticFiles)
 
 	// Create server
 	server := &http.Server{
-		Addr:         ServerPort,
+		Addr:         cfg.Server.Addr,
 		Handler:      h.InitRoutes(),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
@@ -75,7 +266,7 @@ func run() error {
 
 	// Start server
 	go func() {
-		slog.Info("Запуск сервера", "port", ServerPort)
+		slog.Info("Запуск сервера", "port", cfg.Server.Addr)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			slog.Error("Ошибка запуска сервера", "error", err)
 			os.Exit(1)
@@ -88,16 +279,80 @@ func run() error {
 	<-quit
 
 	slog.Info("Завершение работы сервера...")
-	telemetry.WriteMetricsToFile()
+	stop()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Drain in-flight requests first so their metrics make it into the final flush
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
+	shutdownErr := server.Shutdown(shutdownCtx)
 
-	if err := server.Shutdown(ctx); err != nil {
-		return fmt.Errorf("ошибка завершения работы сервера: %w", err)
+	telemetry.WriteMetricsToFile()
+	pushExporter.Flush()
+	statsd.Close()
+	if history != nil {
+		if err := history.Save(cfg.History.SnapshotFile); err != nil {
+			slog.Error("Ошибка сохранения истории метрик", "error", err)
+		}
+	}
+
+	if shutdownErr != nil {
+		return fmt.Errorf("ошибка завершения работы сервера: %w", shutdownErr)
 	}
 
 	slog.Info("Сервер остановлен")
 	return nil
 }
 
+// idStore is a repository that keeps the employee ID sequence and takes
+// an ID generator
+type idStore interface {
+	repository.Repository
+	repository.EmployeeSequence
+	SetIDGenerator(gen repository.IDGenerator)
+}
+
+// openSQLRepository opens the database and brings its schema up to date,
+// or checks that it is when auto migration is off, and seeds it when empty
+func openSQLRepository(ctx context.Context, cfg config.StorageConfig) (*repository.SQLRepository, func(), error) {
+	// The default SQLite DSN points into the storage directory
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("не удалось создать каталог хранилища: %w", err)
+	}
+	db, err := repository.OpenSQLDB(ctx, cfg.SQL.Driver, cfg.SQL.DSN)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка открытия хранилища: %w", err)
+	}
+	closeDB := func() {
+		if err := db.Close(); err != nil {
+			slog.Error("Ошибка закрытия базы данных", "error", err)
+		}
+	}
+
+	migrator, err := repository.NewMigrator(db)
+	if err == nil {
+		if cfg.SQL.AutoMigrate {
+			err = migrator.Up(ctx)
+		} else {
+			var version int
+			if version, err = migrator.Version(ctx); err == nil && version != migrator.Latest() {
+				err = fmt.Errorf("версия схемы %d, требуется %d; выполните migrate up", version, migrator.Latest())
+			}
+		}
+	}
+	if err != nil {
+		closeDB()
+		return nil, nil, fmt.Errorf("ошибка миграции базы данных: %w", err)
+	}
+
+	// A new database starts with the same demo data as the other drivers
+	repo := repository.NewSQLRepository(db)
+	seeded, err := repo.SeedDemoData(ctx)
+	if err != nil {
+		closeDB()
+		return nil, nil, err
+	}
+	if seeded {
+		slog.Info("База данных заполнена демонстрационными данными")
+	}
+	return repo, closeDB, nil
+}
@@ -0,0 +1,395 @@
+package analyzer
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"employee-management/internal/telemetry"
+)
+
+// Options control which series are analyzed
+type Options struct {
+	Include *regexp.Regexp // only metric names matching Include, nil keeps all
+	Exclude *regexp.Regexp // drop metric names matching Exclude, nil keeps all
+	// TimelineMetric is the counter summed into the per-dump timeline
+	TimelineMetric string
+	Quantiles      []float64
+}
+
+// DefaultOptions skip Go runtime and client library metrics
+func DefaultOptions() Options {
+	return Options{
+		Exclude:        regexp.MustCompile(`^(go|process|promhttp)_`),
+		TimelineMetric: "http_requests_total",
+		Quantiles:      []float64{0.5, 0.9, 0.95, 0.99},
+	}
+}
+
+// Point is a value observed at a point in time
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is a time series rebuilt from consecutive dumps
+type Series struct {
+	Name   string
+	Labels map[string]string
+	Points []Point
+}
+
+// Key identifies the series by name and labels
+func (s *Series) Key() string {
+	return seriesKey(s.Name, s.Labels)
+}
+
+// CounterStats describes a counter over the analyzed window
+type CounterStats struct {
+	Name     string
+	Labels   string
+	First    float64
+	Last     float64
+	Increase float64
+	AvgRate  float64 // per second over the series lifetime
+	MaxRate  float64 // highest per-second rate between two dumps
+	Resets   int
+}
+
+// GaugeStats describes a gauge over the analyzed window
+type GaugeStats struct {
+	Name   string
+	Labels string
+	Min    float64
+	Max    float64
+	Avg    float64
+	Last   float64
+}
+
+// HistogramStats describes observations recorded by a histogram or summary
+type HistogramStats struct {
+	Name      string
+	Labels    string
+	Count     float64 // NaN when the dump format lost the count
+	Sum       float64
+	Mean      float64 // NaN when Count is unknown
+	Quantiles []QuantileValue
+}
+
+// QuantileValue is an estimated quantile, NaN when it cannot be estimated
+type QuantileValue struct {
+	Quantile float64
+	Value    float64
+}
+
+// TimelineEntry is the change of the timeline metric between two dumps
+type TimelineEntry struct {
+	From  time.Time
+	To    time.Time
+	Delta float64
+	Rate  float64
+}
+
+// Report is the result of analyzing a dump file
+type Report struct {
+	Source         string
+	Format         Format
+	Dumps          int
+	From           time.Time
+	To             time.Time
+	Counters       []CounterStats
+	Gauges         []GaugeStats
+	Histograms     []HistogramStats
+	TimelineMetric string
+	Timeline       []TimelineEntry
+	Quantiles      []float64
+}
+
+// Analyze rebuilds time series from f and computes report statistics
+func Analyze(source string, f *File, opts Options) *Report {
+	report := &Report{
+		Source:         source,
+		Format:         f.Format,
+		Dumps:          len(f.Dumps),
+		TimelineMetric: opts.TimelineMetric,
+		Quantiles:      opts.Quantiles,
+	}
+	if len(f.Dumps) > 0 {
+		report.From = f.Dumps[0].Time
+		report.To = f.Dumps[len(f.Dumps)-1].Time
+	}
+
+	series := BuildSeries(f)
+	// histogram/summary family -> labels without le/quantile -> its series
+	type histParts struct {
+		labels    map[string]string
+		sum       *Series
+		count     *Series
+		buckets   map[float64]*Series
+		quantiles map[float64]float64
+	}
+	histograms := make(map[string]map[string]*histParts)
+
+	for _, s := range series {
+		family, typ := f.resolveType(s.Name)
+		if !opts.keep(family) {
+			continue
+		}
+
+		switch typ {
+		case "counter":
+			report.Counters = append(report.Counters, counterStats(s))
+		case "gauge", "untyped", "unknown":
+			report.Gauges = append(report.Gauges, gaugeStats(s))
+		case "histogram", "summary":
+			base := withoutLabels(s.Labels, "le", "quantile")
+			key := seriesKey(family, base)
+			if histograms[family] == nil {
+				histograms[family] = make(map[string]*histParts)
+			}
+			parts := histograms[family][key]
+			if parts == nil {
+				parts = &histParts{labels: base, buckets: make(map[float64]*Series), quantiles: make(map[float64]float64)}
+				histograms[family][key] = parts
+			}
+			switch {
+			case s.Name == family+"_sum":
+				parts.sum = s
+			case s.Name == family+"_count":
+				parts.count = s
+			case s.Name == family+"_bucket":
+				if le, err := strconv.ParseFloat(s.Labels["le"], 64); err == nil {
+					parts.buckets[le] = s
+				}
+			case s.Name == family:
+				// Summary quantiles are already computed, keep the latest value
+				if q, err := strconv.ParseFloat(s.Labels["quantile"], 64); err == nil {
+					parts.quantiles[q] = s.Points[len(s.Points)-1].Value
+				}
+			}
+		}
+	}
+
+	for family, byLabels := range histograms {
+		for _, parts := range byLabels {
+			stats := HistogramStats{
+				Name:   family,
+				Labels: telemetry.FormatLabels(parts.labels),
+				Count:  math.NaN(),
+				Mean:   math.NaN(),
+			}
+			if parts.sum != nil {
+				stats.Sum, _ = increase(parts.sum.Points)
+			}
+			if parts.count != nil {
+				stats.Count, _ = increase(parts.count.Points)
+				if stats.Count > 0 {
+					stats.Mean = stats.Sum / stats.Count
+				}
+			}
+
+			var buckets []telemetry.Bucket
+			for le, s := range parts.buckets {
+				inc, _ := increase(s.Points)
+				buckets = append(buckets, telemetry.Bucket{UpperBound: le, Count: inc})
+			}
+			for _, q := range opts.Quantiles {
+				value := telemetry.HistogramQuantile(q, buckets)
+				if v, ok := parts.quantiles[q]; ok && len(buckets) == 0 {
+					value = v
+				}
+				stats.Quantiles = append(stats.Quantiles, QuantileValue{Quantile: q, Value: value})
+			}
+			report.Histograms = append(report.Histograms, stats)
+		}
+	}
+
+	report.Timeline = timeline(f, opts.TimelineMetric)
+
+	sort.Slice(report.Counters, func(i, j int) bool {
+		return report.Counters[i].Name+report.Counters[i].Labels < report.Counters[j].Name+report.Counters[j].Labels
+	})
+	sort.Slice(report.Gauges, func(i, j int) bool {
+		return report.Gauges[i].Name+report.Gauges[i].Labels < report.Gauges[j].Name+report.Gauges[j].Labels
+	})
+	sort.Slice(report.Histograms, func(i, j int) bool {
+		return report.Histograms[i].Name+report.Histograms[i].Labels < report.Histograms[j].Name+report.Histograms[j].Labels
+	})
+	return report
+}
+
+// BuildSeries groups samples of all dumps into time series ordered by key
+func BuildSeries(f *File) []*Series {
+	byKey := make(map[string]*Series)
+	var keys []string
+	for _, d := range f.Dumps {
+		for _, sample := range d.Samples {
+			key := seriesKey(sample.Name, sample.Labels)
+			s, ok := byKey[key]
+			if !ok {
+				s = &Series{Name: sample.Name, Labels: sample.Labels}
+				byKey[key] = s
+				keys = append(keys, key)
+			}
+			s.Points = append(s.Points, Point{Time: d.Time, Value: sample.Value})
+		}
+	}
+
+	sort.Strings(keys)
+	series := make([]*Series, 0, len(keys))
+	for _, key := range keys {
+		series = append(series, byKey[key])
+	}
+	return series
+}
+
+// resolveType returns the metric family of a sample name and the family type
+func (f *File) resolveType(name string) (string, string) {
+	if typ, ok := f.Types[name]; ok {
+		return name, typ
+	}
+	for _, suffix := range []string{"_bucket", "_sum", "_count", "_total"} {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		family := strings.TrimSuffix(name, suffix)
+		typ, ok := f.Types[family]
+		if !ok {
+			continue
+		}
+		if suffix == "_total" && typ == "counter" {
+			return name, typ
+		}
+		if typ == "histogram" || typ == "summary" {
+			return family, typ
+		}
+	}
+	return name, "untyped"
+}
+
+func (o Options) keep(name string) bool {
+	if o.Include != nil && !o.Include.MatchString(name) {
+		return false
+	}
+	if o.Exclude != nil && o.Exclude.MatchString(name) {
+		return false
+	}
+	return true
+}
+
+// increase returns the growth of a counter over the observed points,
+// treating any decrease as a reset. A file may be appended to or rotated
+// long after the process started, so the first value is only a baseline.
+func increase(points []Point) (float64, int) {
+	total := 0.0
+	resets := 0
+	for i := 1; i < len(points); i++ {
+		total += delta(points[i-1].Value, points[i].Value)
+		if points[i].Value < points[i-1].Value {
+			resets++
+		}
+	}
+	return total, resets
+}
+
+// delta is the growth of a counter between two observations
+func delta(prev, cur float64) float64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+func counterStats(s *Series) CounterStats {
+	stats := CounterStats{
+		Name:   s.Name,
+		Labels: telemetry.FormatLabels(s.Labels),
+		First:  s.Points[0].Value,
+		Last:   s.Points[len(s.Points)-1].Value,
+	}
+	stats.Increase, stats.Resets = increase(s.Points)
+
+	if span := s.Points[len(s.Points)-1].Time.Sub(s.Points[0].Time).Seconds(); span > 0 {
+		stats.AvgRate = stats.Increase / span
+	}
+	for i := 1; i < len(s.Points); i++ {
+		dt := s.Points[i].Time.Sub(s.Points[i-1].Time).Seconds()
+		if dt <= 0 {
+			continue
+		}
+		if rate := delta(s.Points[i-1].Value, s.Points[i].Value) / dt; rate > stats.MaxRate {
+			stats.MaxRate = rate
+		}
+	}
+	return stats
+}
+
+func gaugeStats(s *Series) GaugeStats {
+	stats := GaugeStats{
+		Name:   s.Name,
+		Labels: telemetry.FormatLabels(s.Labels),
+		Min:    math.Inf(1),
+		Max:    math.Inf(-1),
+		Last:   s.Points[len(s.Points)-1].Value,
+	}
+	var total float64
+	for _, p := range s.Points {
+		stats.Min = math.Min(stats.Min, p.Value)
+		stats.Max = math.Max(stats.Max, p.Value)
+		total += p.Value
+	}
+	stats.Avg = total / float64(len(s.Points))
+	return stats
+}
+
+// timeline sums the deltas of every series of a counter between consecutive dumps
+func timeline(f *File, metric string) []TimelineEntry {
+	if metric == "" || len(f.Dumps) < 2 {
+		return nil
+	}
+
+	totals := make([]map[string]float64, len(f.Dumps))
+	for i, d := range f.Dumps {
+		totals[i] = make(map[string]float64)
+		for _, s := range d.Samples {
+			if s.Name == metric {
+				totals[i][seriesKey(s.Name, s.Labels)] = s.Value
+			}
+		}
+	}
+
+	var entries []TimelineEntry
+	for i := 1; i < len(f.Dumps); i++ {
+		entry := TimelineEntry{From: f.Dumps[i-1].Time, To: f.Dumps[i].Time}
+		for key, v := range totals[i] {
+			// A series missing from the previous dump starts from zero
+			entry.Delta += delta(totals[i-1][key], v)
+		}
+		if dt := entry.To.Sub(entry.From).Seconds(); dt > 0 {
+			entry.Rate = entry.Delta / dt
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func seriesKey(name string, labels map[string]string) string {
+	return name + "{" + telemetry.FormatLabels(labels) + "}"
+}
+
+func withoutLabels(labels map[string]string, names ...string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		out[k] = v
+	}
+	for _, name := range names {
+		delete(out, name)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
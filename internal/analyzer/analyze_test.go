@@ -0,0 +1,190 @@
+package analyzer_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"employee-management/internal/analyzer"
+)
+
+const legacyDump = `=== METRICS DUMP 2025-10-02T20:59:04+03:00 ===
+Metric: http_requests_total
+Type: COUNTER
+  http_requests_total {method=GET, path=/, status=200}: 1.000000
+Metric: http_request_duration_seconds
+Type: HISTOGRAM
+  http_request_duration_seconds {method=GET, path=/}: 0.500000
+Metric: employees_total
+Type: GAUGE
+  employees_total: 4.000000
+
+=== METRICS DUMP 2025-10-02T21:00:04+03:00 ===
+Metric: http_requests_total
+Type: COUNTER
+  http_requests_total {method=GET, path=/, status=200}: 7.000000
+Metric: http_request_duration_seconds
+Type: HISTOGRAM
+  http_request_duration_seconds {method=GET, path=/}: 2.000000
+Metric: employees_total
+Type: GAUGE
+  employees_total: 6.000000
+`
+
+// Three dumps a minute apart told apart by their timestamps; the counter
+// is reset before the last one
+const openMetricsDump = `# HELP http_requests_total Total requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET"} 10 1700000000
+# TYPE employees gauge
+employees{department="R\"D"} 5 1700000000
+# TYPE request_seconds histogram
+request_seconds_bucket{le="0.1"} 0 1700000000
+request_seconds_bucket{le="0.5"} 0 1700000000
+request_seconds_bucket{le="+Inf"} 0 1700000000
+request_seconds_sum 0 1700000000
+request_seconds_count 0 1700000000
+http_requests_total{method="GET"} 40 1700000060 # {trace_id="4bf92f3577b34da6"} 1 1700000059.5
+employees{department="R\"D"} 7 1700000060
+request_seconds_bucket{le="0.1"} 10 1700000060
+request_seconds_bucket{le="0.5"} 20 1700000060
+request_seconds_bucket{le="+Inf"} 20 1700000060
+request_seconds_sum 4 1700000060
+request_seconds_count 20 1700000060
+http_requests_total{method="GET"} 4 1700000120
+employees{department="R\"D"} 6 1700000120
+request_seconds_bucket{le="0.1"} 10 1700000120
+request_seconds_bucket{le="0.5"} 20 1700000120
+request_seconds_bucket{le="+Inf"} 20 1700000120
+request_seconds_sum 4 1700000120
+request_seconds_count 20 1700000120
+# EOF
+`
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		data string
+		want analyzer.Format
+	}{
+		{legacyDump, analyzer.FormatLegacy},
+		{"\n# DUMP 2025-10-02T20:59:04Z\nup 1\n", analyzer.FormatPrometheus},
+		{openMetricsDump, analyzer.FormatOpenMetrics},
+		{`{"timestamp":"2025-10-02T20:59:04Z","name":"up","type":"gauge","value":1}`, analyzer.FormatJSONLines},
+		{"timestamp,name,type,labels,value\n", analyzer.FormatCSV},
+	}
+	for _, tt := range tests {
+		got, err := analyzer.DetectFormat([]byte(tt.data))
+		if err != nil || got != tt.want {
+			t.Errorf("DetectFormat(%.20q) = %q, %v, want %q", tt.data, got, err, tt.want)
+		}
+	}
+	if _, err := analyzer.DetectFormat([]byte("not a dump")); err == nil {
+		t.Error("DetectFormat accepted an unknown format")
+	}
+}
+
+func TestAnalyzeLegacyDump(t *testing.T) {
+	f, err := analyzer.Parse([]byte(legacyDump))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if f.Format != analyzer.FormatLegacy || len(f.Dumps) != 2 {
+		t.Fatalf("format %q with %d dumps, want legacy with 2", f.Format, len(f.Dumps))
+	}
+	report := analyzer.Analyze("legacy.log", f, analyzer.DefaultOptions())
+
+	if len(report.Counters) != 1 {
+		t.Fatalf("counters = %+v, want one", report.Counters)
+	}
+	c := report.Counters[0]
+	if c.Labels != "method=GET;path=/;status=200" || c.Increase != 6 || !approx(c.AvgRate, 0.1) {
+		t.Errorf("counter = %+v, want an increase of 6 at 0.1/s", c)
+	}
+
+	if len(report.Gauges) != 1 {
+		t.Fatalf("gauges = %+v, want one", report.Gauges)
+	}
+	if g := report.Gauges[0]; g.Min != 4 || g.Max != 6 || g.Avg != 5 || g.Last != 6 {
+		t.Errorf("gauge = %+v, want min 4, max 6, avg 5, last 6", g)
+	}
+
+	// Legacy dumps only kept the histogram sum
+	if len(report.Histograms) != 1 {
+		t.Fatalf("histograms = %+v, want one", report.Histograms)
+	}
+	h := report.Histograms[0]
+	if h.Name != "http_request_duration_seconds" || h.Sum != 1.5 || !math.IsNaN(h.Count) || !math.IsNaN(h.Mean) {
+		t.Errorf("histogram = %+v, want a sum of 1.5 and no count", h)
+	}
+	for _, q := range h.Quantiles {
+		if !math.IsNaN(q.Value) {
+			t.Errorf("quantile %v = %v, want NaN without buckets", q.Quantile, q.Value)
+		}
+	}
+}
+
+func TestAnalyzeOpenMetricsDump(t *testing.T) {
+	f, err := analyzer.Parse([]byte(openMetricsDump))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if f.Format != analyzer.FormatOpenMetrics || len(f.Dumps) != 3 {
+		t.Fatalf("format %q with %d dumps, want openmetrics with 3", f.Format, len(f.Dumps))
+	}
+	if got := f.Dumps[1].Time; !got.Equal(time.Unix(1700000060, 0)) {
+		t.Errorf("second dump at %v", got)
+	}
+	report := analyzer.Analyze("metrics.om", f, analyzer.DefaultOptions())
+
+	c := report.Counters[0]
+	if c.Increase != 34 || c.Resets != 1 || !approx(c.MaxRate, 0.5) || !approx(c.AvgRate, 34.0/120) {
+		t.Errorf("counter = %+v, want an increase of 30+4 with one reset", c)
+	}
+
+	g := report.Gauges[0]
+	if g.Labels != `department=R"D` || g.Min != 5 || g.Max != 7 || g.Avg != 6 {
+		t.Errorf("gauge = %+v, want the escaped label and min 5, max 7, avg 6", g)
+	}
+
+	h := report.Histograms[0]
+	if h.Count != 20 || h.Sum != 4 || !approx(h.Mean, 0.2) {
+		t.Errorf("histogram = %+v, want 20 observations summing to 4", h)
+	}
+	want := map[float64]float64{0.5: 0.1, 0.9: 0.1 + 0.4*0.8, 0.95: 0.1 + 0.4*0.9, 0.99: 0.1 + 0.4*0.98}
+	for _, q := range h.Quantiles {
+		if !approx(q.Value, want[q.Quantile]) {
+			t.Errorf("quantile %v = %v, want %v", q.Quantile, q.Value, want[q.Quantile])
+		}
+	}
+
+	if len(report.Timeline) != 2 {
+		t.Fatalf("timeline = %+v, want two intervals", report.Timeline)
+	}
+	if e := report.Timeline[1]; e.Delta != 4 || !approx(e.Rate, 4.0/60) {
+		t.Errorf("timeline after the reset = %+v, want a delta of 4", e)
+	}
+}
+
+func TestParseCSVDump(t *testing.T) {
+	data := "timestamp,name,type,labels,value\n" +
+		"2025-10-02T20:59:04Z,http_requests_total,counter,method=GET;path=/,3\n" +
+		"2025-10-02T20:59:04Z,request_seconds_count,histogram,,2\n" +
+		"2025-10-02T21:00:04Z,http_requests_total,counter,method=GET;path=/,9\n"
+	f, err := analyzer.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(f.Dumps) != 2 || len(f.Dumps[0].Samples) != 2 {
+		t.Fatalf("dumps = %+v, want two with two samples in the first", f.Dumps)
+	}
+	if got := f.Dumps[0].Samples[0].Labels; got["method"] != "GET" || got["path"] != "/" {
+		t.Errorf("labels = %v", got)
+	}
+	if f.Types["request_seconds"] != "histogram" {
+		t.Errorf("types = %v, want the histogram family without its suffix", f.Types)
+	}
+}
@@ -0,0 +1,446 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"employee-management/internal/telemetry"
+)
+
+// Format of a metrics dump file
+type Format string
+
+const (
+	// FormatLegacy is the "=== METRICS DUMP ===" format written before
+	// full-fidelity dumps. Histograms and summaries only keep their sum.
+	FormatLegacy      Format = "legacy"
+	FormatPrometheus  Format = Format(telemetry.FormatPrometheus)
+	FormatOpenMetrics Format = Format(telemetry.FormatOpenMetrics)
+	FormatJSONLines   Format = Format(telemetry.FormatJSONLines)
+	FormatCSV         Format = Format(telemetry.FormatCSV)
+)
+
+const legacyHeaderPrefix = "=== METRICS DUMP "
+
+// Dump is one snapshot of all metrics taken at Time
+type Dump struct {
+	Time    time.Time
+	Samples []telemetry.Sample
+}
+
+// File is a parsed metrics dump file
+type File struct {
+	Format Format
+	Dumps  []Dump
+	// Types maps a metric family name to its type (counter, gauge, histogram, ...)
+	Types map[string]string
+}
+
+// ParseFile reads and parses a metrics dump file, detecting its format
+func ParseFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать файл метрик: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses dump data in any supported format
+func Parse(data []byte) (*File, error) {
+	format, err := DetectFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{Format: format, Types: make(map[string]string)}
+	switch format {
+	case FormatLegacy:
+		err = f.parseLegacy(data)
+	case FormatPrometheus, FormatOpenMetrics:
+		err = f.parseText(data)
+	case FormatJSONLines:
+		err = f.parseJSONLines(data)
+	case FormatCSV:
+		err = f.parseCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора формата %s: %w", format, err)
+	}
+	return f, nil
+}
+
+// DetectFormat guesses the dump format from the first meaningful line
+func DetectFormat(data []byte) (Format, error) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, legacyHeaderPrefix):
+			return FormatLegacy, nil
+		case strings.HasPrefix(line, telemetry.DumpHeaderPrefix):
+			return FormatPrometheus, nil
+		case strings.HasPrefix(line, "{"):
+			return FormatJSONLines, nil
+		case line == strings.Join(telemetry.CSVHeader, ","):
+			return FormatCSV, nil
+		case strings.HasPrefix(line, "#"):
+			return FormatOpenMetrics, nil
+		}
+		break
+	}
+	return "", fmt.Errorf("не удалось определить формат файла метрик")
+}
+
+// parseLegacy reads dumps like
+//
+//	=== METRICS DUMP 2025-10-02T20:59:04+03:00 ===
+//	Metric: http_requests_total
+//	Type: COUNTER
+//	  http_requests_total {method=GET, path=/, status=200}: 1.000000
+func (f *File) parseLegacy(data []byte) error {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	var current *Dump
+	var family, typ string
+
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, legacyHeaderPrefix):
+			stamp := strings.TrimSuffix(strings.TrimPrefix(line, legacyHeaderPrefix), " ===")
+			ts, err := time.Parse(time.RFC3339, stamp)
+			if err != nil {
+				return fmt.Errorf("неверная метка времени %q: %w", stamp, err)
+			}
+			f.Dumps = append(f.Dumps, Dump{Time: ts})
+			current = &f.Dumps[len(f.Dumps)-1]
+		case strings.HasPrefix(line, "Metric: "):
+			family = strings.TrimPrefix(line, "Metric: ")
+		case strings.HasPrefix(line, "Type: "):
+			typ = strings.ToLower(strings.TrimPrefix(line, "Type: "))
+			f.Types[family] = typ
+		case strings.HasPrefix(line, "  ") && current != nil:
+			sample, err := parseLegacySample(strings.TrimSpace(line))
+			if err != nil {
+				return err
+			}
+			// Legacy dumps reduced histograms and summaries to their sum
+			if typ == "histogram" || typ == "summary" {
+				sample.Name += "_sum"
+			}
+			current.Samples = append(current.Samples, sample)
+		}
+	}
+	return sc.Err()
+}
+
+func parseLegacySample(line string) (telemetry.Sample, error) {
+	sep := strings.LastIndex(line, ": ")
+	if sep < 0 {
+		return telemetry.Sample{}, fmt.Errorf("неверная строка метрики %q", line)
+	}
+	value, err := strconv.ParseFloat(line[sep+2:], 64)
+	if err != nil {
+		return telemetry.Sample{}, fmt.Errorf("неверное значение в строке %q: %w", line, err)
+	}
+
+	head := line[:sep]
+	sample := telemetry.Sample{Name: head, Value: value}
+	if open := strings.Index(head, " {"); open >= 0 && strings.HasSuffix(head, "}") {
+		sample.Name = head[:open]
+		sample.Labels = make(map[string]string)
+		for _, pair := range strings.Split(head[open+2:len(head)-1], ", ") {
+			if k, v, ok := strings.Cut(pair, "="); ok {
+				sample.Labels[k] = v
+			}
+		}
+	}
+	return sample, nil
+}
+
+// parseText reads the Prometheus text and OpenMetrics formats. Prometheus
+// dumps start with a "# DUMP" comment; an OpenMetrics file ends with a
+// single "# EOF" and its dumps are told apart by the sample timestamps.
+// Older OpenMetrics files ending every dump with "# EOF" are read too.
+func (f *File) parseText(data []byte) error {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	openMetrics := f.Format == FormatOpenMetrics
+
+	var current *Dump
+	startDump := func(ts time.Time) {
+		f.Dumps = append(f.Dumps, Dump{Time: ts})
+		current = &f.Dumps[len(f.Dumps)-1]
+	}
+
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, telemetry.DumpHeaderPrefix):
+			ts, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(line, telemetry.DumpHeaderPrefix))
+			if err != nil {
+				return err
+			}
+			startDump(ts)
+		case line == "# EOF":
+			current = nil
+		case strings.HasPrefix(line, "# TYPE "):
+			fields := strings.Fields(line)
+			if len(fields) == 4 {
+				f.Types[fields[2]] = fields[3]
+			}
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			sample, ts, err := parseTextSample(line, openMetrics)
+			if err != nil {
+				return err
+			}
+			if current == nil || (openMetrics && !current.Time.Equal(ts)) {
+				startDump(ts)
+			}
+			current.Samples = append(current.Samples, sample)
+		}
+	}
+	return sc.Err()
+}
+
+// parseTextSample parses `name{label="value",...} value [timestamp]`
+func parseTextSample(line string, openMetrics bool) (telemetry.Sample, time.Time, error) {
+	var sample telemetry.Sample
+	rest := line
+
+	end := strings.IndexAny(rest, "{ ")
+	if end < 0 {
+		return sample, time.Time{}, fmt.Errorf("неверная строка метрики %q", line)
+	}
+	sample.Name = rest[:end]
+	rest = rest[end:]
+
+	if strings.HasPrefix(rest, "{") {
+		labels, n, err := parseLabels(rest)
+		if err != nil {
+			return sample, time.Time{}, fmt.Errorf("%w в строке %q", err, line)
+		}
+		sample.Labels = labels
+		rest = rest[n:]
+	}
+
+	// Exemplars follow a " # " separator and are not needed here
+	if i := strings.Index(rest, " # "); i >= 0 {
+		rest = rest[:i]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return sample, time.Time{}, fmt.Errorf("нет значения в строке %q", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return sample, time.Time{}, fmt.Errorf("неверное значение в строке %q: %w", line, err)
+	}
+	sample.Value = value
+
+	var ts time.Time
+	if len(fields) > 1 {
+		raw, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return sample, time.Time{}, fmt.Errorf("неверная метка времени в строке %q: %w", line, err)
+		}
+		if openMetrics {
+			sec, frac := math.Modf(raw)
+			ts = time.Unix(int64(sec), int64(math.Round(frac*1e3))*int64(time.Millisecond))
+		} else {
+			ts = time.UnixMilli(int64(raw))
+		}
+	}
+	return sample, ts, nil
+}
+
+// parseLabels parses a `{k="v",...}` block and returns the number of bytes consumed
+func parseLabels(s string) (map[string]string, int, error) {
+	labels := make(map[string]string)
+	i := 1
+	for {
+		for i < len(s) && (s[i] == ',' || s[i] == ' ') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("незакрытый блок меток")
+		}
+		if s[i] == '}' {
+			return labels, i + 1, nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 || i+eq+1 >= len(s) || s[i+eq+1] != '"' {
+			return nil, 0, fmt.Errorf("неверный блок меток")
+		}
+		name := s[i : i+eq]
+		i += eq + 2
+
+		var value strings.Builder
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			value.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("незакрытое значение метки")
+		}
+		labels[name] = value.String()
+		i++
+	}
+}
+
+func (f *File) parseJSONLines(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var current *Dump
+
+	for {
+		var series telemetry.JSONSeries
+		if err := dec.Decode(&series); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if current == nil || !current.Time.Equal(series.Timestamp) {
+			f.Dumps = append(f.Dumps, Dump{Time: series.Timestamp})
+			current = &f.Dumps[len(f.Dumps)-1]
+		}
+		f.Types[series.Name] = series.Type
+		current.Samples = append(current.Samples, flattenJSONSeries(series)...)
+	}
+}
+
+func flattenJSONSeries(s telemetry.JSONSeries) []telemetry.Sample {
+	var samples []telemetry.Sample
+	if s.Value != nil {
+		samples = append(samples, telemetry.Sample{Name: s.Name, Labels: s.Labels, Value: float64(*s.Value)})
+	}
+	for _, b := range s.Buckets {
+		samples = append(samples, telemetry.Sample{
+			Name:   s.Name + "_bucket",
+			Labels: withLabel(s.Labels, "le", formatBound(float64(b.UpperBound))),
+			Value:  float64(b.Count),
+		})
+	}
+	for _, q := range s.Quantiles {
+		samples = append(samples, telemetry.Sample{
+			Name:   s.Name,
+			Labels: withLabel(s.Labels, "quantile", formatBound(float64(q.Quantile))),
+			Value:  float64(q.Value),
+		})
+	}
+	if s.Sum != nil {
+		samples = append(samples, telemetry.Sample{Name: s.Name + "_sum", Labels: s.Labels, Value: float64(*s.Sum)})
+	}
+	if s.Count != nil {
+		samples = append(samples, telemetry.Sample{Name: s.Name + "_count", Labels: s.Labels, Value: float64(*s.Count)})
+	}
+	return samples
+}
+
+func (f *File) parseCSV(data []byte) error {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = len(telemetry.CSVHeader)
+	var current *Dump
+
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if row[0] == telemetry.CSVHeader[0] {
+			continue
+		}
+
+		ts, err := time.Parse(time.RFC3339Nano, row[0])
+		if err != nil {
+			return err
+		}
+		if current == nil || !current.Time.Equal(ts) {
+			f.Dumps = append(f.Dumps, Dump{Time: ts})
+			current = &f.Dumps[len(f.Dumps)-1]
+		}
+
+		value, err := strconv.ParseFloat(row[4], 64)
+		if err != nil {
+			return fmt.Errorf("неверное значение %q: %w", row[4], err)
+		}
+		f.Types[familyName(row[1], row[2])] = row[2]
+		current.Samples = append(current.Samples, telemetry.Sample{
+			Name:   row[1],
+			Labels: parseLabelList(row[3]),
+			Value:  value,
+		})
+	}
+}
+
+// parseLabelList parses labels written by telemetry.FormatLabels
+func parseLabelList(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ";") {
+		if k, v, ok := strings.Cut(pair, "="); ok {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// familyName strips the _bucket, _sum and _count suffixes of histogram and summary samples
+func familyName(sample, typ string) string {
+	if typ != "histogram" && typ != "summary" {
+		return sample
+	}
+	for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+		if strings.HasSuffix(sample, suffix) {
+			return strings.TrimSuffix(sample, suffix)
+		}
+	}
+	return sample
+}
+
+func withLabel(labels map[string]string, name, value string) map[string]string {
+	out := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	out[name] = value
+	return out
+}
+
+func formatBound(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
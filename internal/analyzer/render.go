@@ -0,0 +1,183 @@
+package analyzer
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+var templateFuncs = map[string]interface{}{
+	"num":  formatNumber,
+	"rate": func(v float64) string { return formatNumber(v) + "/с" },
+	"time": func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+	"pct":  func(q float64) string { return "p" + strconv.FormatFloat(q*100, 'g', -1, 64) },
+	"md":   escapeMarkdown,
+	"unit": formatObservation,
+	"dash": dashIfEmpty,
+}
+
+const markdownTemplate = `# Отчет по метрикам
+
+- **Источник**: {{.Source}}
+- **Формат**: {{.Format}}
+- **Снимков**: {{.Dumps}}
+- **Период**: {{time .From}} — {{time .To}}
+{{- if eq .Format "legacy"}}
+
+> Устаревший формат хранит для гистограмм только сумму, поэтому количество наблюдений и перцентили недоступны.
+{{- end}}
+
+## Распределения
+{{if .Histograms}}
+| Метрика | Метки | Наблюдений | Среднее |{{range .Quantiles}} {{pct .}} |{{end}}
+|---|---|---|---|{{range .Quantiles}}---|{{end}}
+{{- range .Histograms}}{{$name := .Name}}
+| {{md .Name}} | {{md (dash .Labels)}} | {{num .Count}} | {{unit $name .Mean}} |{{range .Quantiles}} {{unit $name .Value}} |{{end}}
+{{- end}}
+{{else}}
+Нет данных.
+{{end}}
+## Счетчики
+{{if .Counters}}
+| Метрика | Метки | Прирост | Средняя скорость | Макс. скорость | Сбросов |
+|---|---|---|---|---|---|
+{{- range .Counters}}
+| {{md .Name}} | {{md (dash .Labels)}} | {{num .Increase}} | {{rate .AvgRate}} | {{rate .MaxRate}} | {{.Resets}} |
+{{- end}}
+{{else}}
+Нет данных.
+{{end}}
+## Показатели
+{{if .Gauges}}
+| Метрика | Метки | Мин. | Макс. | Среднее | Последнее |
+|---|---|---|---|---|---|
+{{- range .Gauges}}
+| {{md .Name}} | {{md (dash .Labels)}} | {{num .Min}} | {{num .Max}} | {{num .Avg}} | {{num .Last}} |
+{{- end}}
+{{else}}
+Нет данных.
+{{end}}
+## Динамика {{md .TimelineMetric}}
+{{if .Timeline}}
+| С | По | Прирост | Скорость |
+|---|---|---|---|
+{{- range .Timeline}}
+| {{time .From}} | {{time .To}} | {{num .Delta}} | {{rate .Rate}} |
+{{- end}}
+{{else}}
+Нет данных.
+{{end}}`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Отчет по метрикам</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child, th:nth-child(2), td:nth-child(2) { text-align: left; }
+th { background: #f3f3f3; }
+.note { background: #fff8e0; padding: 8px; border-left: 4px solid #e0b000; }
+</style>
+</head>
+<body>
+<h1>Отчет по метрикам</h1>
+<ul>
+<li><b>Источник</b>: {{.Source}}</li>
+<li><b>Формат</b>: {{.Format}}</li>
+<li><b>Снимков</b>: {{.Dumps}}</li>
+<li><b>Период</b>: {{time .From}} — {{time .To}}</li>
+</ul>
+{{if eq .Format "legacy"}}<p class="note">Устаревший формат хранит для гистограмм только сумму, поэтому количество наблюдений и перцентили недоступны.</p>{{end}}
+
+<h2>Распределения</h2>
+{{if .Histograms}}<table>
+<tr><th>Метрика</th><th>Метки</th><th>Наблюдений</th><th>Среднее</th>{{range .Quantiles}}<th>{{pct .}}</th>{{end}}</tr>
+{{range .Histograms}}{{$name := .Name}}<tr><td>{{.Name}}</td><td>{{dash .Labels}}</td><td>{{num .Count}}</td><td>{{unit $name .Mean}}</td>{{range .Quantiles}}<td>{{unit $name .Value}}</td>{{end}}</tr>
+{{end}}</table>{{else}}<p>Нет данных.</p>{{end}}
+
+<h2>Счетчики</h2>
+{{if .Counters}}<table>
+<tr><th>Метрика</th><th>Метки</th><th>Прирост</th><th>Средняя скорость</th><th>Макс. скорость</th><th>Сбросов</th></tr>
+{{range .Counters}}<tr><td>{{.Name}}</td><td>{{dash .Labels}}</td><td>{{num .Increase}}</td><td>{{rate .AvgRate}}</td><td>{{rate .MaxRate}}</td><td>{{.Resets}}</td></tr>
+{{end}}</table>{{else}}<p>Нет данных.</p>{{end}}
+
+<h2>Показатели</h2>
+{{if .Gauges}}<table>
+<tr><th>Метрика</th><th>Метки</th><th>Мин.</th><th>Макс.</th><th>Среднее</th><th>Последнее</th></tr>
+{{range .Gauges}}<tr><td>{{.Name}}</td><td>{{dash .Labels}}</td><td>{{num .Min}}</td><td>{{num .Max}}</td><td>{{num .Avg}}</td><td>{{num .Last}}</td></tr>
+{{end}}</table>{{else}}<p>Нет данных.</p>{{end}}
+
+<h2>Динамика {{.TimelineMetric}}</h2>
+{{if .Timeline}}<table>
+<tr><th>С</th><th>По</th><th>Прирост</th><th>Скорость</th></tr>
+{{range .Timeline}}<tr><td>{{time .From}}</td><td>{{time .To}}</td><td>{{num .Delta}}</td><td>{{rate .Rate}}</td></tr>
+{{end}}</table>{{else}}<p>Нет данных.</p>{{end}}
+</body>
+</html>
+`
+
+var (
+	markdownReport = texttemplate.Must(texttemplate.New("markdown").Funcs(templateFuncs).Parse(markdownTemplate))
+	htmlReport     = htmltemplate.Must(htmltemplate.New("html").Funcs(templateFuncs).Parse(htmlTemplate))
+)
+
+// RenderMarkdown writes the report as Markdown
+func RenderMarkdown(w io.Writer, r *Report) error {
+	return markdownReport.Execute(w, r)
+}
+
+// RenderHTML writes the report as a standalone HTML page
+func RenderHTML(w io.Writer, r *Report) error {
+	return htmlReport.Execute(w, r)
+}
+
+func formatNumber(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "н/д"
+	case math.IsInf(v, 0):
+		return "∞"
+	case v == math.Trunc(v) && math.Abs(v) < 1e15:
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	default:
+		return strconv.FormatFloat(v, 'f', 3, 64)
+	}
+}
+
+// formatObservation prints durations of *_seconds metrics in human units
+func formatObservation(name string, v float64) string {
+	if !strings.HasSuffix(name, "_seconds") {
+		return formatNumber(v)
+	}
+	switch {
+	case math.IsNaN(v):
+		return "н/д"
+	case math.IsInf(v, 0):
+		return "∞"
+	case v < 1:
+		return fmt.Sprintf("%.3f мс", v*1000)
+	default:
+		return fmt.Sprintf("%.3f с", v)
+	}
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return s
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "_", `\_`, "*", `\*`)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
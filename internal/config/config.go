@@ -0,0 +1,404 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultPath is used when CONFIG_FILE is not set
+const DefaultPath = "config.json"
+
+// Config holds application settings
+type Config struct {
+	Server      ServerConfig      `json:"server"`
+	Log         LogConfig         `json:"log"`
+	Metrics     MetricsConfig     `json:"metrics"`
+	Rotation    RotationConfig    `json:"rotation"`
+	History     HistoryConfig     `json:"history"`
+	SLO         SLOConfig         `json:"slo"`
+	Push        PushConfig        `json:"push"`
+	StatsD      StatsDConfig      `json:"statsd"`
+	Cardinality CardinalityConfig `json:"cardinality"`
+	Tracing     TracingConfig     `json:"tracing"`
+	Storage     StorageConfig     `json:"storage"`
+}
+
+// ServerConfig holds HTTP server settings
+type ServerConfig struct {
+	Addr string `json:"addr"`
+}
+
+// LogConfig holds application log settings. SpanEvents mirrors WARN and
+// ERROR records written with a span context as events on that span.
+type LogConfig struct {
+	Dir        string `json:"dir"`
+	File       string `json:"file"`
+	SpanEvents bool   `json:"span_events"`
+}
+
+// MetricsConfig holds settings of the periodic metrics file dump
+type MetricsConfig struct {
+	Dir      string   `json:"dir"`
+	File     string   `json:"file"`
+	Format   string   `json:"format"`
+	Interval Duration `json:"interval"`
+}
+
+// RotationConfig controls rotation and retention of log and metrics files
+type RotationConfig struct {
+	MaxSizeMB  int `json:"max_size_mb"`
+	MaxBackups int `json:"max_backups"`
+	MaxAgeDays int `json:"max_age_days"`
+}
+
+// HistoryConfig holds settings of the in-memory metrics history
+type HistoryConfig struct {
+	Enabled          bool     `json:"enabled"`
+	Resolution       Duration `json:"resolution"`
+	Retention        Duration `json:"retention"`
+	SnapshotFile     string   `json:"snapshot_file"`
+	SnapshotInterval Duration `json:"snapshot_interval"`
+}
+
+// SLOConfig holds service level objectives evaluated from the HTTP metrics
+type SLOConfig struct {
+	Interval   Duration       `json:"interval"`
+	Objectives []SLOObjective `json:"objectives"`
+}
+
+// SLOObjective declares one SLO. Type is "latency" or "availability";
+// empty Method and Path match every request.
+type SLOObjective struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Method    string   `json:"method"`
+	Path      string   `json:"path"`
+	Objective float64  `json:"objective"`
+	Threshold Duration `json:"threshold"`
+	Window    Duration `json:"window"`
+}
+
+// PushConfig holds settings of push-mode metrics export for deployments
+// that cannot be scraped
+type PushConfig struct {
+	Interval     Duration          `json:"interval"`
+	Timeout      Duration          `json:"timeout"`
+	Retries      int               `json:"retries"`       // retries of a failed push within timeout
+	RetryBackoff Duration          `json:"retry_backoff"` // delay before the first retry, doubled on each next one
+	Job          string            `json:"job"`
+	Grouping     map[string]string `json:"grouping"`
+	Pushgateway  PushgatewayConfig `json:"pushgateway"`
+	OTLP         OTLPMetricsConfig `json:"otlp"`
+}
+
+// PushgatewayConfig holds the Prometheus Pushgateway address
+type PushgatewayConfig struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url"`
+}
+
+// OTLPMetricsConfig holds the OTLP/HTTP metrics endpoint and extra request headers
+type OTLPMetricsConfig struct {
+	Enabled  bool              `json:"enabled"`
+	Endpoint string            `json:"endpoint"`
+	Headers  map[string]string `json:"headers"`
+}
+
+// StatsDConfig holds settings of the StatsD/DogStatsD metrics mirror.
+// Flavor is "statsd" or "dogstatsd".
+type StatsDConfig struct {
+	Enabled       bool              `json:"enabled"`
+	Addr          string            `json:"addr"`
+	Flavor        string            `json:"flavor"`
+	Prefix        string            `json:"prefix"`
+	Tags          map[string]string `json:"tags"`
+	SampleRate    float64           `json:"sample_rate"`
+	FlushInterval Duration          `json:"flush_interval"`
+	MaxPacketSize int               `json:"max_packet_size"`
+}
+
+// CardinalityConfig caps distinct values of each metric label. Limits
+// override DefaultLimit by metric name; 0 disables the limit.
+type CardinalityConfig struct {
+	DefaultLimit int            `json:"default_limit"`
+	Limits       map[string]int `json:"limits"`
+}
+
+// TracingConfig holds trace export settings. Exporter is "otlp-grpc",
+// "otlp-http", "stdout" or "file"; Fallback ("file", "stdout" or "none")
+// receives spans while an OTLP collector is unreachable. The file exporter
+// writes JSON lines to Dir/File with the log rotation settings.
+// Exporter "none" keeps spans only in the viewer. Propagators lists context
+// formats: "tracecontext", "baggage", "b3" and "b3multi"; they apply even
+// when span export is disabled.
+type TracingConfig struct {
+	Enabled     bool              `json:"enabled"`
+	ServiceName string            `json:"service_name"`
+	Exporter    string            `json:"exporter"`
+	Endpoint    string            `json:"endpoint"`
+	Insecure    bool              `json:"insecure"`
+	Headers     map[string]string `json:"headers"`
+	Timeout     Duration          `json:"timeout"`
+	Fallback    string            `json:"fallback"`
+	Dir         string            `json:"dir"`
+	File        string            `json:"file"`
+	Propagators []string          `json:"propagators"`
+	Sampling    SamplingConfig    `json:"sampling"`
+	Viewer      TraceViewerConfig `json:"viewer"`
+}
+
+// TraceViewerConfig holds settings of the in-memory store behind
+// /debug/traces
+type TraceViewerConfig struct {
+	Enabled          bool `json:"enabled"`
+	MaxTraces        int  `json:"max_traces"`
+	MaxSpansPerTrace int  `json:"max_spans_per_trace"`
+}
+
+// SamplingConfig holds trace sampling settings. Head is "always", "never",
+// "ratio" or "parent_ratio"; Ratio applies to the last two.
+type SamplingConfig struct {
+	Head  string             `json:"head"`
+	Ratio float64            `json:"ratio"`
+	Tail  TailSamplingConfig `json:"tail"`
+}
+
+// TailSamplingConfig holds settings of the in-process tail sampler, which
+// keeps traces with errors, 5xx responses or latency above LatencyThreshold
+// plus BaselineRatio of the rest
+type TailSamplingConfig struct {
+	Enabled          bool     `json:"enabled"`
+	LatencyThreshold Duration `json:"latency_threshold"`
+	BaselineRatio    float64  `json:"baseline_ratio"`
+	MaxTraces        int      `json:"max_traces"`
+	MaxSpansPerTrace int      `json:"max_spans_per_trace"`
+	DecisionWait     Duration `json:"decision_wait"`
+	DecidedTTL       Duration `json:"decided_ttl"`
+}
+
+// StorageConfig selects the repository. Driver is "memory", "file" or
+// "sql"; the file driver keeps a snapshot and a write-ahead log in Dir.
+// Sync is the WAL fsync policy: "always", "interval" (every SyncInterval)
+// or "never". IDGenerator picks employee IDs: "sequence" (emp1, emp2, ...
+// persisted with the data), "uuidv7" or "ulid".
+type StorageConfig struct {
+	Driver           string    `json:"driver"`
+	IDGenerator      string    `json:"id_generator"`
+	Dir              string    `json:"dir"`
+	Sync             string    `json:"sync"`
+	SyncInterval     Duration  `json:"sync_interval"`
+	SnapshotInterval Duration  `json:"snapshot_interval"`
+	SQL              SQLConfig `json:"sql"`
+}
+
+// SQLConfig holds database settings of the sql storage driver. Without
+// AutoMigrate the server refuses to start on an outdated schema and the
+// migrate command has to be run first.
+type SQLConfig struct {
+	Driver      string `json:"driver"`
+	DSN         string `json:"dsn"`
+	AutoMigrate bool   `json:"auto_migrate"`
+}
+
+// Duration is a time.Duration read from strings like "30s" or "5m"
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("длительность должна быть строкой: %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON formats a duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Std returns the value as time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// Default returns the configuration used when no config file is present
+func Default() Config {
+	return Config{
+		Server: ServerConfig{Addr: ":8080"},
+		Log:    LogConfig{Dir: "logs", File: "app.log", SpanEvents: true},
+		Metrics: MetricsConfig{
+			Dir:      "metrics",
+			File:     "metrics.log",
+			Format:   "prometheus",
+			Interval: Duration(30 * time.Second),
+		},
+		Rotation: RotationConfig{MaxSizeMB: 100, MaxBackups: 10, MaxAgeDays: 30},
+		History: HistoryConfig{
+			Resolution:       Duration(10 * time.Second),
+			Retention:        Duration(24 * time.Hour),
+			SnapshotFile:     "metrics/history.gob",
+			SnapshotInterval: Duration(5 * time.Minute),
+		},
+		// No objectives: SLO tracking is off until they are configured
+		SLO: SLOConfig{Interval: Duration(10 * time.Second)},
+		Push: PushConfig{
+			Interval:     Duration(15 * time.Second),
+			Timeout:      Duration(10 * time.Second),
+			Retries:      3,
+			RetryBackoff: Duration(500 * time.Millisecond),
+			Job:          "employee-management",
+			Pushgateway:  PushgatewayConfig{URL: "http://localhost:9091"},
+			OTLP:         OTLPMetricsConfig{Endpoint: "http://localhost:4318/v1/metrics"},
+		},
+		StatsD: StatsDConfig{
+			Addr:          "localhost:8125",
+			Flavor:        "statsd",
+			Prefix:        "employee_management.",
+			SampleRate:    1,
+			FlushInterval: Duration(time.Second),
+			MaxPacketSize: 1432,
+		},
+		Cardinality: CardinalityConfig{DefaultLimit: 100},
+		Tracing: TracingConfig{
+			ServiceName: "employee-management",
+			Exporter:    "file",
+			Endpoint:    "localhost:4317",
+			Insecure:    true,
+			Timeout:     Duration(5 * time.Second),
+			Fallback:    "file",
+			Dir:         "traces",
+			File:        "traces.jsonl",
+			Propagators: []string{"tracecontext", "baggage"},
+			Sampling: SamplingConfig{
+				Head:  "parent_ratio",
+				Ratio: 1,
+				Tail: TailSamplingConfig{
+					LatencyThreshold: Duration(300 * time.Millisecond),
+					BaselineRatio:    0.1,
+					MaxTraces:        10000,
+					MaxSpansPerTrace: 1000,
+					DecisionWait:     Duration(30 * time.Second),
+					DecidedTTL:       Duration(time.Minute),
+				},
+			},
+			Viewer: TraceViewerConfig{
+				MaxTraces:        500,
+				MaxSpansPerTrace: 500,
+			},
+		},
+		Storage: StorageConfig{
+			Driver:           "memory",
+			IDGenerator:      "sequence",
+			Dir:              "data",
+			Sync:             "always",
+			SyncInterval:     Duration(time.Second),
+			SnapshotInterval: Duration(5 * time.Minute),
+			SQL: SQLConfig{
+				Driver:      "sqlite",
+				DSN:         "file:data/employees.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+				AutoMigrate: true,
+			},
+		},
+	}
+}
+
+// Load reads the file named by CONFIG_FILE (or config.json) over the defaults.
+// A missing file is not an error.
+func Load() (Config, error) {
+	cfg := Default()
+
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		path = DefaultPath
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("не удалось прочитать конфигурацию %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("неверный формат конфигурации %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return cfg, fmt.Errorf("неверная конфигурация %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c Config) validate() error {
+	if c.Metrics.Interval <= 0 {
+		return fmt.Errorf("metrics.interval должен быть положительным")
+	}
+	if len(c.SLO.Objectives) > 0 && c.SLO.Interval <= 0 {
+		return fmt.Errorf("slo.interval должен быть положительным")
+	}
+	if c.History.Enabled && (c.History.Resolution <= 0 || c.History.Retention < c.History.Resolution) {
+		return fmt.Errorf("history.resolution должен быть положительным и не больше history.retention")
+	}
+	if c.Push.Pushgateway.Enabled || c.Push.OTLP.Enabled {
+		if c.Push.Interval <= 0 || c.Push.Timeout <= 0 {
+			return fmt.Errorf("push.interval и push.timeout должны быть положительными")
+		}
+		if c.Push.Job == "" {
+			return fmt.Errorf("push.job не должен быть пустым")
+		}
+		if c.Push.Retries < 0 || (c.Push.Retries > 0 && c.Push.RetryBackoff <= 0) {
+			return fmt.Errorf("push.retries не может быть отрицательным, а push.retry_backoff должен быть положительным")
+		}
+	}
+	if c.StatsD.Enabled {
+		if c.StatsD.SampleRate <= 0 || c.StatsD.SampleRate > 1 {
+			return fmt.Errorf("statsd.sample_rate должен быть в интервале (0, 1]")
+		}
+		if c.StatsD.FlushInterval <= 0 {
+			return fmt.Errorf("statsd.flush_interval должен быть положительным")
+		}
+	}
+	switch c.Storage.Driver {
+	case "memory", "file", "sql":
+	default:
+		return fmt.Errorf("storage.driver: неизвестное хранилище %q", c.Storage.Driver)
+	}
+	switch c.Storage.IDGenerator {
+	case "sequence", "uuidv7", "ulid":
+	default:
+		return fmt.Errorf("storage.id_generator: неизвестный генератор %q", c.Storage.IDGenerator)
+	}
+	switch c.Storage.Sync {
+	case "always", "interval", "never":
+	default:
+		return fmt.Errorf("storage.sync: неизвестная политика %q", c.Storage.Sync)
+	}
+	if c.Tracing.Enabled {
+		switch c.Tracing.Exporter {
+		case "otlp-grpc", "otlp-http", "stdout", "file", "none":
+		default:
+			return fmt.Errorf("tracing.exporter: неизвестный экспортер %q", c.Tracing.Exporter)
+		}
+		switch c.Tracing.Fallback {
+		case "", "none", "stdout", "file":
+		default:
+			return fmt.Errorf("tracing.fallback: неизвестный экспортер %q", c.Tracing.Fallback)
+		}
+		if r := c.Tracing.Sampling.Ratio; r < 0 || r > 1 {
+			return fmt.Errorf("tracing.sampling.ratio должен быть от 0 до 1")
+		}
+		if tail := c.Tracing.Sampling.Tail; tail.Enabled && (tail.BaselineRatio < 0 || tail.BaselineRatio > 1) {
+			return fmt.Errorf("tracing.sampling.tail.baseline_ratio должен быть от 0 до 1")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"employee-management/internal/config"
+)
+
+func TestDefaultsWriteNoTraceOrHistoryFiles(t *testing.T) {
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.json"))
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load without a file: %v", err)
+	}
+	if cfg.Tracing.Enabled || cfg.Tracing.Viewer.Enabled {
+		t.Error("tracing is on without a config file")
+	}
+	if cfg.History.Enabled {
+		t.Error("metrics history is on without a config file")
+	}
+	if len(cfg.SLO.Objectives) != 0 {
+		t.Errorf("SLO objectives %v without a config file", cfg.SLO.Objectives)
+	}
+}
+
+func TestTracingIsOptIn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"tracing": {"enabled": true}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.Tracing.Enabled || cfg.Tracing.Exporter != "file" || cfg.Tracing.File == "" {
+		t.Errorf("tracing = %+v, want it on with the file exporter defaults", cfg.Tracing)
+	}
+	if cfg.History.Enabled || cfg.Tracing.Viewer.Enabled {
+		t.Error("enabling tracing turned on other features")
+	}
+}
@@ -2,34 +2,48 @@ package handler
 
 import (
 	"embed"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"employee-management/internal/models"
+	"employee-management/internal/repository"
 	"employee-management/internal/service"
 	"employee-management/internal/telemetry"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
 // Handler handles HTTP requests
 type Handler struct {
 	service     *service.EmployeeService
+	metrics     *telemetry.Metrics
+	history     *telemetry.HistoryStore
+	slo         *telemetry.SLOTracker
+	traces      *telemetry.TraceStore
 	tracer      trace.Tracer
 	staticFiles embed.FS
 }
 
-// NewHandler creates a new HTTP handler
-func NewHandler(svc *service.EmployeeService, staticFiles embed.FS) *Handler {
+// NewHandler creates a new HTTP handler. history, slo and traces may be nil
+// when metrics history, SLO tracking or the trace viewer is disabled.
+func NewHandler(svc *service.EmployeeService, metrics *telemetry.Metrics, history *telemetry.HistoryStore, slo *telemetry.SLOTracker, traces *telemetry.TraceStore, staticFiles embed.FS) *Handler {
 	return &Handler{
 		service:     svc,
+		metrics:     metrics,
+		history:     history,
+		slo:         slo,
+		traces:      traces,
 		tracer:      otel.Tracer("employee-handler"),
 		staticFiles: staticFiles,
 	}
@@ -45,19 +59,38 @@ func (h *Handler) InitRoutes() *gin.Engine {
 	api := router.Group("/api")
 	{
 		api.GET("/departments", h.getDepartments)
+		api.GET("/departments/tree", h.getDepartmentTree)
+		api.GET("/departments/:id", h.getDepartment)
+		api.POST("/departments", h.createDepartment)
+		api.PUT("/departments/:id", h.updateDepartment)
+		api.POST("/departments/:id/archive", h.archiveDepartment)
 		api.GET("/employees/department/:departmentId", h.getEmployeesByDepartment)
 		api.POST("/employees/search", h.searchEmployees)
 		api.POST("/employees", h.createEmployee)
 		api.PUT("/employees/:id", h.updateEmployee)
 		api.PATCH("/employees/:id/status", h.updateEmployeeStatus)
 		api.GET("/positions", h.getPositions)
+		api.GET("/positions/:id", h.getPosition)
+		api.POST("/positions", h.createPosition)
+		api.PUT("/positions/:id", h.updatePosition)
+		api.DELETE("/positions/:id", h.deletePosition)
 		api.GET("/metrics", h.getMetrics)
+		api.GET("/metrics/history", h.getMetricsHistory)
 		api.GET("/health", h.healthCheck)
 	}
 
-	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	// OpenMetrics is served on request, it is the only format carrying exemplars
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(h.metrics.Registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})))
 	router.StaticFS("/static", http.FS(h.staticFiles))
 
+	debug := router.Group("/debug")
+	{
+		debug.GET("/traces", h.listTraces)
+		debug.GET("/traces/:traceId", h.getTrace)
+	}
+
 	router.GET("/", func(c *gin.Context) {
 		data, err := h.staticFiles.ReadFile("static/index.html")
 		if err != nil {
@@ -84,42 +117,78 @@ func (h *Handler) loggingMiddleware() gin.HandlerFunc {
 			"client_ip", c.ClientIP(),
 		)
 
-		telemetry.HttpRequestsTotal.WithLabelValues(
-			c.Request.Method,
-			c.Request.URL.Path,
-			strconv.Itoa(c.Writer.Status()),
-		).Inc()
-
-		telemetry.HttpRequestDuration.WithLabelValues(
-			c.Request.Method,
-			c.Request.URL.Path,
-		).Observe(duration.Seconds())
+		// The route template keeps IDs out of the path label, requests that
+		// match no route share one value
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		h.metrics.RecordHTTPRequest(c.Request.Context(), c.Request.Method, route, c.Writer.Status(), duration)
 	}
 }
 
+// tracingMiddleware continues the trace of the caller when the request
+// carries propagation headers and returns traceparent in the response
 func (h *Handler) tracingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, span := h.tracer.Start(c.Request.Context(), fmt.Sprintf("%s %s", c.Request.Method, c.Request.URL.Path))
-		defer span.End()
+		// The trace viewer polls, tracing it would crowd out the traces it shows
+		if strings.HasPrefix(c.Request.URL.Path, "/debug/") {
+			c.Next()
+			return
+		}
+
+		propagator := otel.GetTextMapPropagator()
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		// Span names use the route template to keep them low-cardinality
+		route := c.FullPath()
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
 
-		span.SetAttributes(
-			attribute.String("http.method", c.Request.Method),
-			attribute.String("http.path", c.Request.URL.Path),
-			attribute.String("http.client_ip", c.ClientIP()),
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+		ctx, span := h.tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.URLScheme(scheme),
+				semconv.ServerAddress(c.Request.Host),
+				semconv.ClientAddress(c.ClientIP()),
+				semconv.UserAgentOriginal(c.Request.UserAgent()),
+			),
 		)
+		defer span.End()
+		if route != "" {
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+
+		propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
 
 		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 
-		span.SetAttributes(
-			attribute.Int("http.status_code", c.Writer.Status()),
-		)
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if size := c.Writer.Size(); size >= 0 {
+			span.SetAttributes(semconv.HTTPResponseBodySize(size))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
 	}
 }
 
 func (h *Handler) getDepartments(c *gin.Context) {
 	ctx := c.Request.Context()
-	departments, err := h.service.GetDepartments(ctx)
+	departments, err := h.service.GetDepartments(ctx, c.Query("include_archived") == "true")
 	if err != nil {
 		h.sendError(c, http.StatusInternalServerError, "Ошибка получения департаментов: "+err.Error())
 		return
@@ -127,15 +196,118 @@ func (h *Handler) getDepartments(c *gin.Context) {
 	h.sendSuccess(c, departments)
 }
 
+func (h *Handler) getDepartmentTree(c *gin.Context) {
+	ctx := c.Request.Context()
+	tree, err := h.service.GetDepartmentTree(ctx, c.Query("include_archived") == "true")
+	if err != nil {
+		h.sendError(c, http.StatusInternalServerError, "Ошибка получения структуры департаментов: "+err.Error())
+		return
+	}
+	h.sendSuccess(c, tree)
+}
+
+func (h *Handler) getDepartment(c *gin.Context) {
+	ctx := c.Request.Context()
+	dept, err := h.service.GetDepartment(ctx, c.Param("id"))
+	if err != nil {
+		h.sendError(c, errorStatus(err), "Ошибка получения департамента: "+err.Error())
+		return
+	}
+	h.sendSuccess(c, dept)
+}
+
+func (h *Handler) createDepartment(c *gin.Context) {
+	ctx := c.Request.Context()
+	var dept models.Department
+	if err := c.ShouldBindJSON(&dept); err != nil {
+		h.sendError(c, http.StatusBadRequest, "Неверный формат данных: "+err.Error())
+		return
+	}
+
+	created, err := h.service.CreateDepartment(ctx, dept)
+	if err != nil {
+		h.sendError(c, errorStatus(err), "Ошибка создания департамента: "+err.Error())
+		return
+	}
+	h.sendSuccessWithMessage(c, created, "Департамент создан")
+}
+
+func (h *Handler) updateDepartment(c *gin.Context) {
+	ctx := c.Request.Context()
+	var dept models.Department
+	if err := c.ShouldBindJSON(&dept); err != nil {
+		h.sendError(c, http.StatusBadRequest, "Неверный формат данных: "+err.Error())
+		return
+	}
+
+	dept.ID = c.Param("id")
+	updated, err := h.service.UpdateDepartment(ctx, dept)
+	if err != nil {
+		h.sendError(c, errorStatus(err), "Ошибка обновления департамента: "+err.Error())
+		return
+	}
+	h.sendSuccessWithMessage(c, updated, "Департамент обновлен")
+}
+
+func (h *Handler) archiveDepartment(c *gin.Context) {
+	ctx := c.Request.Context()
+	archived, err := h.service.ArchiveDepartment(ctx, c.Param("id"))
+	if err != nil {
+		h.sendError(c, errorStatus(err), "Ошибка архивации департамента: "+err.Error())
+		return
+	}
+	h.sendSuccessWithMessage(c, archived, "Департамент перенесен в архив")
+}
+
+// errorStatus maps service errors to HTTP statuses
+func errorStatus(err error) int {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrDepartmentNotFound), errors.Is(err, repository.ErrPositionNotFound),
+		errors.Is(err, repository.ErrEmployeeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrDepartmentHasEmployees), errors.Is(err, repository.ErrDepartmentHasChildren),
+		errors.Is(err, repository.ErrPositionInUse),
+		errors.Is(err, repository.ErrDuplicatePosition), errors.Is(err, repository.ErrDuplicatePassport):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func (h *Handler) getEmployeesByDepartment(c *gin.Context) {
 	ctx := c.Request.Context()
 	departmentID := c.Param("departmentId")
-	employees, err := h.service.GetEmployeesByDepartment(ctx, departmentID)
+	req, err := parsePageRequest(c)
 	if err != nil {
-		h.sendError(c, http.StatusInternalServerError, "Ошибка получения сотрудников: "+err.Error())
+		h.sendError(c, http.StatusBadRequest, "Неверные параметры запроса: "+err.Error())
 		return
 	}
-	h.sendSuccess(c, employees)
+
+	page, err := h.service.GetEmployeesByDepartment(ctx, departmentID, req)
+	if err != nil {
+		h.sendError(c, errorStatus(err), "Ошибка получения сотрудников: "+err.Error())
+		return
+	}
+	h.sendSuccess(c, page)
+}
+
+// parsePageRequest reads sort, order, limit and cursor query parameters
+func parsePageRequest(c *gin.Context) (models.PageRequest, error) {
+	req := models.PageRequest{
+		Sort:   c.Query("sort"),
+		Order:  c.Query("order"),
+		Cursor: c.Query("cursor"),
+	}
+	if limit := c.Query("limit"); limit != "" {
+		var err error
+		if req.Limit, err = strconv.Atoi(limit); err != nil {
+			return req, fmt.Errorf("неверный limit: %w", err)
+		}
+	}
+	return req, nil
 }
 
 func (h *Handler) searchEmployees(c *gin.Context) {
@@ -146,12 +318,12 @@ func (h *Handler) searchEmployees(c *gin.Context) {
 		return
 	}
 
-	employees, err := h.service.SearchEmployees(ctx, req)
+	page, err := h.service.SearchEmployees(ctx, req)
 	if err != nil {
-		h.sendError(c, http.StatusInternalServerError, "Ошибка поиска сотрудников: "+err.Error())
+		h.sendError(c, errorStatus(err), "Ошибка поиска сотрудников: "+err.Error())
 		return
 	}
-	h.sendSuccess(c, employees)
+	h.sendSuccess(c, page)
 }
 
 func (h *Handler) createEmployee(c *gin.Context) {
@@ -164,11 +336,7 @@ func (h *Handler) createEmployee(c *gin.Context) {
 
 	createdEmp, err := h.service.CreateEmployee(ctx, emp)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if err.Error() == "сотрудник с таким паспортом уже существует" {
-			status = http.StatusBadRequest
-		}
-		h.sendError(c, status, "Ошибка создания сотрудника: "+err.Error())
+		h.sendError(c, errorStatus(err), "Ошибка создания сотрудника: "+err.Error())
 		return
 	}
 	h.sendSuccessWithMessage(c, createdEmp, "Сотрудник успешно создан")
@@ -186,7 +354,7 @@ func (h *Handler) updateEmployee(c *gin.Context) {
 	emp.ID = id
 	updatedEmp, err := h.service.UpdateEmployee(ctx, emp)
 	if err != nil {
-		h.sendError(c, http.StatusInternalServerError, "Ошибка обновления сотрудника: "+err.Error())
+		h.sendError(c, errorStatus(err), "Ошибка обновления сотрудника: "+err.Error())
 		return
 	}
 	h.sendSuccessWithMessage(c, updatedEmp, "Данные сотрудника обновлены")
@@ -203,7 +371,7 @@ func (h *Handler) updateEmployeeStatus(c *gin.Context) {
 
 	updatedEmp, err := h.service.UpdateEmployeeStatus(ctx, id, req.Status)
 	if err != nil {
-		h.sendError(c, http.StatusInternalServerError, "Ошибка обновления статуса: "+err.Error())
+		h.sendError(c, errorStatus(err), "Ошибка обновления статуса: "+err.Error())
 		return
 	}
 
@@ -222,7 +390,7 @@ func (h *Handler) updateEmployeeStatus(c *gin.Context) {
 
 func (h *Handler) getPositions(c *gin.Context) {
 	ctx := c.Request.Context()
-	positions, err := h.service.GetPositions(ctx)
+	positions, err := h.service.GetPositions(ctx, c.Query("include_inactive") == "true")
 	if err != nil {
 		h.sendError(c, http.StatusInternalServerError, "Ошибка получения должностей: "+err.Error())
 		return
@@ -230,6 +398,64 @@ func (h *Handler) getPositions(c *gin.Context) {
 	h.sendSuccess(c, positions)
 }
 
+func (h *Handler) getPosition(c *gin.Context) {
+	ctx := c.Request.Context()
+	pos, err := h.service.GetPosition(ctx, c.Param("id"))
+	if err != nil {
+		h.sendError(c, errorStatus(err), "Ошибка получения должности: "+err.Error())
+		return
+	}
+	h.sendSuccess(c, pos)
+}
+
+func (h *Handler) createPosition(c *gin.Context) {
+	ctx := c.Request.Context()
+	pos := models.Position{Grade: 1, Active: true}
+	if err := c.ShouldBindJSON(&pos); err != nil {
+		h.sendError(c, http.StatusBadRequest, "Неверный формат данных: "+err.Error())
+		return
+	}
+
+	created, err := h.service.CreatePosition(ctx, pos)
+	if err != nil {
+		h.sendError(c, errorStatus(err), "Ошибка создания должности: "+err.Error())
+		return
+	}
+	h.sendSuccessWithMessage(c, created, "Должность создана")
+}
+
+// updatePosition applies the fields present in the body to the stored
+// position, so a rename does not need to repeat grade or active
+func (h *Handler) updatePosition(c *gin.Context) {
+	ctx := c.Request.Context()
+	pos, err := h.service.GetPosition(ctx, c.Param("id"))
+	if err != nil {
+		h.sendError(c, errorStatus(err), "Ошибка обновления должности: "+err.Error())
+		return
+	}
+	if err := c.ShouldBindJSON(pos); err != nil {
+		h.sendError(c, http.StatusBadRequest, "Неверный формат данных: "+err.Error())
+		return
+	}
+
+	pos.ID = c.Param("id")
+	updated, err := h.service.UpdatePosition(ctx, *pos)
+	if err != nil {
+		h.sendError(c, errorStatus(err), "Ошибка обновления должности: "+err.Error())
+		return
+	}
+	h.sendSuccessWithMessage(c, updated, "Должность обновлена")
+}
+
+func (h *Handler) deletePosition(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := h.service.DeletePosition(ctx, c.Param("id")); err != nil {
+		h.sendError(c, errorStatus(err), "Ошибка удаления должности: "+err.Error())
+		return
+	}
+	h.sendSuccessWithMessage(c, nil, "Должность удалена")
+}
+
 func (h *Handler) getMetrics(c *gin.Context) {
 	ctx := c.Request.Context()
 	stats, err := h.service.GetEmployeeStats(ctx)
@@ -237,22 +463,173 @@ func (h *Handler) getMetrics(c *gin.Context) {
 		h.sendError(c, http.StatusInternalServerError, "Ошибка получения метрик: "+err.Error())
 		return
 	}
-	telemetry.UpdateEmployeeMetrics(stats)
+	h.service.RefreshMetrics(ctx)
 	h.sendSuccess(c, map[string]interface{}{
 		"timestamp": time.Now(),
 		"stats":     stats,
+		"slo":       h.sloStatuses(),
 		"message":   "Метрики обновлены",
 	})
 }
 
+func (h *Handler) getMetricsHistory(c *gin.Context) {
+	if h.history == nil {
+		h.sendError(c, http.StatusNotFound, "История метрик отключена")
+		return
+	}
+
+	query, err := parseHistoryQuery(c, time.Now())
+	if err != nil {
+		h.sendError(c, http.StatusBadRequest, "Неверный запрос истории: "+err.Error())
+		return
+	}
+
+	series, err := h.history.Query(query)
+	if err != nil {
+		h.sendError(c, http.StatusBadRequest, "Ошибка получения истории: "+err.Error())
+		return
+	}
+	step := query.Step
+	if step < h.history.Resolution() {
+		step = h.history.Resolution()
+	}
+	h.sendSuccess(c, map[string]interface{}{
+		"from":   query.From,
+		"to":     query.To,
+		"step":   step.String(),
+		"series": series,
+	})
+}
+
+// parseHistoryQuery reads name, labels (k=v,k=v), from and to (RFC3339 or
+// unix seconds, default the last hour), step, fn (rate, quantile), q and sum
+func parseHistoryQuery(c *gin.Context, now time.Time) (telemetry.HistoryQuery, error) {
+	query := telemetry.HistoryQuery{
+		Name: c.Query("name"),
+		From: now.Add(-time.Hour),
+		To:   now,
+		Func: c.Query("fn"),
+		Sum:  c.Query("sum") == "true" || c.Query("sum") == "1",
+	}
+
+	if labels := c.Query("labels"); labels != "" {
+		query.Labels = make(map[string]string)
+		for _, pair := range strings.Split(labels, ",") {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return query, fmt.Errorf("неверная метка: %s", pair)
+			}
+			query.Labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+
+	var err error
+	if from := c.Query("from"); from != "" {
+		if query.From, err = parseTime(from); err != nil {
+			return query, fmt.Errorf("неверный from: %w", err)
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if query.To, err = parseTime(to); err != nil {
+			return query, fmt.Errorf("неверный to: %w", err)
+		}
+	}
+	if step := c.Query("step"); step != "" {
+		if query.Step, err = time.ParseDuration(step); err != nil {
+			return query, fmt.Errorf("неверный step: %w", err)
+		}
+	}
+	if query.Func == telemetry.FuncQuantile {
+		query.Q = 0.95
+		if q := c.Query("q"); q != "" {
+			if query.Q, err = strconv.ParseFloat(q, 64); err != nil {
+				return query, fmt.Errorf("неверный q: %w", err)
+			}
+		}
+	}
+	return query, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func (h *Handler) listTraces(c *gin.Context) {
+	if h.traces == nil {
+		h.sendError(c, http.StatusNotFound, "Просмотр трассировок отключен")
+		return
+	}
+
+	query, err := parseTraceQuery(c)
+	if err != nil {
+		h.sendError(c, http.StatusBadRequest, "Неверный запрос трассировок: "+err.Error())
+		return
+	}
+	h.sendSuccess(c, h.traces.List(query))
+}
+
+func (h *Handler) getTrace(c *gin.Context) {
+	if h.traces == nil {
+		h.sendError(c, http.StatusNotFound, "Просмотр трассировок отключен")
+		return
+	}
+
+	tree, ok := h.traces.Get(c.Param("traceId"))
+	if !ok {
+		h.sendError(c, http.StatusNotFound, "Трассировка не найдена")
+		return
+	}
+	h.sendSuccess(c, tree)
+}
+
+// parseTraceQuery reads trace_id, route, status (404, 5xx or error),
+// min_duration, max_duration and limit (default 100)
+func parseTraceQuery(c *gin.Context) (telemetry.TraceQuery, error) {
+	query := telemetry.TraceQuery{
+		TraceID: c.Query("trace_id"),
+		Route:   c.Query("route"),
+		Status:  c.Query("status"),
+		Limit:   100,
+	}
+
+	var err error
+	if d := c.Query("min_duration"); d != "" {
+		if query.MinDuration, err = time.ParseDuration(d); err != nil {
+			return query, fmt.Errorf("неверный min_duration: %w", err)
+		}
+	}
+	if d := c.Query("max_duration"); d != "" {
+		if query.MaxDuration, err = time.ParseDuration(d); err != nil {
+			return query, fmt.Errorf("неверный max_duration: %w", err)
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			return query, fmt.Errorf("неверный limit: %w", err)
+		}
+	}
+	return query, nil
+}
+
 func (h *Handler) healthCheck(c *gin.Context) {
-	h.sendSuccess(c, map[string]string{
+	h.sendSuccess(c, map[string]interface{}{
 		"status":    "healthy",
 		"timestamp": time.Now().Format(time.RFC3339),
 		"service":   "employee-management-system",
+		"slo":       h.sloStatuses(),
 	})
 }
 
+func (h *Handler) sloStatuses() []telemetry.SLOStatus {
+	if h.slo == nil {
+		return nil
+	}
+	return h.slo.Statuses()
+}
+
 func (h *Handler) sendSuccess(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
@@ -269,7 +646,7 @@ func (h *Handler) sendSuccessWithMessage(c *gin.Context, data interface{}, messa
 }
 
 func (h *Handler) sendError(c *gin.Context, status int, message string) {
-	slog.Error("API error",
+	slog.ErrorContext(c.Request.Context(), "API error",
 		"status", status,
 		"message", message,
 		"path", c.Request.URL.Path,
@@ -0,0 +1,99 @@
+package handler_test
+
+import (
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"employee-management/internal/handler"
+	"employee-management/internal/repository"
+	"employee-management/internal/service"
+	"employee-management/internal/telemetry"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newTestRouter serves the API over the demo data of MemoryRepository
+func newTestRouter() (http.Handler, *telemetry.Metrics) {
+	gin.SetMode(gin.TestMode)
+	metrics := telemetry.NewMetrics(telemetry.BuildInfo{})
+	svc := service.NewEmployeeService(repository.NewMemoryRepository(), metrics)
+	return handler.NewHandler(svc, metrics, nil, nil, nil, embed.FS{}).InitRoutes(), metrics
+}
+
+func serve(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequestMetricsUseRouteTemplate(t *testing.T) {
+	router, metrics := newTestRouter()
+
+	serve(router, http.MethodGet, "/api/positions/pos1", "")
+	serve(router, http.MethodGet, "/api/positions/pos2", "")
+	serve(router, http.MethodGet, "/no/such/page", "")
+
+	if got := testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues("GET", "/api/positions/:id", "200")); got != 2 {
+		t.Errorf("requests to /api/positions/:id = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+	if n := testutil.CollectAndCount(metrics.HttpRequestsTotal); n != 2 {
+		t.Errorf("request series = %d, want 2", n)
+	}
+}
+
+func TestEmployeeErrorStatuses(t *testing.T) {
+	router, _ := newTestRouter()
+	employee := func(passport string) string {
+		return `{"full_name": "Смирнов Олег Петрович", "gender": "male", "age": 30, "education": "higher",
+			"position_id": "pos3", "passport": "` + passport + `", "department_id": "dept1"}`
+	}
+
+	tests := []struct {
+		name         string
+		method, path string
+		body         string
+		want         int
+	}{
+		{"create", http.MethodPost, "/api/employees", employee("9999 000001"), http.StatusOK},
+		{"create with a taken passport", http.MethodPost, "/api/employees", employee("1234 567890"), http.StatusConflict},
+		{"create with missing fields", http.MethodPost, "/api/employees", `{"full_name": "Смирнов"}`, http.StatusBadRequest},
+		{"update a missing employee", http.MethodPut, "/api/employees/emp99", employee("9999 000002"), http.StatusNotFound},
+		{"update to a taken passport", http.MethodPut, "/api/employees/emp2", employee("1234 567890"), http.StatusConflict},
+		{"status of a missing employee", http.MethodPatch, "/api/employees/emp99/status", `{"status": "fired"}`, http.StatusNotFound},
+		{"unknown status", http.MethodPatch, "/api/employees/emp1/status", `{"status": "retired"}`, http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		if rec := serve(router, tc.method, tc.path, tc.body); rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d: %s", tc.name, rec.Code, tc.want, rec.Body)
+		}
+	}
+}
+
+func TestArchiveDepartmentWithActiveChildren(t *testing.T) {
+	router, _ := newTestRouter()
+
+	rec := serve(router, http.MethodPost, "/api/departments", `{"name": "Отдел рекламы", "parent_id": "dept5"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create subdepartment: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(router, http.MethodPost, "/api/departments/dept5/archive", ""); rec.Code != http.StatusConflict {
+		t.Errorf("archive with an active subdepartment: status = %d, want 409", rec.Code)
+	}
+	if rec := serve(router, http.MethodPost, "/api/departments/dept6/archive", ""); rec.Code != http.StatusOK {
+		t.Fatalf("archive the subdepartment: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(router, http.MethodPost, "/api/departments/dept5/archive", ""); rec.Code != http.StatusOK {
+		t.Errorf("archive with only archived subdepartments: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+}
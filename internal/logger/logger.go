@@ -1,31 +1,28 @@
 package logger
 
 import (
-	"fmt"
 	"io"
 	"log/slog"
 	"os"
 )
 
-// Setup initializes the application logger
-func Setup(logDir, logFile string) (*os.File, error) {
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return nil, fmt.Errorf("не удалось создать директорию логов: %w", err)
-	}
-
-	logFilePath := logDir + "/" + logFile
-	file, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+// Setup initializes the application logger. With spanEvents, WARN and
+// ERROR records written with a span context are also added to the span.
+func Setup(logDir, logFile string, rotation RotationOptions, spanEvents bool) (*RotatingFile, error) {
+	file, err := OpenRotatingFile(logDir, logFile, rotation)
 	if err != nil {
-		return nil, fmt.Errorf("не удалось открыть файл логов: %w", err)
+		return nil, err
 	}
 
 	multiWriter := io.MultiWriter(os.Stdout, file)
 
-	logger := slog.New(slog.NewJSONHandler(multiWriter, &slog.HandlerOptions{
+	var handler slog.Handler = slog.NewJSONHandler(multiWriter, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
-	}))
-	slog.SetDefault(logger)
+	})
+	if spanEvents {
+		handler = NewSpanEventHandler(handler, slog.LevelWarn)
+	}
+	slog.SetDefault(slog.New(handler))
 
 	return file, nil
 }
-
@@ -0,0 +1,265 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotationOptions controls when a file is rotated and how many old files are kept
+type RotationOptions struct {
+	MaxSizeMB  int // rotate when the file exceeds this size, 0 disables size rotation
+	MaxBackups int // number of rotated files to keep, 0 keeps all
+	MaxAgeDays int // remove rotated files older than this, 0 keeps all
+}
+
+const backupTimeFormat = "20060102-150405.000"
+
+// RotatingFile is an append-only file that rotates itself by size
+type RotatingFile struct {
+	mu   sync.Mutex
+	path string
+	opts RotationOptions
+	file *os.File
+	size int64
+
+	header  []byte // written at the start of every new file
+	trailer []byte // kept at the end of the file, after the last write
+	trailed bool   // the active file ends with trailer
+}
+
+// OpenRotatingFile opens dir/name for appending and creates dir if needed
+func OpenRotatingFile(dir, name string, opts RotationOptions) (*RotatingFile, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию %s: %w", dir, err)
+	}
+
+	rf := &RotatingFile{path: filepath.Join(dir, name), opts: opts}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	rf.prune()
+	return rf, nil
+}
+
+// SetFraming sets the header every file starts with and the trailer it
+// ends with. The header is written to the active file if it is empty and
+// to each file started by rotation; the trailer is moved past every write.
+func (rf *RotatingFile) SetFraming(header, trailer []byte) error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return os.ErrClosed
+	}
+	rf.header = header
+	rf.trailer = trailer
+	return rf.frame()
+}
+
+// Path returns the path of the active file
+func (rf *RotatingFile) Path() string {
+	return rf.path
+}
+
+// Write appends p to the file, rotating it first if p would exceed the size limit.
+// A single write is never split between files.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	maxSize := int64(rf.opts.MaxSizeMB) * 1024 * 1024
+	if maxSize > 0 && rf.size > int64(len(rf.header)) && rf.size+int64(len(p)) > maxSize {
+		if err := rf.rotate(); err != nil {
+			if rf.file == nil {
+				return 0, err
+			}
+			// Keep writing to the oversized file rather than losing data;
+			// the rotation is retried on the next write
+			fmt.Fprintf(os.Stderr, "ошибка ротации файла %s: %v\n", rf.path, err)
+		}
+	}
+
+	if rf.trailed {
+		if err := rf.file.Truncate(rf.size - int64(len(rf.trailer))); err != nil {
+			return 0, err
+		}
+		rf.size -= int64(len(rf.trailer))
+		rf.trailed = false
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	if len(rf.trailer) > 0 {
+		m, err := rf.file.Write(rf.trailer)
+		rf.size += int64(m)
+		if err != nil {
+			return n, err
+		}
+		rf.trailed = true
+	}
+	return n, nil
+}
+
+// Sync commits the active file to stable storage
+func (rf *RotatingFile) Sync() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return os.ErrClosed
+	}
+	return rf.file.Sync()
+}
+
+// Close closes the active file
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть файл %s: %w", rf.path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("не удалось получить размер файла %s: %w", rf.path, err)
+	}
+
+	rf.file = file
+	rf.size = info.Size()
+	rf.trailed = false
+	return rf.frame()
+}
+
+// frame writes the header to an empty file and notes whether the file
+// already ends with the trailer
+func (rf *RotatingFile) frame() error {
+	if rf.size == 0 && len(rf.header) > 0 {
+		n, err := rf.file.Write(rf.header)
+		rf.size += int64(n)
+		if err != nil {
+			return fmt.Errorf("не удалось записать заголовок файла %s: %w", rf.path, err)
+		}
+	}
+	if len(rf.trailer) > 0 && rf.size >= int64(len(rf.trailer)) {
+		ends, err := endsWith(rf.path, rf.trailer)
+		if err != nil {
+			return fmt.Errorf("не удалось прочитать файл %s: %w", rf.path, err)
+		}
+		rf.trailed = ends
+	}
+	return nil
+}
+
+// endsWith reports whether the file at path ends with suffix
+func endsWith(path string, suffix []byte) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	tail := make([]byte, len(suffix))
+	if _, err := f.Seek(-int64(len(suffix)), io.SeekEnd); err != nil {
+		return false, err
+	}
+	if _, err := io.ReadFull(f, tail); err != nil {
+		return false, err
+	}
+	return bytes.Equal(tail, suffix), nil
+}
+
+func (rf *RotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+
+	if err := os.Rename(rf.path, rf.backupName(time.Now())); err != nil {
+		// Reopen the original file, otherwise every later write would fail
+		if openErr := rf.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("не удалось переименовать файл %s: %w", rf.path, err)
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.prune()
+	return nil
+}
+
+// backupName turns logs/app.log into logs/app-20060102-150405.000.log
+func (rf *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(rf.path)
+	base := strings.TrimSuffix(rf.path, ext)
+	return fmt.Sprintf("%s-%s%s", base, t.Format(backupTimeFormat), ext)
+}
+
+// backups returns rotated files for the active file, newest first
+func (rf *RotatingFile) backups() []string {
+	ext := filepath.Ext(rf.path)
+	base := strings.TrimSuffix(rf.path, ext)
+
+	matches, err := filepath.Glob(base + "-*" + ext)
+	if err != nil {
+		return nil
+	}
+
+	var backups []string
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(m, base+"-"), ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	// The timestamp format sorts lexicographically in time order
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+func (rf *RotatingFile) prune() {
+	if rf.opts.MaxBackups <= 0 && rf.opts.MaxAgeDays <= 0 {
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -rf.opts.MaxAgeDays)
+	for i, name := range rf.backups() {
+		expired := false
+		if rf.opts.MaxBackups > 0 && i >= rf.opts.MaxBackups {
+			expired = true
+		}
+		if rf.opts.MaxAgeDays > 0 {
+			if info, err := os.Stat(name); err == nil && info.ModTime().Before(cutoff) {
+				expired = true
+			}
+		}
+		if expired {
+			os.Remove(name)
+		}
+	}
+}
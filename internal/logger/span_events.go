@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanEventHandler passes records to the wrapped handler and adds those at
+// or above level as events to the recording span in the record context
+type spanEventHandler struct {
+	next   slog.Handler
+	level  slog.Level
+	attrs  []attribute.KeyValue
+	prefix string // group path of attributes added later, with a trailing dot
+}
+
+// NewSpanEventHandler wraps next so that records of level and above written
+// with a context holding an active span also become span events named
+// "log", carrying the level, message and record attributes
+func NewSpanEventHandler(next slog.Handler, level slog.Level) slog.Handler {
+	return &spanEventHandler{next: next, level: level}
+}
+
+func (h *spanEventHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *spanEventHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.level && ctx != nil {
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			attrs := make([]attribute.KeyValue, 0, len(h.attrs)+r.NumAttrs()+2)
+			attrs = append(attrs,
+				attribute.String("log.severity", r.Level.String()),
+				attribute.String("log.message", r.Message),
+			)
+			attrs = append(attrs, h.attrs...)
+			r.Attrs(func(a slog.Attr) bool {
+				attrs = appendSlogAttr(attrs, h.prefix, a)
+				return true
+			})
+			span.AddEvent("log", trace.WithAttributes(attrs...), trace.WithTimestamp(r.Time))
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *spanEventHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+	clone.attrs = append([]attribute.KeyValue(nil), h.attrs...)
+	for _, a := range attrs {
+		clone.attrs = appendSlogAttr(clone.attrs, h.prefix, a)
+	}
+	return &clone
+}
+
+func (h *spanEventHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// appendSlogAttr converts a, flattening groups into dotted keys
+func appendSlogAttr(attrs []attribute.KeyValue, prefix string, a slog.Attr) []attribute.KeyValue {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			attrs = appendSlogAttr(attrs, groupPrefix, ga)
+		}
+		return attrs
+	}
+	if a.Key == "" {
+		return attrs
+	}
+
+	key := prefix + a.Key
+	switch v.Kind() {
+	case slog.KindBool:
+		return append(attrs, attribute.Bool(key, v.Bool()))
+	case slog.KindInt64:
+		return append(attrs, attribute.Int64(key, v.Int64()))
+	case slog.KindUint64:
+		return append(attrs, attribute.Int64(key, int64(v.Uint64())))
+	case slog.KindFloat64:
+		return append(attrs, attribute.Float64(key, v.Float64()))
+	default:
+		return append(attrs, attribute.String(key, v.String()))
+	}
+}
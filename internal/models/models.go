@@ -2,12 +2,27 @@ package models
 
 import "time"
 
-// Department represents a department in the organization
+// Department represents a department in the organization. ParentID links
+// it into the hierarchy; archived departments keep their history but take
+// no new employees.
 type Department struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	ParentID    string     `json:"parent_id,omitempty"`
+	Archived    bool       `json:"archived"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+}
+
+// DepartmentNode is a department in the hierarchy with the number of
+// employees who are not fired
+type DepartmentNode struct {
+	Department
+	Headcount      int               `json:"headcount"`       // in this department
+	TotalHeadcount int               `json:"total_headcount"` // including subdepartments
+	Children       []*DepartmentNode `json:"children,omitempty"`
 }
 
 // Employee represents an employee
@@ -17,7 +32,8 @@ type Employee struct {
 	Gender       string     `json:"gender"`
 	Age          int        `json:"age"`
 	Education    string     `json:"education"`
-	Position     string     `json:"position"`
+	Position     string     `json:"position"` // title of PositionID, kept in sync on rename
+	PositionID   string     `json:"position_id,omitempty"`
 	Passport     string     `json:"passport"`
 	DepartmentID string     `json:"department_id"`
 	Status       string     `json:"status"`
@@ -26,14 +42,52 @@ type Employee struct {
 	FiredAt      *time.Time `json:"fired_at,omitempty"`
 }
 
+// Position is an entry of the positions catalog. A position with a
+// DepartmentID can only be held in that department; inactive positions
+// are kept for existing employees but cannot be assigned.
+type Position struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	DepartmentID string    `json:"department_id,omitempty"`
+	Grade        int       `json:"grade"`
+	Active       bool      `json:"active"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
 // EmployeeSearchRequest represents search filters for employees
 type EmployeeSearchRequest struct {
-	FullName  string `json:"full_name"`
-	Position  string `json:"position"`
-	Gender    string `json:"gender"`
-	Education string `json:"education"`
-	AgeFrom   *int   `json:"age_from,omitempty"`
-	AgeTo     *int   `json:"age_to,omitempty"`
+	FullName     string `json:"full_name"`
+	Position     string `json:"position"`
+	Gender       string `json:"gender"`
+	Education    string `json:"education"`
+	AgeFrom      *int   `json:"age_from,omitempty"`
+	AgeTo        *int   `json:"age_to,omitempty"`
+	DepartmentID string `json:"department_id,omitempty"`
+	Status       string `json:"status,omitempty"`
+	Passport     string `json:"passport,omitempty"`
+	PageRequest
+}
+
+// PageRequest selects the order and the page of an employee listing. Sort
+// is "name", "age", "created_at", "updated_at" or "position", by default
+// relevance for a name search and name otherwise; Order is "asc" or
+// "desc". Cursor is the NextCursor of the previous page.
+type PageRequest struct {
+	Sort   string `json:"sort,omitempty"`
+	Order  string `json:"order,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// EmployeePage is one page of an employee listing
+type EmployeePage struct {
+	Items      []Employee `json:"items"`
+	Total      int        `json:"total"`                 // on all pages
+	NextCursor string     `json:"next_cursor,omitempty"` // empty on the last page
+	// Headcount is the number of employees who are not fired, set for
+	// department listings
+	Headcount int `json:"headcount,omitempty"`
 }
 
 // StatusUpdateRequest represents a status update request
@@ -0,0 +1,504 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"employee-management/internal/models"
+)
+
+// WAL fsync policies
+const (
+	SyncAlways   = "always"   // fsync after every write
+	SyncInterval = "interval" // fsync from Run every SyncInterval
+	SyncNever    = "never"    // leave flushing to the OS
+)
+
+const (
+	snapshotFileName = "snapshot.db"
+	walFileName      = "wal.log"
+)
+
+// WAL operations
+const (
+	walOpPutEmployee   = "put_employee"
+	walOpPutDepartment = "put_department"
+	walOpPutPosition   = "put_position"
+	walOpDelPosition   = "delete_position"
+)
+
+// FileRepositoryOptions configures a FileRepository
+type FileRepositoryOptions struct {
+	Dir              string
+	Sync             string
+	SyncInterval     time.Duration
+	SnapshotInterval time.Duration // how often Run compacts the WAL into a snapshot
+}
+
+// walRecord is one mutation in the write-ahead log. Records carry the full
+// resulting entity, so replaying them is idempotent.
+type walRecord struct {
+	Seq        uint64             `json:"seq"`
+	Op         string             `json:"op"`
+	Employee   *models.Employee   `json:"employee,omitempty"`
+	Department *models.Department `json:"department,omitempty"`
+	Position   *models.Position   `json:"position,omitempty"`
+}
+
+// fileSnapshot is the compacted state up to and including record Seq
+type fileSnapshot struct {
+	Seq         uint64              `json:"seq"`
+	CreatedAt   time.Time           `json:"created_at"`
+	Departments []models.Department `json:"departments"`
+	Employees   []models.Employee   `json:"employees"`
+	Positions   []models.Position   `json:"positions"` // nil in snapshots written before positions existed
+	EmployeeSeq uint64              `json:"employee_seq"`
+	PositionSeq uint64              `json:"position_seq"` // 0 in snapshots written before it was kept
+}
+
+// FileRepository persists data to a directory. Reads are served from
+// memory; every mutation is appended to a checksummed write-ahead log
+// before it is applied and acknowledged, and the log is periodically
+// compacted into a snapshot. On open the snapshot is loaded and the log replayed, a
+// corrupted tail left by a crash is cut off and kept in a .corrupt file.
+type FileRepository struct {
+	*MemoryRepository
+
+	opts FileRepositoryOptions
+
+	mu         sync.Mutex // serializes mutations and WAL access
+	wal        *os.File
+	walSize    int64 // bytes of complete records in the WAL
+	seq        uint64
+	walRecords int   // records since the last snapshot
+	dirty      bool  // written but not synced
+	failed     error // set when the WAL could not be restored after a failed write or sync
+}
+
+// OpenFileRepository opens or creates the repository in opts.Dir. A new
+// directory is seeded with the demo data of MemoryRepository.
+func OpenFileRepository(opts FileRepositoryOptions) (*FileRepository, error) {
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать каталог хранилища: %w", err)
+	}
+
+	r := &FileRepository{MemoryRepository: newEmptyMemoryRepository(), opts: opts}
+
+	snapshotPath := filepath.Join(opts.Dir, snapshotFileName)
+	walPath := filepath.Join(opts.Dir, walFileName)
+	_, snapErr := os.Stat(snapshotPath)
+	_, walErr := os.Stat(walPath)
+	fresh := os.IsNotExist(snapErr) && os.IsNotExist(walErr)
+
+	if !fresh {
+		if err := r.loadSnapshot(snapshotPath); err != nil {
+			return nil, err
+		}
+		if err := r.replayWAL(walPath); err != nil {
+			return nil, err
+		}
+		r.linkPositions()
+	}
+
+	wal, err := os.OpenFile(walPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть журнал: %w", err)
+	}
+	r.wal = wal
+	info, err := wal.Stat()
+	if err != nil {
+		wal.Close()
+		return nil, err
+	}
+	r.walSize = info.Size()
+
+	if fresh {
+		r.initTestData()
+		if err := r.Snapshot(); err != nil {
+			wal.Close()
+			return nil, err
+		}
+	}
+	r.journal = r.logChangeLocked
+	return r, nil
+}
+
+func (r *FileRepository) loadSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		r.seedPositions(time.Now())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать снимок: %w", err)
+	}
+
+	payload, err := readFrame(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return fmt.Errorf("снимок %s поврежден: %w", path, errCorruptFrame)
+	}
+	var snap fileSnapshot
+	if err := json.Unmarshal(payload, &snap); err != nil {
+		return fmt.Errorf("снимок %s поврежден: %w", path, err)
+	}
+
+	for _, dept := range snap.Departments {
+		r.putDepartment(dept)
+	}
+	if snap.Positions == nil {
+		r.seedPositions(snap.CreatedAt)
+	}
+	for _, pos := range snap.Positions {
+		r.putPosition(pos)
+	}
+	for _, emp := range snap.Employees {
+		r.putEmployee(emp)
+	}
+	r.restoreEmployeeSequence(snap.EmployeeSeq)
+	r.restorePositionSequence(snap.PositionSeq)
+	r.seq = snap.Seq
+	return nil
+}
+
+// replayWAL applies records newer than the snapshot and truncates the log
+// after the last valid record
+func (r *FileRepository) replayWAL(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось открыть журнал: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var valid int64
+	replayed := 0
+	for {
+		payload, err := readFrame(reader)
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			var rec walRecord
+			if err = json.Unmarshal(payload, &rec); err == nil {
+				if rec.Seq > r.seq {
+					r.apply(rec)
+					r.seq = rec.Seq
+					replayed++
+				}
+				r.walRecords++
+				valid += int64(frameHeaderSize + len(payload))
+				continue
+			}
+		}
+
+		// A torn tail after a crash and damage in the middle of the log
+		// look the same here, so the dropped bytes are kept for recovery
+		corruptPath, dropped, err := saveCorruptTail(f, path, valid)
+		if err != nil {
+			return fmt.Errorf("не удалось сохранить поврежденную часть журнала: %w", err)
+		}
+		slog.Warn("Поврежденный хвост журнала отброшен",
+			"file", path, "offset", valid, "dropped_bytes", dropped, "saved_to", corruptPath)
+		if err := f.Truncate(valid); err != nil {
+			return fmt.Errorf("не удалось обрезать журнал: %w", err)
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		break
+	}
+
+	if replayed > 0 {
+		slog.Info("Журнал восстановлен", "records", replayed, "seq", r.seq)
+	}
+	return nil
+}
+
+// saveCorruptTail copies the log from offset to its end into a new
+// .corrupt file next to it and returns that file and the bytes copied
+func saveCorruptTail(f *os.File, path string, offset int64) (string, int64, error) {
+	corruptPath := fmt.Sprintf("%s.%s.corrupt", path, time.Now().UTC().Format("20060102T150405.000000000"))
+	out, err := os.OpenFile(corruptPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return "", 0, err
+	}
+	n, err := io.Copy(out, io.NewSectionReader(f, offset, math.MaxInt64-offset))
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = syncDir(filepath.Dir(corruptPath))
+	}
+	if err != nil {
+		os.Remove(corruptPath)
+		return "", 0, err
+	}
+	return corruptPath, n, nil
+}
+
+func (r *FileRepository) apply(rec walRecord) {
+	switch rec.Op {
+	case walOpPutEmployee:
+		if rec.Employee != nil {
+			r.putEmployee(*rec.Employee)
+		}
+	case walOpPutDepartment:
+		if rec.Department != nil {
+			r.putDepartment(*rec.Department)
+		}
+	case walOpPutPosition:
+		if rec.Position != nil {
+			r.putPosition(*rec.Position)
+		}
+	case walOpDelPosition:
+		if rec.Position != nil {
+			r.removePosition(rec.Position.ID)
+		}
+	default:
+		slog.Warn("Неизвестная операция в журнале", "op", rec.Op, "seq", rec.Seq)
+	}
+}
+
+// logChangeLocked is the journal of the memory repository: it appends c
+// to the WAL before the change is applied. Callers hold mu.
+func (r *FileRepository) logChangeLocked(c change) error {
+	rec := walRecord{Employee: c.employee, Department: c.department, Position: c.position}
+	switch {
+	case c.employee != nil:
+		rec.Op = walOpPutEmployee
+	case c.department != nil:
+		rec.Op = walOpPutDepartment
+	case c.position != nil:
+		rec.Op = walOpPutPosition
+	default:
+		rec.Op = walOpDelPosition
+		rec.Position = &models.Position{ID: c.deletedPosition}
+	}
+	return r.appendLocked(rec)
+}
+
+// appendLocked writes rec to the WAL, syncing it under the always policy.
+// A record that failed is cut off again; when that is not possible the
+// WAL is in an unknown state and further writes are refused.
+func (r *FileRepository) appendLocked(rec walRecord) error {
+	if r.failed != nil {
+		return fmt.Errorf("журнал недоступен для записи: %w", r.failed)
+	}
+	rec.Seq = r.seq + 1
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	frame := encodeFrame(payload)
+	if _, err := r.wal.Write(frame); err != nil {
+		// A partial frame would hide the records written after it on replay
+		r.discardLocked(err)
+		return fmt.Errorf("ошибка записи в журнал: %w", err)
+	}
+	if r.opts.Sync == SyncAlways {
+		if err := r.wal.Sync(); err != nil {
+			// The record may or may not be on disk; it must not be replayed
+			// for a change that was reported as failed
+			r.discardLocked(err)
+			return fmt.Errorf("ошибка синхронизации журнала: %w", err)
+		}
+	} else {
+		r.dirty = true
+	}
+	r.walSize += int64(len(frame))
+	r.seq = rec.Seq
+	r.walRecords++
+	return nil
+}
+
+// discardLocked cuts the WAL back to its last acknowledged record after
+// cause, marking the repository failed when that does not work
+func (r *FileRepository) discardLocked(cause error) {
+	if err := r.wal.Truncate(r.walSize); err != nil {
+		slog.Error("Не удалось отменить запись журнала, запись в хранилище остановлена", "error", err)
+		r.failed = cause
+		return
+	}
+	if err := r.wal.Sync(); err != nil {
+		slog.Error("Не удалось синхронизировать журнал, запись в хранилище остановлена", "error", err)
+		r.failed = cause
+	}
+}
+
+func (r *FileRepository) CreateEmployee(ctx context.Context, emp models.Employee) (*models.Employee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.MemoryRepository.CreateEmployee(ctx, emp)
+}
+
+func (r *FileRepository) UpdateEmployee(ctx context.Context, emp models.Employee) (*models.Employee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.MemoryRepository.UpdateEmployee(ctx, emp)
+}
+
+func (r *FileRepository) UpdateEmployeeStatus(ctx context.Context, id string, status string) (*models.Employee, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.MemoryRepository.UpdateEmployeeStatus(ctx, id, status)
+}
+
+func (r *FileRepository) CreateDepartment(ctx context.Context, dept models.Department) (*models.Department, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.MemoryRepository.CreateDepartment(ctx, dept)
+}
+
+func (r *FileRepository) UpdateDepartment(ctx context.Context, dept models.Department) (*models.Department, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.MemoryRepository.UpdateDepartment(ctx, dept)
+}
+
+func (r *FileRepository) ArchiveDepartment(ctx context.Context, id string) (*models.Department, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.MemoryRepository.ArchiveDepartment(ctx, id)
+}
+
+func (r *FileRepository) CreatePosition(ctx context.Context, pos models.Position) (*models.Position, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.MemoryRepository.CreatePosition(ctx, pos)
+}
+
+// UpdatePosition renames the employees holding the position as well; the
+// WAL record alone is enough to redo that on replay
+func (r *FileRepository) UpdatePosition(ctx context.Context, pos models.Position) (*models.Position, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.MemoryRepository.UpdatePosition(ctx, pos)
+}
+
+func (r *FileRepository) DeletePosition(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.MemoryRepository.DeletePosition(ctx, id)
+}
+
+// Snapshot writes the current state to the snapshot file and empties the WAL
+func (r *FileRepository) Snapshot() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.snapshotLocked()
+}
+
+func (r *FileRepository) snapshotLocked() error {
+	departments, employees, positions := r.state()
+	payload, err := json.Marshal(fileSnapshot{
+		Seq:         r.seq,
+		CreatedAt:   time.Now(),
+		Departments: departments,
+		Employees:   employees,
+		Positions:   positions,
+		EmployeeSeq: r.employeeSequence(),
+		PositionSeq: r.positionSequence(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(r.opts.Dir, snapshotFileName), encodeFrame(payload)); err != nil {
+		return fmt.Errorf("ошибка записи снимка: %w", err)
+	}
+
+	// Records up to seq are in the snapshot now; if the process dies before
+	// the truncation they are skipped on replay
+	if err := r.wal.Truncate(0); err != nil {
+		return fmt.Errorf("ошибка очистки журнала: %w", err)
+	}
+	if err := r.wal.Sync(); err != nil {
+		return fmt.Errorf("ошибка синхронизации журнала: %w", err)
+	}
+	r.walSize = 0
+	r.walRecords = 0
+	r.dirty = false
+	r.failed = nil
+	return nil
+}
+
+// Run syncs the WAL under the interval policy and compacts it into a
+// snapshot every SnapshotInterval until ctx is done
+func (r *FileRepository) Run(ctx context.Context) {
+	var syncC, snapshotC <-chan time.Time
+	if r.opts.Sync == SyncInterval && r.opts.SyncInterval > 0 {
+		ticker := time.NewTicker(r.opts.SyncInterval)
+		defer ticker.Stop()
+		syncC = ticker.C
+	}
+	if r.opts.SnapshotInterval > 0 {
+		ticker := time.NewTicker(r.opts.SnapshotInterval)
+		defer ticker.Stop()
+		snapshotC = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-syncC:
+			if err := r.Sync(); err != nil {
+				slog.Error("Ошибка синхронизации журнала", "error", err)
+			}
+		case <-snapshotC:
+			r.mu.Lock()
+			var err error
+			if r.walRecords > 0 {
+				err = r.snapshotLocked()
+			}
+			r.mu.Unlock()
+			if err != nil {
+				slog.Error("Ошибка создания снимка", "error", err)
+			}
+		}
+	}
+}
+
+// Sync flushes written WAL records to disk
+func (r *FileRepository) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirty {
+		return nil
+	}
+	if err := r.wal.Sync(); err != nil {
+		// Acknowledged records may be lost and the state of the file is
+		// unknown, so writes stop until a snapshot saves the whole state
+		r.failed = err
+		return fmt.Errorf("ошибка синхронизации журнала, запись в хранилище остановлена: %w", err)
+	}
+	r.dirty = false
+	return nil
+}
+
+// Close compacts the WAL into a snapshot and closes it
+func (r *FileRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.snapshotLocked()
+	if closeErr := r.wal.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
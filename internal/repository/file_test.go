@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"employee-management/internal/models"
+)
+
+func openTestFileRepository(t *testing.T, dir string) *FileRepository {
+	t.Helper()
+	repo, err := OpenFileRepository(FileRepositoryOptions{Dir: dir, Sync: SyncAlways})
+	if err != nil {
+		t.Fatalf("OpenFileRepository: %v", err)
+	}
+	return repo
+}
+
+func newTestEmployee(passport string) models.Employee {
+	return models.Employee{
+		FullName: "Смирнов Олег Петрович", Gender: "male", Age: 30, Education: "higher",
+		Position: "Тестировщик", PositionID: "pos3", Passport: passport, DepartmentID: "dept1",
+	}
+}
+
+func TestFileRepositoryReplaysWALAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	repo := openTestFileRepository(t, dir)
+
+	created, err := repo.CreateEmployee(ctx, newTestEmployee("9999 000001"))
+	if err != nil {
+		t.Fatalf("CreateEmployee: %v", err)
+	}
+	if _, _, err := repo.UpdateEmployeeStatus(ctx, created.ID, "vacation"); err != nil {
+		t.Fatalf("UpdateEmployeeStatus: %v", err)
+	}
+	// No Close: the snapshot is stale and the changes live only in the WAL
+	repo.wal.Close()
+
+	reopened := openTestFileRepository(t, dir)
+	defer reopened.Close()
+	got, err := reopened.GetEmployee(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetEmployee after reopen: %v", err)
+	}
+	if got.Status != "vacation" {
+		t.Errorf("status = %q, want vacation", got.Status)
+	}
+}
+
+func TestFileRepositoryFailedAppendLeavesMemoryUnchanged(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestFileRepository(t, t.TempDir())
+	before, _ := repo.GetEmployeeStats(ctx)
+	emp1, _ := repo.GetEmployee(ctx, "emp1")
+
+	// Writes to a closed file fail and it cannot be truncated either
+	repo.wal.Close()
+
+	if _, err := repo.CreateEmployee(ctx, newTestEmployee("9999 000002")); err == nil {
+		t.Fatal("CreateEmployee succeeded without a WAL")
+	}
+	if _, _, err := repo.UpdateEmployeeStatus(ctx, "emp1", "fired"); err == nil {
+		t.Fatal("UpdateEmployeeStatus succeeded without a WAL")
+	}
+
+	after, _ := repo.GetEmployeeStats(ctx)
+	if after["total"] != before["total"] {
+		t.Errorf("total = %v, want %v", after["total"], before["total"])
+	}
+	if owner, taken := repo.index.passportOwner("9999 000002"); taken {
+		t.Errorf("passport of the failed create is indexed for %s", owner)
+	}
+	if got, _ := repo.GetEmployee(ctx, "emp1"); got.Status != emp1.Status {
+		t.Errorf("emp1 status = %q, want %q", got.Status, emp1.Status)
+	}
+	if repo.failed == nil {
+		t.Error("repository not marked failed after the WAL could not be restored")
+	}
+}
+
+func TestFileRepositoryRefusesWritesAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestFileRepository(t, t.TempDir())
+	repo.failed = os.ErrClosed
+
+	if _, err := repo.CreateDepartment(ctx, models.Department{Name: "Новый отдел"}); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("CreateDepartment error = %v, want the recorded failure", err)
+	}
+	if err := repo.Snapshot(); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if _, err := repo.CreateDepartment(ctx, models.Department{Name: "Новый отдел"}); err != nil {
+		t.Errorf("CreateDepartment after a snapshot: %v", err)
+	}
+	repo.Close()
+}
+
+// crashWithRecords creates one employee per passport and abandons the
+// repository, returning the IDs and the end offset of each WAL frame
+func crashWithRecords(t *testing.T, dir string, passports ...string) ([]string, []int64) {
+	t.Helper()
+	ctx := context.Background()
+	repo := openTestFileRepository(t, dir)
+	var ids []string
+	var ends []int64
+	for _, passport := range passports {
+		created, err := repo.CreateEmployee(ctx, newTestEmployee(passport))
+		if err != nil {
+			t.Fatalf("CreateEmployee: %v", err)
+		}
+		info, err := repo.wal.Stat()
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		ids = append(ids, created.ID)
+		ends = append(ends, info.Size())
+	}
+	repo.wal.Close()
+	return ids, ends
+}
+
+// checkSurvivors checks which of ids exist after reopening dir and that
+// the bytes cut from the WAL were kept in one .corrupt file
+func checkSurvivors(t *testing.T, dir string, ids []string, survivors int, dropped []byte) {
+	t.Helper()
+	ctx := context.Background()
+	repo := openTestFileRepository(t, dir)
+	defer repo.Close()
+	for i, id := range ids {
+		_, err := repo.GetEmployee(ctx, id)
+		if i < survivors && err != nil {
+			t.Errorf("record %d lost: %v", i, err)
+		}
+		if i >= survivors && err == nil {
+			t.Errorf("record %d after the damage was replayed", i)
+		}
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, walFileName+".*.corrupt"))
+	if len(matches) != 1 {
+		t.Fatalf("corrupt files = %v, want one", matches)
+	}
+	saved, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !bytes.Equal(saved, dropped) {
+		t.Errorf("corrupt file holds %d bytes, want the %d dropped", len(saved), len(dropped))
+	}
+}
+
+func TestFileRepositoryCutsTornTail(t *testing.T) {
+	dir := t.TempDir()
+	ids, ends := crashWithRecords(t, dir, "9999 000011", "9999 000012")
+
+	// The crash interrupted the second append
+	walPath := filepath.Join(dir, walFileName)
+	data, _ := os.ReadFile(walPath)
+	if err := os.Truncate(walPath, ends[1]-5); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+
+	checkSurvivors(t, dir, ids, 1, data[ends[0]:ends[1]-5])
+	if info, _ := os.Stat(walPath); info.Size() != 0 {
+		t.Errorf("WAL size after reopen = %d, want 0 after the snapshot", info.Size())
+	}
+}
+
+func TestFileRepositoryKeepsRecordsAfterCorruptFrame(t *testing.T) {
+	dir := t.TempDir()
+	ids, ends := crashWithRecords(t, dir, "9999 000021", "9999 000022", "9999 000023")
+
+	// A flipped bit in the payload of the middle record fails its checksum
+	walPath := filepath.Join(dir, walFileName)
+	data, _ := os.ReadFile(walPath)
+	data[ends[0]+frameHeaderSize+3] ^= 0x01
+	if err := os.WriteFile(walPath, data, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	// Records after the damage cannot be replayed, but stay on disk
+	checkSurvivors(t, dir, ids, 1, data[ends[0]:])
+}
+
+func TestFileRepositoryFailedIntervalSyncStopsWrites(t *testing.T) {
+	ctx := context.Background()
+	repo, err := OpenFileRepository(FileRepositoryOptions{Dir: t.TempDir(), Sync: SyncInterval})
+	if err != nil {
+		t.Fatalf("OpenFileRepository: %v", err)
+	}
+	if _, err := repo.CreateEmployee(ctx, newTestEmployee("9999 000031")); err != nil {
+		t.Fatalf("CreateEmployee: %v", err)
+	}
+	repo.wal.Close()
+
+	if err := repo.Sync(); err == nil {
+		t.Fatal("Sync of a closed WAL succeeded")
+	}
+	if repo.failed == nil {
+		t.Error("repository not marked failed after a failed sync")
+	}
+	if _, err := repo.CreateEmployee(ctx, newTestEmployee("9999 000032")); err == nil {
+		t.Error("CreateEmployee succeeded after a failed sync")
+	}
+}
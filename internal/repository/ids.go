@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Employee ID generators
+const (
+	IDGeneratorSequence = "sequence"
+	IDGeneratorUUIDv7   = "uuidv7"
+	IDGeneratorULID     = "ulid"
+)
+
+// Prefixes of sequence IDs: emp1, emp2, ..., dept1, dept2, ...
+const (
+	employeeIDPrefix   = "emp"
+	departmentIDPrefix = "dept"
+	positionIDPrefix   = "pos"
+)
+
+// IDGenerator issues IDs for new employees. IDs are never reused; how far
+// they are unique depends on the generator.
+type IDGenerator interface {
+	NewID(ctx context.Context) (string, error)
+}
+
+// EmployeeSequence is implemented by stores that keep the counter of
+// "empN" employee IDs, persisted together with their data
+type EmployeeSequence interface {
+	// NextEmployeeNumber returns an N no employee ID of the store has had
+	NextEmployeeNumber(ctx context.Context) (uint64, error)
+}
+
+// NewIDGenerator returns the generator of the given kind. The sequence
+// kind draws from seq.
+func NewIDGenerator(kind string, seq EmployeeSequence) (IDGenerator, error) {
+	switch kind {
+	case IDGeneratorSequence:
+		return NewSequenceGenerator(seq), nil
+	case IDGeneratorUUIDv7:
+		return &UUIDv7Generator{}, nil
+	case IDGeneratorULID:
+		return &ULIDGenerator{}, nil
+	default:
+		return nil, fmt.Errorf("неизвестный генератор идентификаторов: %s", kind)
+	}
+}
+
+// SequenceGenerator issues "emp1", "emp2", ... from the counter of a
+// store. IDs are unique within that store only, not across stores, and
+// the numbers of failed inserts are skipped.
+type SequenceGenerator struct {
+	seq EmployeeSequence
+}
+
+// NewSequenceGenerator creates a generator drawing from seq
+func NewSequenceGenerator(seq EmployeeSequence) *SequenceGenerator {
+	return &SequenceGenerator{seq: seq}
+}
+
+func (g *SequenceGenerator) NewID(ctx context.Context) (string, error) {
+	n, err := g.seq.NextEmployeeNumber(ctx)
+	if err != nil {
+		return "", err
+	}
+	return sequenceID(employeeIDPrefix, n), nil
+}
+
+// sequenceNumber returns N of a sequence ID prefix+N
+func sequenceNumber(prefix, id string) (uint64, bool) {
+	digits, ok := strings.CutPrefix(id, prefix)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(digits, 10, 64)
+	return n, err == nil
+}
+
+func sequenceID(prefix string, n uint64) string {
+	return prefix + strconv.FormatUint(n, 10)
+}
+
+// monotonicClock hands out millisecond timestamps that never go back, so
+// time-ordered IDs stay sorted when the wall clock is adjusted
+type monotonicClock struct {
+	lastMs uint64
+}
+
+// tick returns the timestamp for the next ID and whether it equals the
+// previous one
+func (c *monotonicClock) tick() (uint64, bool) {
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= c.lastMs {
+		return c.lastMs, true
+	}
+	c.lastMs = ms
+	return ms, false
+}
+
+// UUIDv7Generator issues RFC 9562 version 7 UUIDs, unique across
+// processes. Within one millisecond the 12-bit rand_a field is used as a
+// counter starting at a random value, so IDs of one process are strictly
+// increasing.
+type UUIDv7Generator struct {
+	mu      sync.Mutex
+	clock   monotonicClock
+	counter uint16
+}
+
+func (g *UUIDv7Generator) NewID(ctx context.Context) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", fmt.Errorf("ошибка генерации UUID: %w", err)
+	}
+
+	g.mu.Lock()
+	ms, same := g.clock.tick()
+	if same {
+		g.counter++
+		if g.counter > 0xfff {
+			// Counter exhausted: borrow the next millisecond
+			g.clock.lastMs++
+			ms = g.clock.lastMs
+			g.counter = binary.BigEndian.Uint16(b[6:8]) & 0x7ff
+		}
+	} else {
+		// The top bit stays clear to leave room for increments
+		g.counter = binary.BigEndian.Uint16(b[6:8]) & 0x7ff
+	}
+	counter := g.counter
+	g.mu.Unlock()
+
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	binary.BigEndian.PutUint16(b[6:8], 0x7000|counter)
+	b[8] = b[8]&0x3f | 0x80 // RFC 9562 variant
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:]), nil
+}
+
+// crockford is the ULID base32 alphabet
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator issues ULIDs, unique across processes: a 48-bit
+// millisecond timestamp and 80 random bits in Crockford base32. Within one
+// millisecond the random part of the previous ID is incremented, as in the
+// monotonic ULID spec.
+type ULIDGenerator struct {
+	mu      sync.Mutex
+	clock   monotonicClock
+	entropy [10]byte
+}
+
+func (g *ULIDGenerator) NewID(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms, same := g.clock.tick()
+	if same && !incrementBytes(g.entropy[:]) {
+		// Random part overflowed: borrow the next millisecond
+		g.clock.lastMs++
+		ms = g.clock.lastMs
+		same = false
+	}
+	if !same {
+		if _, err := rand.Read(g.entropy[:]); err != nil {
+			return "", fmt.Errorf("ошибка генерации ULID: %w", err)
+		}
+	}
+
+	var b [16]byte
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	copy(b[6:], g.entropy[:])
+	return encodeULID(b), nil
+}
+
+// incrementBytes adds one to a big-endian number, reporting false on
+// overflow
+func incrementBytes(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeULID writes 128 bits as 26 base32 characters, the first one
+// carrying the top 3 bits
+func encodeULID(b [16]byte) string {
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	var s [26]byte
+	for i := 25; i >= 0; i-- {
+		s[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
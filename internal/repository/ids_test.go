@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	uuidv7Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulidPattern   = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+)
+
+// uuidv7Millis returns the timestamp of a UUIDv7
+func uuidv7Millis(t *testing.T, id string) uint64 {
+	t.Helper()
+	ms, err := strconv.ParseUint(strings.ReplaceAll(id[:13], "-", ""), 16, 64)
+	if err != nil {
+		t.Fatalf("timestamp of %s: %v", id, err)
+	}
+	return ms
+}
+
+// ulidMillis returns the timestamp of a ULID, its first 10 characters
+func ulidMillis(id string) uint64 {
+	var ms uint64
+	for _, c := range id[:10] {
+		ms = ms<<5 | uint64(strings.IndexRune(crockford, c))
+	}
+	return ms
+}
+
+// checkIncreasing issues n IDs and checks that each sorts after the last
+func checkIncreasing(t *testing.T, gen IDGenerator, n int, check func(id string)) {
+	t.Helper()
+	var last string
+	for i := 0; i < n; i++ {
+		id, err := gen.NewID(context.Background())
+		if err != nil {
+			t.Fatalf("NewID: %v", err)
+		}
+		check(id)
+		if id <= last {
+			t.Fatalf("ID %d = %s, not after %s", i, id, last)
+		}
+		last = id
+	}
+}
+
+func TestUUIDv7Format(t *testing.T) {
+	before := uint64(time.Now().UnixMilli())
+	checkIncreasing(t, &UUIDv7Generator{}, 100, func(id string) {
+		if !uuidv7Pattern.MatchString(id) {
+			t.Fatalf("%s is not a version 7 UUID of the RFC 9562 variant", id)
+		}
+		if ms := uuidv7Millis(t, id); ms < before || ms > uint64(time.Now().UnixMilli()) {
+			t.Fatalf("timestamp of %s = %d, not the time it was issued", id, ms)
+		}
+	})
+}
+
+func TestUUIDv7IncreasesWithinOneMillisecond(t *testing.T) {
+	// A clock ahead of the wall clock keeps every ID in its millisecond
+	ms := uint64(time.Now().Add(time.Hour).UnixMilli())
+	gen := &UUIDv7Generator{clock: monotonicClock{lastMs: ms}}
+	checkIncreasing(t, gen, 1000, func(id string) {
+		if !uuidv7Pattern.MatchString(id) {
+			t.Fatalf("%s is not a version 7 UUID of the RFC 9562 variant", id)
+		}
+		if got := uuidv7Millis(t, id); got != ms {
+			t.Fatalf("timestamp of %s = %d, want %d", id, got, ms)
+		}
+	})
+
+	// An exhausted counter moves on to the next millisecond
+	gen = &UUIDv7Generator{clock: monotonicClock{lastMs: ms}, counter: 0xfff}
+	id, _ := gen.NewID(context.Background())
+	if got := uuidv7Millis(t, id); got != ms+1 {
+		t.Errorf("timestamp after the counter ran out = %d, want %d", got, ms+1)
+	}
+}
+
+func TestULIDFormat(t *testing.T) {
+	before := uint64(time.Now().UnixMilli())
+	checkIncreasing(t, &ULIDGenerator{}, 100, func(id string) {
+		if !ulidPattern.MatchString(id) {
+			t.Fatalf("%s is not a ULID", id)
+		}
+		if ms := ulidMillis(id); ms < before || ms > uint64(time.Now().UnixMilli()) {
+			t.Fatalf("timestamp of %s = %d, not the time it was issued", id, ms)
+		}
+	})
+}
+
+func TestULIDIncreasesWithinOneMillisecond(t *testing.T) {
+	ms := uint64(time.Now().Add(time.Hour).UnixMilli())
+	gen := &ULIDGenerator{clock: monotonicClock{lastMs: ms}}
+	checkIncreasing(t, gen, 1000, func(id string) {
+		if got := ulidMillis(id); got != ms {
+			t.Fatalf("timestamp of %s = %d, want %d", id, got, ms)
+		}
+	})
+
+	// An overflowing random part moves on to the next millisecond
+	gen = &ULIDGenerator{clock: monotonicClock{lastMs: ms}}
+	for i := range gen.entropy {
+		gen.entropy[i] = 0xff
+	}
+	id, _ := gen.NewID(context.Background())
+	if got := ulidMillis(id); got != ms+1 {
+		t.Errorf("timestamp after the random part overflowed = %d, want %d", got, ms+1)
+	}
+}
+
+func TestSequenceGeneratorDrawsFromTheStore(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	gen, err := NewIDGenerator(IDGeneratorSequence, repo)
+	if err != nil {
+		t.Fatalf("NewIDGenerator: %v", err)
+	}
+	repo.SetIDGenerator(gen)
+
+	// The demo data ends at emp4
+	if id, _ := gen.NewID(ctx); id != "emp5" {
+		t.Errorf("first ID = %s, want emp5", id)
+	}
+	created, err := repo.CreateEmployee(ctx, newTestEmployee("9999 000001"))
+	if err != nil {
+		t.Fatalf("CreateEmployee: %v", err)
+	}
+	if created.ID != "emp6" {
+		t.Errorf("created ID = %s, want emp6 from the same sequence", created.ID)
+	}
+}
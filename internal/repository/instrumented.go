@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"employee-management/internal/models"
+	"employee-management/internal/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentedRepository wraps a Repository with per-method metrics and a
+// child span per call
+type InstrumentedRepository struct {
+	repo    Repository
+	metrics *telemetry.Metrics
+	tracer  trace.Tracer
+}
+
+// NewInstrumentedRepository wraps repo
+func NewInstrumentedRepository(repo Repository, metrics *telemetry.Metrics) *InstrumentedRepository {
+	return &InstrumentedRepository{
+		repo:    repo,
+		metrics: metrics,
+		tracer:  otel.Tracer("employee-repository"),
+	}
+}
+
+// repoCall tracks one repository call from start to end
+type repoCall struct {
+	metrics *telemetry.Metrics
+	method  string
+	span    trace.Span
+	start   time.Time
+}
+
+func (r *InstrumentedRepository) begin(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, *repoCall) {
+	ctx, span := r.tracer.Start(ctx, "Repository."+method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, &repoCall{metrics: r.metrics, method: method, span: span, start: time.Now()}
+}
+
+func (c *repoCall) end(err error) {
+	c.metrics.RecordRepositoryCall(c.method, time.Since(c.start), err)
+	if err != nil {
+		c.span.RecordError(err)
+		c.span.SetStatus(codes.Error, err.Error())
+	}
+	c.span.End()
+}
+
+// endList also records the number of returned records
+func (c *repoCall) endList(size int, err error) {
+	if err == nil {
+		c.metrics.RecordRepositoryResultSize(c.method, size)
+		c.span.SetAttributes(attribute.Int("repository.result_size", size))
+	}
+	c.end(err)
+}
+
+func (r *InstrumentedRepository) GetDepartments(ctx context.Context) ([]models.Department, error) {
+	ctx, call := r.begin(ctx, "GetDepartments")
+	departments, err := r.repo.GetDepartments(ctx)
+	call.end(err)
+	return departments, err
+}
+
+func (r *InstrumentedRepository) GetDepartment(ctx context.Context, id string) (*models.Department, error) {
+	ctx, call := r.begin(ctx, "GetDepartment", attribute.String("department_id", id))
+	dept, err := r.repo.GetDepartment(ctx, id)
+	call.end(err)
+	return dept, err
+}
+
+func (r *InstrumentedRepository) CreateDepartment(ctx context.Context, dept models.Department) (*models.Department, error) {
+	ctx, call := r.begin(ctx, "CreateDepartment", attribute.String("department.parent_id", dept.ParentID))
+	created, err := r.repo.CreateDepartment(ctx, dept)
+	call.end(err)
+	return created, err
+}
+
+func (r *InstrumentedRepository) UpdateDepartment(ctx context.Context, dept models.Department) (*models.Department, error) {
+	ctx, call := r.begin(ctx, "UpdateDepartment", attribute.String("department_id", dept.ID))
+	updated, err := r.repo.UpdateDepartment(ctx, dept)
+	call.end(err)
+	return updated, err
+}
+
+func (r *InstrumentedRepository) ArchiveDepartment(ctx context.Context, id string) (*models.Department, error) {
+	ctx, call := r.begin(ctx, "ArchiveDepartment", attribute.String("department_id", id))
+	archived, err := r.repo.ArchiveDepartment(ctx, id)
+	call.end(err)
+	return archived, err
+}
+
+func (r *InstrumentedRepository) GetEmployeesByDepartment(ctx context.Context, departmentID string) ([]models.Employee, error) {
+	ctx, call := r.begin(ctx, "GetEmployeesByDepartment", attribute.String("department_id", departmentID))
+	employees, err := r.repo.GetEmployeesByDepartment(ctx, departmentID)
+	call.endList(len(employees), err)
+	return employees, err
+}
+
+func (r *InstrumentedRepository) GetEmployee(ctx context.Context, id string) (*models.Employee, error) {
+	ctx, call := r.begin(ctx, "GetEmployee", attribute.String("employee_id", id))
+	emp, err := r.repo.GetEmployee(ctx, id)
+	call.end(err)
+	return emp, err
+}
+
+func (r *InstrumentedRepository) SearchEmployees(ctx context.Context, req models.EmployeeSearchRequest) ([]models.Employee, error) {
+	ctx, call := r.begin(ctx, "SearchEmployees", SearchAttributes(req)...)
+	employees, err := r.repo.SearchEmployees(ctx, req)
+	call.endList(len(employees), err)
+	return employees, err
+}
+
+func (r *InstrumentedRepository) SearchEmployeesPage(ctx context.Context, req models.EmployeeSearchRequest, q PageQuery) ([]models.Employee, int, error) {
+	ctx, call := r.begin(ctx, "SearchEmployeesPage", append(SearchAttributes(req),
+		attribute.String("page.sort", q.Sort),
+		attribute.Bool("page.continued", q.After != nil),
+	)...)
+	employees, total, err := r.repo.SearchEmployeesPage(ctx, req, q)
+	call.endList(len(employees), err)
+	return employees, total, err
+}
+
+func (r *InstrumentedRepository) CreateEmployee(ctx context.Context, emp models.Employee) (*models.Employee, error) {
+	ctx, call := r.begin(ctx, "CreateEmployee", attribute.String("department_id", emp.DepartmentID))
+	created, err := r.repo.CreateEmployee(ctx, emp)
+	call.end(err)
+	return created, err
+}
+
+func (r *InstrumentedRepository) UpdateEmployee(ctx context.Context, emp models.Employee) (*models.Employee, error) {
+	ctx, call := r.begin(ctx, "UpdateEmployee", attribute.String("employee_id", emp.ID))
+	updated, err := r.repo.UpdateEmployee(ctx, emp)
+	call.end(err)
+	return updated, err
+}
+
+func (r *InstrumentedRepository) UpdateEmployeeStatus(ctx context.Context, id string, status string) (*models.Employee, string, error) {
+	ctx, call := r.begin(ctx, "UpdateEmployeeStatus",
+		attribute.String("employee_id", id),
+		attribute.String("employee.status", status),
+	)
+	updated, previous, err := r.repo.UpdateEmployeeStatus(ctx, id, status)
+	call.end(err)
+	return updated, previous, err
+}
+
+func (r *InstrumentedRepository) GetPositions(ctx context.Context) ([]models.Position, error) {
+	ctx, call := r.begin(ctx, "GetPositions")
+	positions, err := r.repo.GetPositions(ctx)
+	call.end(err)
+	return positions, err
+}
+
+func (r *InstrumentedRepository) GetPosition(ctx context.Context, id string) (*models.Position, error) {
+	ctx, call := r.begin(ctx, "GetPosition", attribute.String("position_id", id))
+	pos, err := r.repo.GetPosition(ctx, id)
+	call.end(err)
+	return pos, err
+}
+
+func (r *InstrumentedRepository) CreatePosition(ctx context.Context, pos models.Position) (*models.Position, error) {
+	ctx, call := r.begin(ctx, "CreatePosition", attribute.String("position.department_id", pos.DepartmentID))
+	created, err := r.repo.CreatePosition(ctx, pos)
+	call.end(err)
+	return created, err
+}
+
+func (r *InstrumentedRepository) UpdatePosition(ctx context.Context, pos models.Position) (*models.Position, error) {
+	ctx, call := r.begin(ctx, "UpdatePosition", attribute.String("position_id", pos.ID))
+	updated, err := r.repo.UpdatePosition(ctx, pos)
+	call.end(err)
+	return updated, err
+}
+
+func (r *InstrumentedRepository) DeletePosition(ctx context.Context, id string) error {
+	ctx, call := r.begin(ctx, "DeletePosition", attribute.String("position_id", id))
+	err := r.repo.DeletePosition(ctx, id)
+	call.end(err)
+	return err
+}
+
+func (r *InstrumentedRepository) GetEmployeeStats(ctx context.Context) (map[string]interface{}, error) {
+	ctx, call := r.begin(ctx, "GetEmployeeStats")
+	stats, err := r.repo.GetEmployeeStats(ctx)
+	call.end(err)
+	return stats, err
+}
+
+// SearchAttributes describes the filters of a search request as span attributes
+func SearchAttributes(req models.EmployeeSearchRequest) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if req.FullName != "" {
+		attrs = append(attrs, attribute.String("search.full_name", req.FullName))
+	}
+	if req.Position != "" {
+		attrs = append(attrs, attribute.String("search.position", req.Position))
+	}
+	if req.Gender != "" {
+		attrs = append(attrs, attribute.String("search.gender", req.Gender))
+	}
+	if req.Education != "" {
+		attrs = append(attrs, attribute.String("search.education", req.Education))
+	}
+	if req.AgeFrom != nil {
+		attrs = append(attrs, attribute.Int("search.age_from", *req.AgeFrom))
+	}
+	if req.AgeTo != nil {
+		attrs = append(attrs, attribute.Int("search.age_to", *req.AgeTo))
+	}
+	if req.DepartmentID != "" {
+		attrs = append(attrs, attribute.String("search.department_id", req.DepartmentID))
+	}
+	if req.Status != "" {
+		attrs = append(attrs, attribute.String("search.status", req.Status))
+	}
+	// The passport itself is personal data and stays out of traces
+	if req.Passport != "" {
+		attrs = append(attrs, attribute.Bool("search.by_passport", true))
+	}
+	return attrs
+}
@@ -9,69 +9,218 @@ import (
 	"employee-management/internal/models"
 )
 
+// defaultPositions is the initial positions catalog
+var defaultPositions = []models.Position{
+	{ID: "pos1", Title: "Программист", Grade: 2},
+	{ID: "pos2", Title: "Аналитик", Grade: 2},
+	{ID: "pos3", Title: "Тестировщик", Grade: 2},
+	{ID: "pos4", Title: "Менеджер по продажам", Grade: 2},
+	{ID: "pos5", Title: "HR-менеджер", Grade: 2},
+	{ID: "pos6", Title: "Бухгалтер", Grade: 2},
+	{ID: "pos7", Title: "Маркетолог", Grade: 2},
+	{ID: "pos8", Title: "Дизайнер", Grade: 2},
+	{ID: "pos9", Title: "Системный администратор", Grade: 2},
+	{ID: "pos10", Title: "Руководитель отдела", Grade: 4},
+}
+
 // MemoryRepository is an in-memory implementation of Repository
 type MemoryRepository struct {
 	mu          sync.RWMutex
 	departments map[string]models.Department
 	employees   map[string]models.Employee
-	positions   []string
+	positions   map[string]models.Position
+	index       *employeeIndex
+
+	ids           IDGenerator // by default a SequenceGenerator over employeeSeq
+	employeeSeq   uint64      // highest N of the "empN" IDs issued or loaded
+	departmentSeq uint64      // highest N of the "deptN" IDs issued or loaded
+	positionSeq   uint64      // highest N of the "posN" IDs ever issued or loaded
+
+	// journal, when set, receives every change under the write lock before
+	// it is applied; an error aborts the change
+	journal func(change) error
+}
+
+// change is a mutation as handed to the journal. Exactly one field is set.
+type change struct {
+	employee        *models.Employee
+	department      *models.Department
+	position        *models.Position
+	deletedPosition string
 }
 
 // NewMemoryRepository creates a new in-memory repository with test data
 func NewMemoryRepository() *MemoryRepository {
-	repo := &MemoryRepository{
+	repo := newEmptyMemoryRepository()
+	repo.initTestData()
+	return repo
+}
+
+func newEmptyMemoryRepository() *MemoryRepository {
+	r := &MemoryRepository{
 		departments: make(map[string]models.Department),
 		employees:   make(map[string]models.Employee),
-		positions: []string{
-			"Программист", "Аналитик", "Тестировщик", "Менеджер по продажам",
-			"HR-менеджер", "Бухгалтер", "Маркетолог", "Дизайнер",
-			"Системный администратор", "Руководитель отдела",
-		},
+		positions:   make(map[string]models.Position),
+		index:       newEmployeeIndex(),
+	}
+	r.ids = NewSequenceGenerator(r)
+	return r
+}
+
+// SetIDGenerator sets the generator of employee IDs. With nil, the default,
+// IDs are "emp1", "emp2", ... from a sequence that only grows, so IDs of
+// removed employees are never handed out again.
+func (r *MemoryRepository) SetIDGenerator(gen IDGenerator) {
+	if gen == nil {
+		gen = NewSequenceGenerator(r)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = gen
+}
+
+// NextEmployeeNumber moves the employee sequence past the last number
+// issued or loaded and returns it
+func (r *MemoryRepository) NextEmployeeNumber(ctx context.Context) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		r.employeeSeq++
+		if _, exists := r.employees[sequenceID(employeeIDPrefix, r.employeeSeq)]; !exists {
+			return r.employeeSeq, nil
+		}
+	}
+}
+
+// journalLocked passes c to the journal, if any
+func (r *MemoryRepository) journalLocked(c change) error {
+	if r.journal == nil {
+		return nil
+	}
+	return r.journal(c)
+}
+
+// nextDepartmentIDLocked returns an unused "deptN" ID. Departments are
+// never removed, so the highest N seen is enough to stay unique.
+func (r *MemoryRepository) nextDepartmentIDLocked() string {
+	for {
+		r.departmentSeq++
+		id := sequenceID(departmentIDPrefix, r.departmentSeq)
+		if _, exists := r.departments[id]; !exists {
+			return id
+		}
+	}
+}
+
+// nextPositionIDLocked returns a "posN" ID that was never issued, so the
+// ID of a deleted position is not handed out again
+func (r *MemoryRepository) nextPositionIDLocked() string {
+	for {
+		r.positionSeq++
+		id := sequenceID(positionIDPrefix, r.positionSeq)
+		if _, exists := r.positions[id]; !exists {
+			return id
+		}
+	}
+}
+
+// putDepartmentLocked stores dept and moves the department sequence past its ID
+func (r *MemoryRepository) putDepartmentLocked(dept models.Department) {
+	r.departments[dept.ID] = dept
+	if n, ok := sequenceNumber(departmentIDPrefix, dept.ID); ok && n > r.departmentSeq {
+		r.departmentSeq = n
+	}
+}
+
+// observeEmployeeIDLocked moves the sequence past id
+func (r *MemoryRepository) observeEmployeeIDLocked(id string) {
+	if n, ok := sequenceNumber(employeeIDPrefix, id); ok && n > r.employeeSeq {
+		r.employeeSeq = n
+	}
+}
+
+// seedPositions adds the default positions catalog
+func (r *MemoryRepository) seedPositions(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pos := range defaultPositions {
+		pos.Active = true
+		pos.CreatedAt = now
+		pos.UpdatedAt = now
+		r.putPositionLocked(pos)
+	}
+}
+
+// linkPositions sets PositionID of employees that only have a title, as
+// stored before positions became entities
+func (r *MemoryRepository) linkPositions() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, emp := range r.employees {
+		if emp.PositionID != "" {
+			continue
+		}
+		for _, pos := range r.positions {
+			if pos.Title == emp.Position {
+				emp.PositionID = pos.ID
+				r.setEmployeeLocked(emp)
+				break
+			}
+		}
 	}
-	repo.initTestData()
-	return repo
 }
 
 func (r *MemoryRepository) initTestData() {
 	now := time.Now()
-	depts := []models.Department{
-		{ID: "dept1", Name: "IT-департамент", Description: "Разработка ПО", CreatedAt: now},
-		{ID: "dept2", Name: "Отдел продаж", Description: "Продажи и маркетинг", CreatedAt: now},
-		{ID: "dept3", Name: "HR-отдел", Description: "Управление персоналом", CreatedAt: now},
-		{ID: "dept4", Name: "Финансовый отдел", Description: "Финансы и бухгалтерия", CreatedAt: now},
-		{ID: "dept5", Name: "Маркетинг", Description: "Маркетинг и реклама", CreatedAt: now},
+	r.seedPositions(now)
+
+	departments, employees := demoData(now)
+	for _, dept := range departments {
+		r.putDepartmentLocked(dept)
 	}
+	for _, emp := range employees {
+		r.setEmployeeLocked(emp)
+		r.observeEmployeeIDLocked(emp.ID)
+	}
+}
 
-	for _, dept := range depts {
-		r.departments[dept.ID] = dept
+// demoData returns the demo departments and employees, created at now.
+// Employees refer to the default positions.
+func demoData(now time.Time) ([]models.Department, []models.Employee) {
+	depts := []models.Department{
+		{ID: "dept1", Name: "IT-департамент", Description: "Разработка ПО", CreatedAt: now, UpdatedAt: now},
+		{ID: "dept2", Name: "Отдел продаж", Description: "Продажи и маркетинг", CreatedAt: now, UpdatedAt: now},
+		{ID: "dept3", Name: "HR-отдел", Description: "Управление персоналом", CreatedAt: now, UpdatedAt: now},
+		{ID: "dept4", Name: "Финансовый отдел", Description: "Финансы и бухгалтерия", CreatedAt: now, UpdatedAt: now},
+		{ID: "dept5", Name: "Маркетинг", Description: "Маркетинг и реклама", CreatedAt: now, UpdatedAt: now},
 	}
 
 	employees := []models.Employee{
 		{
 			ID: "emp1", FullName: "Иванов Иван Иванович", Gender: "male", Age: 35,
-			Education: "higher", Position: "Программист", Passport: "1234 567890",
+			Education: "higher", Position: "Программист", PositionID: "pos1", Passport: "1234 567890",
 			DepartmentID: "dept1", Status: "active", CreatedAt: now, UpdatedAt: now,
 		},
 		{
 			ID: "emp2", FullName: "Петрова Анна Сергеевна", Gender: "female", Age: 28,
-			Education: "higher", Position: "Аналитик", Passport: "2345 678901",
+			Education: "higher", Position: "Аналитик", PositionID: "pos2", Passport: "2345 678901",
 			DepartmentID: "dept1", Status: "vacation", CreatedAt: now, UpdatedAt: now,
 		},
 		{
 			ID: "emp3", FullName: "Сидоров Петр Александрович", Gender: "male", Age: 42,
-			Education: "higher", Position: "Менеджер по продажам", Passport: "3456 789012",
+			Education: "higher", Position: "Менеджер по продажам", PositionID: "pos4", Passport: "3456 789012",
 			DepartmentID: "dept2", Status: "active", CreatedAt: now, UpdatedAt: now,
 		},
 		{
 			ID: "emp4", FullName: "Козлова Мария Викторовна", Gender: "female", Age: 31,
-			Education: "higher", Position: "HR-менеджер", Passport: "4567 890123",
+			Education: "higher", Position: "HR-менеджер", PositionID: "pos5", Passport: "4567 890123",
 			DepartmentID: "dept3", Status: "active", CreatedAt: now, UpdatedAt: now,
 		},
 	}
 
-	for _, emp := range employees {
-		r.employees[emp.ID] = emp
-	}
+	return depts, employees
 }
 
 func (r *MemoryRepository) GetDepartments(ctx context.Context) ([]models.Department, error) {
@@ -85,59 +234,180 @@ func (r *MemoryRepository) GetDepartments(ctx context.Context) ([]models.Departm
 	return departments, nil
 }
 
-func (r *MemoryRepository) GetEmployeesByDepartment(ctx context.Context, departmentID string) ([]models.Employee, error) {
+func (r *MemoryRepository) GetDepartment(ctx context.Context, id string) (*models.Department, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	var employees []models.Employee
-	for _, emp := range r.employees {
-		if emp.DepartmentID == departmentID {
-			employees = append(employees, emp)
+	dept, exists := r.departments[id]
+	if !exists {
+		return nil, ErrDepartmentNotFound
+	}
+	return &dept, nil
+}
+
+func (r *MemoryRepository) CreateDepartment(ctx context.Context, dept models.Department) (*models.Department, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dept.ID = r.nextDepartmentIDLocked()
+	now := time.Now()
+	dept.CreatedAt = now
+	dept.UpdatedAt = now
+	dept.Archived = false
+	dept.ArchivedAt = nil
+
+	if err := r.journalLocked(change{department: &dept}); err != nil {
+		return nil, err
+	}
+	r.departments[dept.ID] = dept
+	return &dept, nil
+}
+
+func (r *MemoryRepository) UpdateDepartment(ctx context.Context, dept models.Department) (*models.Department, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.departments[dept.ID]
+	if !exists {
+		return nil, ErrDepartmentNotFound
+	}
+
+	existing.Name = dept.Name
+	existing.Description = dept.Description
+	existing.ParentID = dept.ParentID
+	existing.UpdatedAt = time.Now()
+
+	if err := r.journalLocked(change{department: &existing}); err != nil {
+		return nil, err
+	}
+	r.departments[dept.ID] = existing
+	return &existing, nil
+}
+
+func (r *MemoryRepository) ArchiveDepartment(ctx context.Context, id string) (*models.Department, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dept, exists := r.departments[id]
+	if !exists {
+		return nil, ErrDepartmentNotFound
+	}
+	for empID := range r.index.byDepartment[id] {
+		if r.employees[empID].Status != "fired" {
+			return nil, ErrDepartmentHasEmployees
 		}
 	}
-	return employees, nil
+	for _, child := range r.departments {
+		if child.ParentID == id && !child.Archived {
+			return nil, ErrDepartmentHasChildren
+		}
+	}
+
+	now := time.Now()
+	dept.Archived = true
+	dept.ArchivedAt = &now
+	dept.UpdatedAt = now
+
+	if err := r.journalLocked(change{department: &dept}); err != nil {
+		return nil, err
+	}
+	r.departments[id] = dept
+	return &dept, nil
+}
+
+func (r *MemoryRepository) GetEmployeesByDepartment(ctx context.Context, departmentID string) ([]models.Employee, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.employeesLocked(r.index.byDepartment[departmentID]), nil
 }
 
+func (r *MemoryRepository) GetEmployee(ctx context.Context, id string) (*models.Employee, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	emp, exists := r.employees[id]
+	if !exists {
+		return nil, ErrEmployeeNotFound
+	}
+	return &emp, nil
+}
+
+// SearchEmployees starts from the smallest index entry the request filters
+// on and only scans all employees when it filters on no indexed field
 func (r *MemoryRepository) SearchEmployees(ctx context.Context, req models.EmployeeSearchRequest) ([]models.Employee, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	var employees []models.Employee
-	for _, emp := range r.employees {
-		if req.FullName != "" && !contains(emp.FullName, req.FullName) {
-			continue
-		}
-		if req.Position != "" && emp.Position != req.Position {
-			continue
+	if ids, ok := r.index.lookup(req); ok {
+		for id := range ids {
+			if emp := r.employees[id]; matchesSearch(emp, req) {
+				employees = append(employees, emp)
+			}
 		}
-		if req.Gender != "" && emp.Gender != req.Gender {
-			continue
-		}
-		if req.Education != "" && emp.Education != req.Education {
-			continue
-		}
-		if req.AgeFrom != nil && emp.Age < *req.AgeFrom {
-			continue
-		}
-		if req.AgeTo != nil && emp.Age > *req.AgeTo {
-			continue
+	} else {
+		for _, emp := range r.employees {
+			if matchesSearch(emp, req) {
+				employees = append(employees, emp)
+			}
 		}
-		employees = append(employees, emp)
+	}
+	if req.FullName != "" {
+		employees = rankByName(employees, req.FullName)
 	}
 	return employees, nil
 }
 
+func (r *MemoryRepository) SearchEmployeesPage(ctx context.Context, req models.EmployeeSearchRequest, q PageQuery) ([]models.Employee, int, error) {
+	employees, err := r.SearchEmployees(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, err := pageEmployees(employees, q)
+	return page, len(employees), err
+}
+
+// matchesSearch reports whether emp passes the filters of req other than
+// the name, which is ranked instead
+func matchesSearch(emp models.Employee, req models.EmployeeSearchRequest) bool {
+	switch {
+	case req.Passport != "" && emp.Passport != req.Passport,
+		req.DepartmentID != "" && emp.DepartmentID != req.DepartmentID,
+		req.Status != "" && emp.Status != req.Status,
+		req.Position != "" && emp.Position != req.Position,
+		req.Gender != "" && emp.Gender != req.Gender,
+		req.Education != "" && emp.Education != req.Education,
+		req.AgeFrom != nil && emp.Age < *req.AgeFrom,
+		req.AgeTo != nil && emp.Age > *req.AgeTo:
+		return false
+	}
+	return true
+}
+
 func (r *MemoryRepository) CreateEmployee(ctx context.Context, emp models.Employee) (*models.Employee, error) {
+	// The sequence generator takes the lock itself
+	r.mu.RLock()
+	ids := r.ids
+	r.mu.RUnlock()
+	id, err := ids.NewID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выдачи идентификатора: %w", err)
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	for _, existing := range r.employees {
-		if existing.Passport == emp.Passport {
-			return nil, fmt.Errorf("сотрудник с таким паспортом уже существует")
-		}
+	if dept, exists := r.departments[emp.DepartmentID]; exists && dept.Archived {
+		return nil, ErrDepartmentArchived
 	}
-
-	emp.ID = fmt.Sprintf("emp%d", len(r.employees)+1)
+	if _, taken := r.index.passportOwner(emp.Passport); taken {
+		return nil, ErrDuplicatePassport
+	}
+	if _, exists := r.employees[id]; exists {
+		return nil, fmt.Errorf("идентификатор %s уже занят", id)
+	}
+	emp.ID = id
 	now := time.Now()
 	emp.CreatedAt = now
 	emp.UpdatedAt = now
@@ -145,7 +415,10 @@ func (r *MemoryRepository) CreateEmployee(ctx context.Context, emp models.Employ
 		emp.Status = "active"
 	}
 
-	r.employees[emp.ID] = emp
+	if err := r.journalLocked(change{employee: &emp}); err != nil {
+		return nil, err
+	}
+	r.setEmployeeLocked(emp)
 	return &emp, nil
 }
 
@@ -155,32 +428,35 @@ func (r *MemoryRepository) UpdateEmployee(ctx context.Context, emp models.Employ
 
 	existing, exists := r.employees[emp.ID]
 	if !exists {
-		return nil, fmt.Errorf("сотрудник не найден")
+		return nil, ErrEmployeeNotFound
 	}
 
-	for _, e := range r.employees {
-		if e.ID != emp.ID && e.Passport == emp.Passport {
-			return nil, fmt.Errorf("сотрудник с таким паспортом уже существует")
-		}
+	if owner, taken := r.index.passportOwner(emp.Passport); taken && owner != emp.ID {
+		return nil, ErrDuplicatePassport
 	}
 
 	emp.CreatedAt = existing.CreatedAt
 	emp.UpdatedAt = time.Now()
 	emp.Status = existing.Status
+	emp.FiredAt = existing.FiredAt
 
-	r.employees[emp.ID] = emp
+	if err := r.journalLocked(change{employee: &emp}); err != nil {
+		return nil, err
+	}
+	r.setEmployeeLocked(emp)
 	return &emp, nil
 }
 
-func (r *MemoryRepository) UpdateEmployeeStatus(ctx context.Context, id string, status string) (*models.Employee, error) {
+func (r *MemoryRepository) UpdateEmployeeStatus(ctx context.Context, id string, status string) (*models.Employee, string, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	emp, exists := r.employees[id]
 	if !exists {
-		return nil, fmt.Errorf("сотрудник не найден")
+		return nil, "", ErrEmployeeNotFound
 	}
 
+	previous := emp.Status
 	emp.Status = status
 	emp.UpdatedAt = time.Now()
 	if status == "fired" {
@@ -190,12 +466,126 @@ func (r *MemoryRepository) UpdateEmployeeStatus(ctx context.Context, id string,
 		emp.FiredAt = nil
 	}
 
-	r.employees[id] = emp
-	return &emp, nil
+	if err := r.journalLocked(change{employee: &emp}); err != nil {
+		return nil, "", err
+	}
+	r.setEmployeeLocked(emp)
+	return &emp, previous, nil
+}
+
+func (r *MemoryRepository) GetPositions(ctx context.Context) ([]models.Position, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	positions := make([]models.Position, 0, len(r.positions))
+	for _, pos := range r.positions {
+		positions = append(positions, pos)
+	}
+	return positions, nil
 }
 
-func (r *MemoryRepository) GetPositions(ctx context.Context) ([]string, error) {
-	return r.positions, nil
+func (r *MemoryRepository) GetPosition(ctx context.Context, id string) (*models.Position, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pos, exists := r.positions[id]
+	if !exists {
+		return nil, ErrPositionNotFound
+	}
+	return &pos, nil
+}
+
+func (r *MemoryRepository) CreatePosition(ctx context.Context, pos models.Position) (*models.Position, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.positionTitleTakenLocked(pos) {
+		return nil, ErrDuplicatePosition
+	}
+
+	pos.ID = r.nextPositionIDLocked()
+	now := time.Now()
+	pos.CreatedAt = now
+	pos.UpdatedAt = now
+
+	if err := r.journalLocked(change{position: &pos}); err != nil {
+		return nil, err
+	}
+	r.putPositionLocked(pos)
+	return &pos, nil
+}
+
+func (r *MemoryRepository) UpdatePosition(ctx context.Context, pos models.Position) (*models.Position, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.positions[pos.ID]
+	if !exists {
+		return nil, ErrPositionNotFound
+	}
+	if r.positionTitleTakenLocked(pos) {
+		return nil, ErrDuplicatePosition
+	}
+
+	pos.CreatedAt = existing.CreatedAt
+	pos.UpdatedAt = time.Now()
+	if err := r.journalLocked(change{position: &pos}); err != nil {
+		return nil, err
+	}
+	r.putPositionLocked(pos)
+	return &pos, nil
+}
+
+func (r *MemoryRepository) DeletePosition(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pos, exists := r.positions[id]
+	if !exists {
+		return ErrPositionNotFound
+	}
+	// Holders of a position always carry its title
+	for empID := range r.index.byPosition[pos.Title] {
+		if r.employees[empID].PositionID == id {
+			return ErrPositionInUse
+		}
+	}
+	if err := r.journalLocked(change{deletedPosition: id}); err != nil {
+		return err
+	}
+	delete(r.positions, id)
+	return nil
+}
+
+// positionTitleTakenLocked reports whether another position in the same
+// department scope has the title of pos
+func (r *MemoryRepository) positionTitleTakenLocked(pos models.Position) bool {
+	for _, other := range r.positions {
+		if other.ID != pos.ID && other.DepartmentID == pos.DepartmentID && other.Title == pos.Title {
+			return true
+		}
+	}
+	return false
+}
+
+// putPositionLocked stores pos, moves the position sequence past its ID
+// and carries a new title over to the employees holding it, found by the
+// previous title
+func (r *MemoryRepository) putPositionLocked(pos models.Position) {
+	if n, ok := sequenceNumber(positionIDPrefix, pos.ID); ok && n > r.positionSeq {
+		r.positionSeq = n
+	}
+	previous, exists := r.positions[pos.ID]
+	r.positions[pos.ID] = pos
+	if !exists || previous.Title == pos.Title {
+		return
+	}
+	for id := range r.index.byPosition[previous.Title] {
+		if emp := r.employees[id]; emp.PositionID == pos.ID {
+			emp.Position = pos.Title
+			r.setEmployeeLocked(emp)
+		}
+	}
 }
 
 func (r *MemoryRepository) GetEmployeeStats(ctx context.Context) (map[string]interface{}, error) {
@@ -203,24 +593,101 @@ func (r *MemoryRepository) GetEmployeeStats(ctx context.Context) (map[string]int
 	defer r.mu.RUnlock()
 
 	stats := make(map[string]interface{})
-	statusCount := make(map[string]int)
-	deptCount := make(map[string]int)
-	total := 0
-
-	for _, emp := range r.employees {
-		total++
-		statusCount[emp.Status]++
-		deptCount[emp.DepartmentID]++
+	statusCount := make(map[string]int, len(r.index.byStatus))
+	for status, ids := range r.index.byStatus {
+		statusCount[status] = len(ids)
+	}
+	deptCount := make(map[string]int, len(r.index.byDepartment))
+	for dept, ids := range r.index.byDepartment {
+		deptCount[dept] = len(ids)
 	}
 
-	stats["total"] = total
+	stats["total"] = len(r.employees)
 	stats["by_status"] = statusCount
 	stats["by_department"] = deptCount
 
 	return stats, nil
 }
 
-func contains(str, substr string) bool {
-	return len(str) >= len(substr) && str[:len(substr)] == substr
+// employeeSequence returns the last issued sequence number
+func (r *MemoryRepository) employeeSequence() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.employeeSeq
+}
+
+// restoreEmployeeSequence moves the sequence to at least n
+func (r *MemoryRepository) restoreEmployeeSequence(n uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n > r.employeeSeq {
+		r.employeeSeq = n
+	}
 }
 
+// positionSequence returns the last issued position number
+func (r *MemoryRepository) positionSequence() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.positionSeq
+}
+
+// restorePositionSequence moves the position sequence to at least n
+func (r *MemoryRepository) restorePositionSequence(n uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n > r.positionSeq {
+		r.positionSeq = n
+	}
+}
+
+// state returns copies of all departments, employees and positions
+func (r *MemoryRepository) state() ([]models.Department, []models.Employee, []models.Position) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	positions := make([]models.Position, 0, len(r.positions))
+	for _, pos := range r.positions {
+		positions = append(positions, pos)
+	}
+
+	departments := make([]models.Department, 0, len(r.departments))
+	for _, dept := range r.departments {
+		departments = append(departments, dept)
+	}
+	employees := make([]models.Employee, 0, len(r.employees))
+	for _, emp := range r.employees {
+		employees = append(employees, emp)
+	}
+	return departments, employees, positions
+}
+
+// putDepartment stores dept as is, used when restoring persisted state
+func (r *MemoryRepository) putDepartment(dept models.Department) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.putDepartmentLocked(dept)
+}
+
+// putEmployee stores emp as is, used when restoring persisted state
+func (r *MemoryRepository) putEmployee(emp models.Employee) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setEmployeeLocked(emp)
+	r.observeEmployeeIDLocked(emp.ID)
+}
+
+// putPosition stores pos as is, renaming the employees holding it; used
+// when restoring persisted state
+func (r *MemoryRepository) putPosition(pos models.Position) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.putPositionLocked(pos)
+}
+
+// removePosition deletes a position, used when restoring persisted state
+func (r *MemoryRepository) removePosition(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.positions, id)
+}
@@ -0,0 +1,120 @@
+package repository
+
+import "employee-management/internal/models"
+
+// idSet is a set of employee IDs
+type idSet map[string]struct{}
+
+// employeeIndex holds the secondary indexes of MemoryRepository. It is
+// guarded by the repository lock and updated by setEmployeeLocked and
+// deleteEmployeeLocked, which every write to the employees map goes through.
+type employeeIndex struct {
+	byPassport   map[string]string // passport → employee ID
+	byDepartment map[string]idSet
+	byStatus     map[string]idSet
+	byPosition   map[string]idSet // by position title, which PositionID holders share
+}
+
+func newEmployeeIndex() *employeeIndex {
+	return &employeeIndex{
+		byPassport:   make(map[string]string),
+		byDepartment: make(map[string]idSet),
+		byStatus:     make(map[string]idSet),
+		byPosition:   make(map[string]idSet),
+	}
+}
+
+func (ix *employeeIndex) add(emp models.Employee) {
+	ix.byPassport[emp.Passport] = emp.ID
+	addToSet(ix.byDepartment, emp.DepartmentID, emp.ID)
+	addToSet(ix.byStatus, emp.Status, emp.ID)
+	addToSet(ix.byPosition, emp.Position, emp.ID)
+}
+
+func (ix *employeeIndex) remove(emp models.Employee) {
+	if ix.byPassport[emp.Passport] == emp.ID {
+		delete(ix.byPassport, emp.Passport)
+	}
+	removeFromSet(ix.byDepartment, emp.DepartmentID, emp.ID)
+	removeFromSet(ix.byStatus, emp.Status, emp.ID)
+	removeFromSet(ix.byPosition, emp.Position, emp.ID)
+}
+
+// lookup returns the IDs of the smallest index entry req filters on, and
+// false when req filters on no indexed field. The other filters of req
+// still have to be applied to the result.
+func (ix *employeeIndex) lookup(req models.EmployeeSearchRequest) (idSet, bool) {
+	if req.Passport != "" {
+		id, ok := ix.byPassport[req.Passport]
+		if !ok {
+			return nil, true
+		}
+		return idSet{id: {}}, true
+	}
+
+	var best idSet
+	found := false
+	consider := func(index map[string]idSet, key string) {
+		if key == "" {
+			return
+		}
+		if set := index[key]; !found || len(set) < len(best) {
+			best, found = set, true
+		}
+	}
+	consider(ix.byDepartment, req.DepartmentID)
+	consider(ix.byStatus, req.Status)
+	consider(ix.byPosition, req.Position)
+	return best, found
+}
+
+// passportOwner returns the ID of the employee with the passport
+func (ix *employeeIndex) passportOwner(passport string) (string, bool) {
+	id, ok := ix.byPassport[passport]
+	return id, ok
+}
+
+func addToSet(index map[string]idSet, key, id string) {
+	set := index[key]
+	if set == nil {
+		set = make(idSet)
+		index[key] = set
+	}
+	set[id] = struct{}{}
+}
+
+// removeFromSet drops id and the key once its set is empty, so counts
+// taken from the index never report empty groups
+func removeFromSet(index map[string]idSet, key, id string) {
+	set := index[key]
+	delete(set, id)
+	if len(set) == 0 {
+		delete(index, key)
+	}
+}
+
+// setEmployeeLocked stores emp, replacing the index entries of the
+// version it overwrites
+func (r *MemoryRepository) setEmployeeLocked(emp models.Employee) {
+	if existing, exists := r.employees[emp.ID]; exists {
+		r.index.remove(existing)
+	}
+	r.employees[emp.ID] = emp
+	r.index.add(emp)
+}
+
+func (r *MemoryRepository) deleteEmployeeLocked(id string) {
+	if existing, exists := r.employees[id]; exists {
+		r.index.remove(existing)
+		delete(r.employees, id)
+	}
+}
+
+// employeesLocked returns the employees with the given IDs
+func (r *MemoryRepository) employeesLocked(ids idSet) []models.Employee {
+	employees := make([]models.Employee, 0, len(ids))
+	for id := range ids {
+		employees = append(employees, r.employees[id])
+	}
+	return employees
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"employee-management/internal/models"
+)
+
+// newIndexedRepository returns a repository with n employees spread over
+// 20 departments, the three statuses and 10 positions
+func newIndexedRepository(n int) *MemoryRepository {
+	r := newEmptyMemoryRepository()
+	statuses := []string{"active", "active", "active", "vacation", "fired"}
+	now := time.Now()
+	for i := 1; i <= n; i++ {
+		r.setEmployeeLocked(models.Employee{
+			ID:           sequenceID(employeeIDPrefix, uint64(i)),
+			FullName:     fmt.Sprintf("Сотрудник %d", i),
+			Gender:       []string{"male", "female"}[i%2],
+			Age:          20 + i%45,
+			Education:    "higher",
+			Position:     defaultPositions[i%10].Title,
+			PositionID:   defaultPositions[i%10].ID,
+			Passport:     fmt.Sprintf("%04d %06d", i%10000, i),
+			DepartmentID: sequenceID(departmentIDPrefix, uint64(i%20+1)),
+			Status:       statuses[i%len(statuses)],
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		})
+	}
+	return r
+}
+
+// scanEmployees is SearchEmployees without the indexes
+func (r *MemoryRepository) scanEmployees(req models.EmployeeSearchRequest) []models.Employee {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var employees []models.Employee
+	for _, emp := range r.employees {
+		if matchesSearch(emp, req) {
+			employees = append(employees, emp)
+		}
+	}
+	return employees
+}
+
+var searchBenchmarks = []struct {
+	name string
+	req  models.EmployeeSearchRequest
+}{
+	{"passport", models.EmployeeSearchRequest{Passport: "0500 000500"}},
+	{"department", models.EmployeeSearchRequest{DepartmentID: "dept7"}},
+	{"department_status", models.EmployeeSearchRequest{DepartmentID: "dept7", Status: "vacation"}},
+	{"status_gender", models.EmployeeSearchRequest{Status: "fired", Gender: "female"}},
+	{"position", models.EmployeeSearchRequest{Position: "Аналитик"}},
+}
+
+func TestSearchEmployeesIndexMatchesScan(t *testing.T) {
+	r := newIndexedRepository(1000)
+	ageFrom := 40
+	requests := []models.EmployeeSearchRequest{
+		{},
+		{Passport: "no such passport"},
+		{Passport: "0500 000500", Status: "active"},
+		{DepartmentID: "dept99"},
+		{DepartmentID: "dept3", Position: "Бухгалтер", AgeFrom: &ageFrom},
+	}
+	for _, bm := range searchBenchmarks {
+		requests = append(requests, bm.req)
+	}
+
+	ids := func(employees []models.Employee) []string {
+		out := make([]string, 0, len(employees))
+		for _, emp := range employees {
+			out = append(out, emp.ID)
+		}
+		slices.Sort(out)
+		return out
+	}
+	for _, req := range requests {
+		indexed, err := r.SearchEmployees(context.Background(), req)
+		if err != nil {
+			t.Fatalf("SearchEmployees(%+v): %v", req, err)
+		}
+		if got, want := ids(indexed), ids(r.scanEmployees(req)); !slices.Equal(got, want) {
+			t.Errorf("SearchEmployees(%+v) = %d employees, the full scan %d", req, len(got), len(want))
+		}
+	}
+}
+
+// BenchmarkSearchEmployees compares index lookups with a full scan of the
+// employees map at growing sizes
+func BenchmarkSearchEmployees(b *testing.B) {
+	ctx := context.Background()
+	for _, n := range []int{1000, 10000, 100000} {
+		r := newIndexedRepository(n)
+		for _, bm := range searchBenchmarks {
+			b.Run(fmt.Sprintf("%s/indexed/%d", bm.name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					r.SearchEmployees(ctx, bm.req)
+				}
+			})
+			b.Run(fmt.Sprintf("%s/scan/%d", bm.name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					r.scanEmployees(bm.req)
+				}
+			})
+		}
+	}
+}
@@ -2,19 +2,54 @@ package repository
 
 import (
 	"context"
+	"errors"
 
 	"employee-management/internal/models"
 )
 
+// Repository errors
+var (
+	ErrEmployeeNotFound       = errors.New("сотрудник не найден")
+	ErrDuplicatePassport      = errors.New("сотрудник с таким паспортом уже существует")
+	ErrDepartmentNotFound     = errors.New("департамент не найден")
+	ErrDepartmentHasEmployees = errors.New("в департаменте есть работающие сотрудники")
+	ErrDepartmentHasChildren  = errors.New("у департамента есть действующие подразделения")
+	ErrDepartmentArchived     = errors.New("департамент в архиве")
+	ErrPositionNotFound       = errors.New("должность не найдена")
+	ErrDuplicatePosition      = errors.New("должность с таким названием уже существует")
+	ErrPositionInUse          = errors.New("должность назначена сотрудникам")
+)
+
 // Repository defines the interface for data access
 type Repository interface {
 	GetDepartments(ctx context.Context) ([]models.Department, error)
+	GetDepartment(ctx context.Context, id string) (*models.Department, error)
+	CreateDepartment(ctx context.Context, dept models.Department) (*models.Department, error)
+	UpdateDepartment(ctx context.Context, dept models.Department) (*models.Department, error)
+	// ArchiveDepartment fails with ErrDepartmentHasEmployees while the
+	// department has employees who are not fired and with
+	// ErrDepartmentHasChildren while it has subdepartments not archived
+	ArchiveDepartment(ctx context.Context, id string) (*models.Department, error)
 	GetEmployeesByDepartment(ctx context.Context, departmentID string) ([]models.Employee, error)
+	GetEmployee(ctx context.Context, id string) (*models.Employee, error)
 	SearchEmployees(ctx context.Context, req models.EmployeeSearchRequest) ([]models.Employee, error)
+	// SearchEmployeesPage returns the page of the employees matching req
+	// that q selects, and the number of all employees matching req
+	SearchEmployeesPage(ctx context.Context, req models.EmployeeSearchRequest, q PageQuery) ([]models.Employee, int, error)
+	// CreateEmployee fails with ErrDepartmentArchived when the department
+	// is archived, checked in the same atomic step as the insert
 	CreateEmployee(ctx context.Context, emp models.Employee) (*models.Employee, error)
 	UpdateEmployee(ctx context.Context, emp models.Employee) (*models.Employee, error)
-	UpdateEmployeeStatus(ctx context.Context, id string, status string) (*models.Employee, error)
-	GetPositions(ctx context.Context) ([]string, error)
+	// UpdateEmployeeStatus also returns the status the employee had, read
+	// in the same atomic step as the update
+	UpdateEmployeeStatus(ctx context.Context, id string, status string) (*models.Employee, string, error)
+	GetPositions(ctx context.Context) ([]models.Position, error)
+	GetPosition(ctx context.Context, id string) (*models.Position, error)
+	CreatePosition(ctx context.Context, pos models.Position) (*models.Position, error)
+	// UpdatePosition also renames the position of the employees holding it
+	UpdatePosition(ctx context.Context, pos models.Position) (*models.Position, error)
+	// DeletePosition fails with ErrPositionInUse while employees hold it
+	DeletePosition(ctx context.Context, id string) error
 	GetEmployeeStats(ctx context.Context) (map[string]interface{}, error)
 }
 
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a schema change read from migrations/NNNN_name.up.sql and
+// the matching .down.sql
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrator applies the embedded migrations and records the schema version
+// in the schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for db
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func loadMigrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		prefix, title, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("неверное имя миграции: %s", name)
+		}
+
+		data, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("у миграции %d нет up или down части", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest returns the version of the newest embedded migration
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at INTEGER NOT NULL -- Unix time in nanoseconds
+	)`)
+	if err != nil {
+		return fmt.Errorf("не удалось создать таблицу версий схемы: %w", err)
+	}
+	return nil
+}
+
+// Version returns the current schema version, 0 for an empty database
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+	var version int
+	err := m.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось прочитать версию схемы: %w", err)
+	}
+	return version, nil
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the last steps migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	target := 0
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if m.migrations[i].Version > current {
+			continue
+		}
+		if steps == 0 {
+			target = m.migrations[i].Version
+			break
+		}
+		steps--
+	}
+	return m.To(ctx, target)
+}
+
+// To migrates up or down to version. Each migration runs in its own
+// transaction together with the version table update.
+func (m *Migrator) To(ctx context.Context, version int) error {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version > m.Latest() {
+		return fmt.Errorf("миграции версии %d нет, последняя версия %d", version, m.Latest())
+	}
+
+	if version >= current {
+		for _, mig := range m.migrations {
+			if mig.Version <= current || mig.Version > version {
+				continue
+			}
+			err := m.apply(ctx, mig, mig.Up,
+				`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				mig.Version, mig.Name, time.Now().UnixNano())
+			if err != nil {
+				return err
+			}
+			slog.Info("Миграция применена", "version", mig.Version, "name", mig.Name)
+		}
+		return nil
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version > current || mig.Version <= version {
+			continue
+		}
+		err := m.apply(ctx, mig, mig.Down, `DELETE FROM schema_migrations WHERE version = ?`, mig.Version)
+		if err != nil {
+			return err
+		}
+		slog.Info("Миграция отменена", "version", mig.Version, "name", mig.Name)
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, mig Migration, script string, record string, args ...any) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("ошибка миграции %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("ошибка записи версии схемы: %w", err)
+	}
+	return tx.Commit()
+}
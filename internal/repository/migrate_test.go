@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// newTestDB opens an empty SQLite database in a temporary directory
+func newTestDB(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	db, err := OpenSQLDB(context.Background(), "sqlite", dsn)
+	if err != nil {
+		t.Fatalf("OpenSQLDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&n)
+	if err != nil {
+		t.Fatalf("query sqlite_master: %v", err)
+	}
+	return n > 0
+}
+
+func TestMigrationsAreContiguous(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, m.Version, i+1)
+		}
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if v, _ := migrator.Version(ctx); v != migrator.Latest() {
+		t.Fatalf("version after Up = %d, want %d", v, migrator.Latest())
+	}
+	var recorded int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE name <> ''`).Scan(&recorded); err != nil {
+		t.Fatalf("read schema_migrations: %v", err)
+	}
+	if recorded != migrator.Latest() {
+		t.Errorf("recorded migrations = %d, want %d", recorded, migrator.Latest())
+	}
+	for _, table := range []string{"departments", "employees", "positions", "id_sequences"} {
+		if !tableExists(t, db, table) {
+			t.Errorf("table %s missing after Up", table)
+		}
+	}
+	// The schema carries no demo data, only the positions catalog
+	var employees int
+	db.QueryRow(`SELECT COUNT(*) FROM employees`).Scan(&employees)
+	if employees != 0 {
+		t.Errorf("employees after Up = %d, want 0", employees)
+	}
+
+	if err := migrator.Down(ctx, 1); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if v, _ := migrator.Version(ctx); v != migrator.Latest()-1 {
+		t.Errorf("version after Down = %d, want %d", v, migrator.Latest()-1)
+	}
+	var statusIndex int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'employees_status_idx'`).Scan(&statusIndex)
+	if statusIndex != 0 {
+		t.Error("employees_status_idx left after rolling back its migration")
+	}
+
+	if err := migrator.To(ctx, 0); err != nil {
+		t.Fatalf("To(0): %v", err)
+	}
+	if v, _ := migrator.Version(ctx); v != 0 {
+		t.Errorf("version after To(0) = %d, want 0", v)
+	}
+	for _, table := range []string{"departments", "employees", "positions"} {
+		if tableExists(t, db, table) {
+			t.Errorf("table %s left after To(0)", table)
+		}
+	}
+
+	// Every down script undid its up script completely
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up after To(0): %v", err)
+	}
+}
+
+func TestMigrateToUnknownVersion(t *testing.T) {
+	migrator, err := NewMigrator(newTestDB(t))
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	if err := migrator.To(context.Background(), migrator.Latest()+1); err == nil {
+		t.Error("To accepted a version past the latest migration")
+	}
+}
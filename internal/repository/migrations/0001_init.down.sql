@@ -0,0 +1,3 @@
+DROP INDEX employees_department_idx;
+DROP TABLE employees;
+DROP TABLE departments;
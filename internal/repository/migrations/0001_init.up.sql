@@ -0,0 +1,25 @@
+-- Timestamps are stored as Unix time in nanoseconds
+CREATE TABLE departments (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  INTEGER NOT NULL
+);
+
+CREATE TABLE employees (
+    id            TEXT PRIMARY KEY,
+    full_name     TEXT NOT NULL,
+    gender        TEXT NOT NULL,
+    age           INTEGER NOT NULL,
+    education     TEXT NOT NULL,
+    position      TEXT NOT NULL,
+    passport      TEXT NOT NULL,
+    department_id TEXT NOT NULL REFERENCES departments (id),
+    status        TEXT NOT NULL DEFAULT 'active',
+    created_at    INTEGER NOT NULL,
+    updated_at    INTEGER NOT NULL,
+    fired_at      INTEGER,
+    CONSTRAINT employees_passport_unique UNIQUE (passport)
+);
+
+CREATE INDEX employees_department_idx ON employees (department_id);
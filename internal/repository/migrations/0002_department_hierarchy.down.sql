@@ -0,0 +1,6 @@
+DROP INDEX departments_parent_idx;
+
+ALTER TABLE departments DROP COLUMN updated_at;
+ALTER TABLE departments DROP COLUMN archived_at;
+ALTER TABLE departments DROP COLUMN archived;
+ALTER TABLE departments DROP COLUMN parent_id;
@@ -0,0 +1,8 @@
+ALTER TABLE departments ADD COLUMN parent_id TEXT;
+ALTER TABLE departments ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE departments ADD COLUMN archived_at INTEGER;
+ALTER TABLE departments ADD COLUMN updated_at INTEGER;
+
+UPDATE departments SET updated_at = created_at;
+
+CREATE INDEX departments_parent_idx ON departments (parent_id);
@@ -0,0 +1,5 @@
+DROP INDEX employees_position_idx;
+
+ALTER TABLE employees DROP COLUMN position_id;
+
+DROP TABLE positions;
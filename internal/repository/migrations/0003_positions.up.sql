@@ -0,0 +1,30 @@
+CREATE TABLE positions (
+    id            TEXT PRIMARY KEY,
+    title         TEXT NOT NULL,
+    department_id TEXT NOT NULL DEFAULT '',
+    grade         INTEGER NOT NULL DEFAULT 1,
+    active        BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at    INTEGER NOT NULL,
+    updated_at    INTEGER NOT NULL,
+    UNIQUE (title, department_id)
+);
+
+INSERT INTO positions (id, title, grade, active, created_at, updated_at) VALUES
+    ('pos1', 'Программист', 2, TRUE, unixepoch() * 1000000000, unixepoch() * 1000000000),
+    ('pos2', 'Аналитик', 2, TRUE, unixepoch() * 1000000000, unixepoch() * 1000000000),
+    ('pos3', 'Тестировщик', 2, TRUE, unixepoch() * 1000000000, unixepoch() * 1000000000),
+    ('pos4', 'Менеджер по продажам', 2, TRUE, unixepoch() * 1000000000, unixepoch() * 1000000000),
+    ('pos5', 'HR-менеджер', 2, TRUE, unixepoch() * 1000000000, unixepoch() * 1000000000),
+    ('pos6', 'Бухгалтер', 2, TRUE, unixepoch() * 1000000000, unixepoch() * 1000000000),
+    ('pos7', 'Маркетолог', 2, TRUE, unixepoch() * 1000000000, unixepoch() * 1000000000),
+    ('pos8', 'Дизайнер', 2, TRUE, unixepoch() * 1000000000, unixepoch() * 1000000000),
+    ('pos9', 'Системный администратор', 2, TRUE, unixepoch() * 1000000000, unixepoch() * 1000000000),
+    ('pos10', 'Руководитель отдела', 4, TRUE, unixepoch() * 1000000000, unixepoch() * 1000000000);
+
+ALTER TABLE employees ADD COLUMN position_id TEXT;
+
+UPDATE employees SET position_id = (
+    SELECT id FROM positions WHERE positions.title = employees.position AND positions.department_id = ''
+);
+
+CREATE INDEX employees_position_idx ON employees (position_id);
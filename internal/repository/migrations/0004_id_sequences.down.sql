@@ -0,0 +1 @@
+DROP TABLE id_sequences;
@@ -0,0 +1,8 @@
+CREATE TABLE id_sequences (
+    name  TEXT PRIMARY KEY,
+    value INTEGER NOT NULL
+);
+
+INSERT INTO id_sequences (name, value)
+SELECT 'positions', COALESCE(MAX(CAST(SUBSTR(id, 4) AS INTEGER)), 0)
+FROM positions WHERE id LIKE 'pos%';
@@ -0,0 +1,3 @@
+INSERT INTO id_sequences (name, value)
+SELECT 'employees', COALESCE(MAX(CAST(SUBSTR(id, 4) AS INTEGER)), 0)
+FROM employees WHERE id LIKE 'emp%';
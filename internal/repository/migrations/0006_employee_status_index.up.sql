@@ -0,0 +1 @@
+CREATE INDEX employees_status_idx ON employees (status);
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"employee-management/internal/models"
+)
+
+// Full-name search. Names and queries are split into words, lower-cased
+// and ё is folded into е. Every query word has to match some word of the
+// name, exactly, as a prefix or within a few typos; a Latin query is
+// compared with the transliterated name. Matches are ranked by the mean
+// score of the query words.
+
+// translit is the Cyrillic to Latin transliteration of names
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n",
+	'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y",
+	'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g", 'ў': "u",
+}
+
+// latinVariants folds Latin spellings that transliteration never produces
+var latinVariants = strings.NewReplacer("w", "v", "x", "ks", "q", "k", "j", "y")
+
+// nameWord is a word of a name or a query in both scripts
+type nameWord struct {
+	cyrillic bool
+	text     []rune // normalized original script
+	latin    []rune // transliterated, equal to text for Latin words
+}
+
+// splitName splits s into normalized words
+func splitName(s string) []nameWord {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := make([]nameWord, 0, len(fields))
+	for _, field := range fields {
+		field = strings.ReplaceAll(field, "ё", "е")
+		word := nameWord{text: []rune(field)}
+		var latin strings.Builder
+		for _, r := range word.text {
+			if t, ok := translit[r]; ok {
+				word.cyrillic = true
+				latin.WriteString(t)
+			} else {
+				latin.WriteRune(r)
+			}
+		}
+		word.latin = []rune(latinVariants.Replace(latin.String()))
+		words = append(words, word)
+	}
+	return words
+}
+
+// NameScore returns the relevance of fullName to query, 0 when they do
+// not match. Name searches rank by it, then by name and ID.
+func NameScore(query, fullName string) float64 {
+	return nameScore(splitName(query), splitName(fullName))
+}
+
+// nameScore returns the relevance of a name to the query words, 0 when
+// some query word matches no word of the name
+func nameScore(query, name []nameWord) float64 {
+	if len(query) == 0 {
+		return 1
+	}
+	total := 0.0
+	for _, q := range query {
+		best := 0.0
+		for _, w := range name {
+			score := 0.0
+			if q.cyrillic && w.cyrillic {
+				score = wordScore(q.text, w.text)
+			} else {
+				// Transliterated matches rank slightly below native ones
+				score = 0.95 * wordScore(q.latin, w.latin)
+			}
+			if score > best {
+				best = score
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total / float64(len(query))
+}
+
+// wordScore rates how well query word q matches name word w: 1 for equal
+// words, 0.75-0.95 for a prefix, less for matches with typos
+func wordScore(q, w []rune) float64 {
+	if len(q) == 0 || len(w) == 0 {
+		return 0
+	}
+	if runesEqual(q, w) {
+		return 1
+	}
+	if len(q) < len(w) && runesEqual(q, w[:len(q)]) {
+		return 0.75 + 0.2*float64(len(q))/float64(len(w))
+	}
+
+	maxTypos := allowedTypos(len(q))
+	if maxTypos == 0 {
+		return 0
+	}
+	if d := editDistance(q, w, maxTypos); d <= maxTypos {
+		return 0.6 - 0.1*float64(d)
+	}
+	// A word being typed: compare with a prefix of the same length
+	if len(q) < len(w) {
+		if d := editDistance(q, w[:len(q)], maxTypos); d <= maxTypos {
+			return 0.5 - 0.1*float64(d)
+		}
+	}
+	return 0
+}
+
+// allowedTypos is the edit distance tolerated for a word of n letters
+func allowedTypos(n int) int {
+	switch {
+	case n < 3:
+		return 0
+	case n < 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func runesEqual(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and swaps of adjacent letters.
+// Results above limit are reported as limit+1.
+func editDistance(a, b []rune, limit int) int {
+	if diff := len(a) - len(b); diff > limit || -diff > limit {
+		return limit + 1
+	}
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return min(prev[len(b)], limit+1)
+}
+
+// rankByName keeps the employees whose name matches query, most relevant
+// first; equally relevant ones are ordered by name
+func rankByName(employees []models.Employee, query string) []models.Employee {
+	words := splitName(query)
+	if len(words) == 0 {
+		return employees
+	}
+
+	type scored struct {
+		emp   models.Employee
+		score float64
+	}
+	matches := make([]scored, 0, len(employees))
+	for _, emp := range employees {
+		if score := nameScore(words, splitName(emp.FullName)); score > 0 {
+			matches = append(matches, scored{emp, score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		if matches[i].emp.FullName != matches[j].emp.FullName {
+			return matches[i].emp.FullName < matches[j].emp.FullName
+		}
+		return matches[i].emp.ID < matches[j].emp.ID
+	})
+
+	ranked := make([]models.Employee, len(matches))
+	for i, m := range matches {
+		ranked[i] = m.emp
+	}
+	return ranked
+}
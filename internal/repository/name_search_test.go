@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+
+	"employee-management/internal/models"
+)
+
+func TestNameMatchScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		fullName string
+		want     float64 // -1: any score above 0
+	}{
+		{"first name", "Иван", "Сидоров Иван Петрович", 1},
+		{"middle name prefix", "иванов", "Петров Сергей Иванович", 0.75 + 0.2*6/8},
+		{"several words", "иван сидоров", "Сидоров Иван Петрович", 1},
+		{"every word has to match", "Иванов Петр", "Иванов Иван Иванович", 0},
+		{"case", "ИВАНОВ", "иванов иван", 1},
+		{"ё in the name", "Семен", "Семён Артемов", 1},
+		{"ё in the query", "Артём", "Семен Артемов", 0.75 + 0.2*5/7},
+		{"Latin query", "ivanov", "Иванов Иван Иванович", 0.95},
+		{"Latin spelling variants", "jurij", "Юрий Смирнов", -1},
+		{"Latin name, Cyrillic query", "смит", "John Smith", 0.95 * (0.75 + 0.2*4/5)},
+		{"swapped letters", "Ивнаов", "Иванов Иван", 0.5},
+		{"typo while typing", "Сидр", "Сидоров Павел", 0.4},
+		{"two typos in a long word", "Алекандрвич", "Сидоров Петр Александрович", -1},
+		{"too many typos", "Ивнаво", "Иванов Иван", 0},
+		{"short words allow no typos", "Иа", "Иванов Иван", 0},
+		{"short prefix", "Ив", "Петров Иван", 0.75 + 0.2*2/4},
+		{"no match", "Смирнов", "Иванов Иван Иванович", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NameScore(tt.query, tt.fullName)
+			if tt.want < 0 {
+				if got <= 0 {
+					t.Errorf("NameScore(%q, %q) = %v, want a match", tt.query, tt.fullName, got)
+				}
+				return
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("NameScore(%q, %q) = %v, want %v", tt.query, tt.fullName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"иванов", "иванов", 2, 0},
+		{"иванов", "ивнаов", 2, 1}, // a swap is one edit
+		{"иванов", "иваов", 2, 1},
+		{"иванов", "иваанов", 2, 1},
+		{"иванов", "ивакоф", 2, 2},
+		{"kitten", "sitting", 3, 3},
+		{"ca", "abc", 5, 3}, // OSA does not edit a swapped pair again
+		{"иванов", "петров", 2, 3},
+		{"ив", "иванов", 2, 3}, // too long to compare
+	}
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b), tt.limit); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedTypos(t *testing.T) {
+	for n, want := range map[int]int{1: 0, 2: 0, 3: 1, 6: 1, 7: 2, 12: 2} {
+		if got := allowedTypos(n); got != want {
+			t.Errorf("allowedTypos(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func TestSQLRanksNamesLikeRankByName(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLRepository(t)
+	for _, name := range []string{
+		"Иванова Мария Петровна", "Ивнаов Олег Сергеевич", "Иванов Иван Иванович",
+		"Семёнов Иван Ильич", "Ivanov Ivan", "Петров Сергей Иванович",
+	} {
+		emp := newTestEmployee("")
+		emp.FullName = name
+		emp.Passport = "9999 " + name
+		if _, err := repo.CreateEmployee(ctx, emp); err != nil {
+			t.Fatalf("CreateEmployee: %v", err)
+		}
+	}
+	all, err := repo.SearchEmployees(ctx, models.EmployeeSearchRequest{})
+	if err != nil {
+		t.Fatalf("SearchEmployees: %v", err)
+	}
+
+	for _, query := range []string{"иванов", "Иван", "ivanov", "семенов", "Иванов Иван", "Смирнов"} {
+		got, err := repo.SearchEmployees(ctx, models.EmployeeSearchRequest{FullName: query})
+		if err != nil {
+			t.Fatalf("SearchEmployees(%q): %v", query, err)
+		}
+		want := rankByName(slices.Clone(all), query)
+		if !slices.Equal(employeeIDs(got), employeeIDs(want)) {
+			t.Errorf("SearchEmployees(%q) = %v, want %v", query, employeeIDs(got), employeeIDs(want))
+		}
+	}
+}
+
+func employeeIDs(employees []models.Employee) []string {
+	ids := make([]string, len(employees))
+	for i, emp := range employees {
+		ids[i] = emp.ID
+	}
+	return ids
+}
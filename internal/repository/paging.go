@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"employee-management/internal/models"
+)
+
+// PageQuery selects a page of an employee search. Employees are ordered
+// by the Sort field, ties broken by ID, so the order is total and the same
+// for every repository.
+type PageQuery struct {
+	Sort string // "name", "age", "created_at", "updated_at" or "position"
+	Desc bool
+	// After is the last item of the previous page, nil for the first
+	// page; only its sort field and ID are read
+	After *models.Employee
+	Limit int // 0 for no limit
+}
+
+// employeeCompare compares employees by a sort field
+var employeeCompare = map[string]func(a, b *models.Employee) int{
+	"name": func(a, b *models.Employee) int {
+		return compareCollated(a.FullName, b.FullName)
+	},
+	"age": func(a, b *models.Employee) int {
+		return a.Age - b.Age
+	},
+	"created_at": func(a, b *models.Employee) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	},
+	"updated_at": func(a, b *models.Employee) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	},
+	"position": func(a, b *models.Employee) int {
+		return compareCollated(a.Position, b.Position)
+	},
+}
+
+// IsSortField reports whether employee searches can be sorted by field
+func IsSortField(field string) bool {
+	_, ok := employeeCompare[field]
+	return ok
+}
+
+// compareCollated compares names case-insensitively, with ё as е
+func compareCollated(a, b string) int {
+	return strings.Compare(collationKey(a), collationKey(b))
+}
+
+func collationKey(s string) string {
+	return strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+}
+
+// compare is the full order of q
+func (q PageQuery) compare(a, b *models.Employee) int {
+	c := employeeCompare[q.Sort](a, b)
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	if q.Desc {
+		c = -c
+	}
+	return c
+}
+
+// pageEmployees sorts employees in the order of q and cuts out the page.
+// Repositories that cannot sort themselves page this way.
+func pageEmployees(employees []models.Employee, q PageQuery) ([]models.Employee, error) {
+	if !IsSortField(q.Sort) {
+		return nil, fmt.Errorf("неверное поле сортировки: %s", q.Sort)
+	}
+	sort.Slice(employees, func(i, j int) bool { return q.compare(&employees[i], &employees[j]) < 0 })
+	start := 0
+	if q.After != nil {
+		start = sort.Search(len(employees), func(i int) bool { return q.compare(&employees[i], q.After) > 0 })
+	}
+	end := len(employees)
+	if q.Limit > 0 {
+		end = min(start+q.Limit, end)
+	}
+	return employees[start:end], nil
+}
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"employee-management/internal/models"
+)
+
+// forEachRepository runs test against every implementation, each starting
+// from the demo data
+func forEachRepository(t *testing.T, test func(t *testing.T, repo Repository)) {
+	t.Run("memory", func(t *testing.T) { test(t, NewMemoryRepository()) })
+	t.Run("file", func(t *testing.T) {
+		repo := openTestFileRepository(t, t.TempDir())
+		defer repo.Close()
+		test(t, repo)
+	})
+	t.Run("sql", func(t *testing.T) { test(t, newTestSQLRepository(t)) })
+}
+
+func TestArchiveDepartmentRequiresArchivedChildren(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo Repository) {
+		ctx := context.Background()
+		child, err := repo.CreateDepartment(ctx, models.Department{Name: "Отдел рекламы", ParentID: "dept5"})
+		if err != nil {
+			t.Fatalf("CreateDepartment: %v", err)
+		}
+
+		if _, err := repo.ArchiveDepartment(ctx, "dept5"); !errors.Is(err, ErrDepartmentHasChildren) {
+			t.Errorf("archive with an active child: %v, want ErrDepartmentHasChildren", err)
+		}
+		if _, err := repo.ArchiveDepartment(ctx, child.ID); err != nil {
+			t.Fatalf("archive the child: %v", err)
+		}
+		if _, err := repo.ArchiveDepartment(ctx, "dept5"); err != nil {
+			t.Errorf("archive with an archived child: %v", err)
+		}
+		if _, err := repo.ArchiveDepartment(ctx, "dept1"); !errors.Is(err, ErrDepartmentHasEmployees) {
+			t.Errorf("archive with employees: %v, want ErrDepartmentHasEmployees", err)
+		}
+		if _, err := repo.ArchiveDepartment(ctx, "dept99"); !errors.Is(err, ErrDepartmentNotFound) {
+			t.Errorf("archive a missing department: %v, want ErrDepartmentNotFound", err)
+		}
+	})
+}
+
+func TestCreateEmployeeInArchivedDepartment(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo Repository) {
+		ctx := context.Background()
+		if _, err := repo.ArchiveDepartment(ctx, "dept4"); err != nil {
+			t.Fatalf("ArchiveDepartment: %v", err)
+		}
+		emp := newTestEmployee("9999 000003")
+		emp.DepartmentID = "dept4"
+		if _, err := repo.CreateEmployee(ctx, emp); !errors.Is(err, ErrDepartmentArchived) {
+			t.Errorf("CreateEmployee in an archived department: %v, want ErrDepartmentArchived", err)
+		}
+	})
+}
+
+func TestDepartmentIDsAreUnique(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo Repository) {
+		ctx := context.Background()
+		seen := make(map[string]bool)
+		departments, _ := repo.GetDepartments(ctx)
+		for _, dept := range departments {
+			seen[dept.ID] = true
+		}
+		for i := 0; i < 3; i++ {
+			dept, err := repo.CreateDepartment(ctx, models.Department{Name: "Новый отдел"})
+			if err != nil {
+				t.Fatalf("CreateDepartment: %v", err)
+			}
+			if seen[dept.ID] {
+				t.Errorf("CreateDepartment reused ID %s", dept.ID)
+			}
+			seen[dept.ID] = true
+		}
+	})
+}
+
+func TestMemoryDepartmentIDSkipsLoadedIDs(t *testing.T) {
+	repo := NewMemoryRepository()
+	// dept1..dept5 and dept7, as restored from a snapshot
+	repo.putDepartment(models.Department{ID: "dept7", Name: "Склад"})
+
+	dept, err := repo.CreateDepartment(context.Background(), models.Department{Name: "Новый отдел"})
+	if err != nil {
+		t.Fatalf("CreateDepartment: %v", err)
+	}
+	if dept.ID != "dept8" {
+		t.Errorf("new department ID = %s, want dept8", dept.ID)
+	}
+	if stored, _ := repo.GetDepartment(context.Background(), "dept7"); stored.Name != "Склад" {
+		t.Errorf("dept7 = %q, want it untouched", stored.Name)
+	}
+}
+
+func TestPositionIDsAreNotReused(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo Repository) {
+		ctx := context.Background()
+		first, err := repo.CreatePosition(ctx, models.Position{Title: "Стажер", Grade: 1, Active: true})
+		if err != nil {
+			t.Fatalf("CreatePosition: %v", err)
+		}
+		if err := repo.DeletePosition(ctx, first.ID); err != nil {
+			t.Fatalf("DeletePosition: %v", err)
+		}
+		second, err := repo.CreatePosition(ctx, models.Position{Title: "Стажер", Grade: 1, Active: true})
+		if err != nil {
+			t.Fatalf("CreatePosition: %v", err)
+		}
+		if second.ID == first.ID {
+			t.Errorf("CreatePosition reused the ID %s of a deleted position", first.ID)
+		}
+		if first.ID != "pos11" || second.ID != "pos12" {
+			t.Errorf("IDs = %s, %s, want pos11, pos12 after the catalog", first.ID, second.ID)
+		}
+	})
+}
+
+func TestFilePositionSequenceSurvivesSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	repo := openTestFileRepository(t, dir)
+	pos, err := repo.CreatePosition(ctx, models.Position{Title: "Стажер", Grade: 1, Active: true})
+	if err == nil {
+		err = repo.DeletePosition(ctx, pos.ID)
+	}
+	if err != nil {
+		t.Fatalf("create and delete a position: %v", err)
+	}
+	// The snapshot no longer holds the deleted position, only the sequence
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened := openTestFileRepository(t, dir)
+	defer reopened.Close()
+	next, err := reopened.CreatePosition(ctx, models.Position{Title: "Стажер", Grade: 1, Active: true})
+	if err != nil {
+		t.Fatalf("CreatePosition: %v", err)
+	}
+	if next.ID == pos.ID {
+		t.Errorf("CreatePosition after reopen reused %s", pos.ID)
+	}
+}
+
+func TestSearchEmployeesByIndexedFields(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo Repository) {
+		ctx := context.Background()
+		tests := []struct {
+			req  models.EmployeeSearchRequest
+			want []string
+		}{
+			{models.EmployeeSearchRequest{DepartmentID: "dept1"}, []string{"emp1", "emp2"}},
+			{models.EmployeeSearchRequest{DepartmentID: "dept1", Status: "active"}, []string{"emp1"}},
+			{models.EmployeeSearchRequest{Status: "active", Gender: "female"}, []string{"emp4"}},
+			{models.EmployeeSearchRequest{Passport: "3456 789012"}, []string{"emp3"}},
+			{models.EmployeeSearchRequest{Passport: "3456 789012", Status: "vacation"}, nil},
+			{models.EmployeeSearchRequest{DepartmentID: "dept99"}, nil},
+		}
+		for _, tt := range tests {
+			employees, err := repo.SearchEmployees(ctx, tt.req)
+			if err != nil {
+				t.Fatalf("SearchEmployees(%+v): %v", tt.req, err)
+			}
+			var got []string
+			for _, emp := range employees {
+				got = append(got, emp.ID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SearchEmployees(%+v) = %v, want %v", tt.req, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestSearchEmployeesPageFollowsSortOrder(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo Repository) {
+		ctx := context.Background()
+		// Case, ё and equal keys must order the same in every repository
+		for i, name := range []string{"ёлкин Петр", "Елкин Иван", "елкин Петр", "Абрамов Олег", "абрамов Олег"} {
+			emp := newTestEmployee(fmt.Sprintf("9999 10000%d", i))
+			emp.FullName = name
+			emp.Position = []string{"Аналитик", "аналитик", "Программист"}[i%3]
+			emp.Age = 30 + i%2
+			if _, err := repo.CreateEmployee(ctx, emp); err != nil {
+				t.Fatalf("CreateEmployee: %v", err)
+			}
+		}
+		all, err := repo.SearchEmployees(ctx, models.EmployeeSearchRequest{})
+		if err != nil {
+			t.Fatalf("SearchEmployees: %v", err)
+		}
+
+		for _, sortBy := range []string{"name", "age", "created_at", "updated_at", "position"} {
+			for _, desc := range []bool{false, true} {
+				q := PageQuery{Sort: sortBy, Desc: desc, Limit: 2}
+				want, _ := pageEmployees(slices.Clone(all), PageQuery{Sort: sortBy, Desc: desc})
+
+				var got []string
+				for {
+					page, total, err := repo.SearchEmployeesPage(ctx, models.EmployeeSearchRequest{}, q)
+					if err != nil {
+						t.Fatalf("SearchEmployeesPage(%+v): %v", q, err)
+					}
+					if total != len(all) {
+						t.Errorf("total = %d, want %d", total, len(all))
+					}
+					for _, emp := range page {
+						got = append(got, emp.ID)
+					}
+					if len(page) < q.Limit {
+						break
+					}
+					q.After = &page[len(page)-1]
+				}
+
+				var wantIDs []string
+				for _, emp := range want {
+					wantIDs = append(wantIDs, emp.ID)
+				}
+				if !slices.Equal(got, wantIDs) {
+					t.Errorf("sort %s desc=%v: pages = %v, want %v", sortBy, desc, got, wantIDs)
+				}
+			}
+		}
+
+		page, total, err := repo.SearchEmployeesPage(ctx,
+			models.EmployeeSearchRequest{DepartmentID: "dept1", Status: "active"}, PageQuery{Sort: "name", Limit: 10})
+		if err != nil {
+			t.Fatalf("SearchEmployeesPage with filters: %v", err)
+		}
+		if total != 6 || len(page) != 6 {
+			t.Errorf("active employees of dept1: %d on the page, total %d, want 6", len(page), total)
+		}
+	})
+}
+
+func TestUpdateEmployeeKeepsFiredAt(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo Repository) {
+		ctx := context.Background()
+		fired, _, err := repo.UpdateEmployeeStatus(ctx, "emp1", "fired")
+		if err != nil {
+			t.Fatalf("UpdateEmployeeStatus: %v", err)
+		}
+
+		emp := *fired
+		forged := fired.FiredAt.Add(-time.Hour)
+		emp.FiredAt = &forged
+		emp.Age++
+		updated, err := repo.UpdateEmployee(ctx, emp)
+		if err != nil {
+			t.Fatalf("UpdateEmployee: %v", err)
+		}
+		if updated.FiredAt == nil || !updated.FiredAt.Equal(*fired.FiredAt) {
+			t.Errorf("FiredAt after update = %v, want %v", updated.FiredAt, fired.FiredAt)
+		}
+	})
+}
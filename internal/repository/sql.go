@@ -0,0 +1,786 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"employee-management/internal/models"
+
+	"modernc.org/sqlite" // registers the "sqlite" driver
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	employeeColumns = `id, full_name, gender, age, education, position, passport,
+	department_id, status, created_at, updated_at, fired_at, position_id`
+	departmentColumns = `id, name, description, parent_id, archived, created_at, updated_at, archived_at`
+	positionColumns   = `id, title, department_id, grade, active, created_at, updated_at`
+)
+
+// The collation sorts names the way compareCollated does, as the SQLite
+// lower() only folds ASCII; the function scores names as the full-name
+// search does, so the rows that do not match are never read
+const (
+	nameCollation = "employee_name"
+	nameScoreFunc = "employee_name_score"
+)
+
+func init() {
+	if err := sqlite.RegisterCollationUtf8(nameCollation, compareCollated); err != nil {
+		panic(err)
+	}
+	if err := sqlite.RegisterDeterministicScalarFunction(nameScoreFunc, 2, nameScoreSQL); err != nil {
+		panic(err)
+	}
+}
+
+// nameScoreSQL is NameScore(query, full_name) for SQL
+func nameScoreSQL(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	fullName, ok := args[0].(string)
+	query, ok2 := args[1].(string)
+	if !ok || !ok2 {
+		return nil, fmt.Errorf("%s: ожидались строки, получено %T и %T", nameScoreFunc, args[0], args[1])
+	}
+	return NameScore(query, fullName), nil
+}
+
+// Timestamps are stored as Unix time in nanoseconds, which sorts by time
+// and reads back without parsing
+
+// unixTime scans a stored timestamp into *t
+type unixTime struct{ t *time.Time }
+
+func (u unixTime) Scan(src any) error {
+	n, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("неверная метка времени: %v", src)
+	}
+	*u.t = time.Unix(0, n)
+	return nil
+}
+
+// nullUnixTime scans a stored timestamp that may be NULL into *t
+type nullUnixTime struct{ t **time.Time }
+
+func (u nullUnixTime) Scan(src any) error {
+	if src == nil {
+		*u.t = nil
+		return nil
+	}
+	var t time.Time
+	if err := (unixTime{&t}).Scan(src); err != nil {
+		return err
+	}
+	*u.t = &t
+	return nil
+}
+
+// nullUnixNano is the stored form of a timestamp that may be nil
+func nullUnixNano(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+// sqlSortKeys are the ORDER BY expressions of the sort fields, each with
+// the value of an employee to seek past
+var sqlSortKeys = map[string]struct {
+	expr  string
+	value func(emp *models.Employee) any
+}{
+	"name":       {`full_name COLLATE ` + nameCollation, func(emp *models.Employee) any { return emp.FullName }},
+	"age":        {`age`, func(emp *models.Employee) any { return emp.Age }},
+	"created_at": {`created_at`, func(emp *models.Employee) any { return emp.CreatedAt.UnixNano() }},
+	"updated_at": {`updated_at`, func(emp *models.Employee) any { return emp.UpdatedAt.UnixNano() }},
+	"position":   {`position COLLATE ` + nameCollation, func(emp *models.Employee) any { return emp.Position }},
+}
+
+// SQLRepository implements Repository over database/sql. Queries use the
+// SQLite dialect; the schema is created by Migrator.
+type SQLRepository struct {
+	db  *sql.DB
+	ids IDGenerator // nil: IDs come from the id_sequences table within the insert
+}
+
+// OpenSQLDB opens the database and checks that it is reachable
+func OpenSQLDB(ctx context.Context, driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть базу данных: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("база данных недоступна: %w", err)
+	}
+	return db, nil
+}
+
+// NewSQLRepository creates a repository over db
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// SetIDGenerator sets the generator of employee IDs; it must be called
+// before the repository is used. With nil, the default, IDs are "empN"
+// from the employees row of id_sequences, which is never decremented,
+// taken in the transaction of the insert.
+func (r *SQLRepository) SetIDGenerator(gen IDGenerator) {
+	if seq, ok := gen.(*SequenceGenerator); ok && seq.seq == EmployeeSequence(r) {
+		// The same sequence, without gaps from failed inserts
+		gen = nil
+	}
+	r.ids = gen
+}
+
+// NextEmployeeNumber takes the next number from the employees row of
+// id_sequences
+func (r *SQLRepository) NextEmployeeNumber(ctx context.Context) (uint64, error) {
+	var n uint64
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE id_sequences SET value = value + 1 WHERE name = 'employees' RETURNING value`).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка выдачи идентификатора: %w", err)
+	}
+	return n, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEmployee(row rowScanner) (models.Employee, error) {
+	var emp models.Employee
+	var positionID sql.NullString
+	err := row.Scan(&emp.ID, &emp.FullName, &emp.Gender, &emp.Age, &emp.Education, &emp.Position,
+		&emp.Passport, &emp.DepartmentID, &emp.Status, unixTime{&emp.CreatedAt}, unixTime{&emp.UpdatedAt},
+		nullUnixTime{&emp.FiredAt}, &positionID)
+	if err != nil {
+		return emp, err
+	}
+	emp.PositionID = positionID.String
+	return emp, nil
+}
+
+func (r *SQLRepository) queryEmployees(ctx context.Context, query string, args ...any) ([]models.Employee, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса сотрудников: %w", err)
+	}
+	defer rows.Close()
+
+	var employees []models.Employee
+	for rows.Next() {
+		emp, err := scanEmployee(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения сотрудника: %w", err)
+		}
+		employees = append(employees, emp)
+	}
+	return employees, rows.Err()
+}
+
+func scanDepartment(row rowScanner) (models.Department, error) {
+	var dept models.Department
+	var parentID sql.NullString
+	err := row.Scan(&dept.ID, &dept.Name, &dept.Description, &parentID, &dept.Archived,
+		unixTime{&dept.CreatedAt}, unixTime{&dept.UpdatedAt}, nullUnixTime{&dept.ArchivedAt})
+	if err != nil {
+		return dept, err
+	}
+	dept.ParentID = parentID.String
+	return dept, nil
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func (r *SQLRepository) GetDepartments(ctx context.Context) ([]models.Department, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+departmentColumns+` FROM departments ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса департаментов: %w", err)
+	}
+	defer rows.Close()
+
+	var departments []models.Department
+	for rows.Next() {
+		dept, err := scanDepartment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения департамента: %w", err)
+		}
+		departments = append(departments, dept)
+	}
+	return departments, rows.Err()
+}
+
+func (r *SQLRepository) GetDepartment(ctx context.Context, id string) (*models.Department, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+departmentColumns+` FROM departments WHERE id = ?`, id)
+	dept, err := scanDepartment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDepartmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса департамента: %w", err)
+	}
+	return &dept, nil
+}
+
+func (r *SQLRepository) CreateDepartment(ctx context.Context, dept models.Department) (*models.Department, error) {
+	now := time.Now()
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO departments (id, name, description, parent_id, archived, created_at, updated_at)
+		SELECT 'dept' || (COALESCE(MAX(CAST(SUBSTR(id, 5) AS INTEGER)), 0) + 1), ?, ?, ?, FALSE, ?, ?
+		FROM departments WHERE id LIKE 'dept%'
+		RETURNING id`,
+		dept.Name, dept.Description, nullString(dept.ParentID), now.UnixNano(), now.UnixNano())
+	if err := row.Scan(&dept.ID); err != nil {
+		return nil, fmt.Errorf("ошибка создания департамента: %w", err)
+	}
+	dept.Archived = false
+	dept.ArchivedAt = nil
+	dept.CreatedAt = now
+	dept.UpdatedAt = now
+	return &dept, nil
+}
+
+func (r *SQLRepository) UpdateDepartment(ctx context.Context, dept models.Department) (*models.Department, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE departments SET name = ?, description = ?, parent_id = ?, updated_at = ? WHERE id = ?`,
+		dept.Name, dept.Description, nullString(dept.ParentID), time.Now().UnixNano(), dept.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка обновления департамента: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrDepartmentNotFound
+	}
+	return r.GetDepartment(ctx, dept.ID)
+}
+
+func (r *SQLRepository) ArchiveDepartment(ctx context.Context, id string) (*models.Department, error) {
+	// The checks are part of the update, and hires check the archived flag
+	// in their insert, so neither a hire nor a new subdepartment can slip
+	// in between
+	now := time.Now()
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE departments SET archived = TRUE, archived_at = ?, updated_at = ?
+		WHERE id = ?
+			AND NOT EXISTS (SELECT 1 FROM employees WHERE department_id = ? AND status <> 'fired')
+			AND NOT EXISTS (SELECT 1 FROM departments WHERE parent_id = ? AND NOT archived)`,
+		now.UnixNano(), now.UnixNano(), id, id, id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка архивации департамента: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, r.archiveBlocker(ctx, id)
+	}
+	return r.GetDepartment(ctx, id)
+}
+
+// archiveBlocker tells why ArchiveDepartment did not update the department
+func (r *SQLRepository) archiveBlocker(ctx context.Context, id string) error {
+	if _, err := r.GetDepartment(ctx, id); err != nil {
+		return err
+	}
+	var working bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM employees WHERE department_id = ? AND status <> 'fired')`, id).Scan(&working)
+	if err != nil {
+		return fmt.Errorf("ошибка архивации департамента: %w", err)
+	}
+	if working {
+		return ErrDepartmentHasEmployees
+	}
+	return ErrDepartmentHasChildren
+}
+
+func (r *SQLRepository) GetEmployeesByDepartment(ctx context.Context, departmentID string) ([]models.Employee, error) {
+	return r.queryEmployees(ctx,
+		`SELECT `+employeeColumns+` FROM employees WHERE department_id = ? ORDER BY id`, departmentID)
+}
+
+func (r *SQLRepository) GetEmployee(ctx context.Context, id string) (*models.Employee, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+employeeColumns+` FROM employees WHERE id = ?`, id)
+	emp, err := scanEmployee(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrEmployeeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса сотрудника: %w", err)
+	}
+	return &emp, nil
+}
+
+// SearchEmployees ranks name searches in the database in the order of
+// rankByName: by score, then by name and ID
+func (r *SQLRepository) SearchEmployees(ctx context.Context, req models.EmployeeSearchRequest) ([]models.Employee, error) {
+	where, args := employeeFilters(req)
+	order := ` ORDER BY id`
+	if req.FullName != "" {
+		order = ` ORDER BY ` + nameScoreFunc + `(full_name, ?) DESC, full_name, id`
+		args = append(args, req.FullName)
+	}
+	return r.queryEmployees(ctx, `SELECT `+employeeColumns+` FROM employees`+where+order, args...)
+}
+
+// SearchEmployeesPage sorts and pages in the database, seeking past the
+// (key, id) of q.After
+func (r *SQLRepository) SearchEmployeesPage(ctx context.Context, req models.EmployeeSearchRequest, q PageQuery) ([]models.Employee, int, error) {
+	key, ok := sqlSortKeys[q.Sort]
+	if !ok {
+		return nil, 0, fmt.Errorf("неверное поле сортировки: %s", q.Sort)
+	}
+
+	where, args := employeeFilters(req)
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM employees`+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка подсчета сотрудников: %w", err)
+	}
+
+	direction, seek := ` ASC`, ` > `
+	if q.Desc {
+		direction, seek = ` DESC`, ` < `
+	}
+	if q.After != nil {
+		if where == "" {
+			where = ` WHERE `
+		} else {
+			where += ` AND `
+		}
+		where += `(` + key.expr + `, id)` + seek + `(?, ?)`
+		args = append(args, key.value(q.After), q.After.ID)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1 // no limit in SQLite
+	}
+	query := `SELECT ` + employeeColumns + ` FROM employees` + where +
+		` ORDER BY ` + key.expr + direction + `, id` + direction + ` LIMIT ?`
+	employees, err := r.queryEmployees(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return employees, total, nil
+}
+
+// employeeFilters returns the WHERE clause of the filters of req and its
+// arguments
+func employeeFilters(req models.EmployeeSearchRequest) (string, []any) {
+	var where []string
+	var args []any
+	if req.FullName != "" {
+		where = append(where, nameScoreFunc+`(full_name, ?) > 0`)
+		args = append(args, req.FullName)
+	}
+	if req.Position != "" {
+		where = append(where, `position = ?`)
+		args = append(args, req.Position)
+	}
+	if req.Gender != "" {
+		where = append(where, `gender = ?`)
+		args = append(args, req.Gender)
+	}
+	if req.Education != "" {
+		where = append(where, `education = ?`)
+		args = append(args, req.Education)
+	}
+	if req.AgeFrom != nil {
+		where = append(where, `age >= ?`)
+		args = append(args, *req.AgeFrom)
+	}
+	if req.AgeTo != nil {
+		where = append(where, `age <= ?`)
+		args = append(args, *req.AgeTo)
+	}
+	if req.DepartmentID != "" {
+		where = append(where, `department_id = ?`)
+		args = append(args, req.DepartmentID)
+	}
+	if req.Status != "" {
+		where = append(where, `status = ?`)
+		args = append(args, req.Status)
+	}
+	if req.Passport != "" {
+		where = append(where, `passport = ?`)
+		args = append(args, req.Passport)
+	}
+	if len(where) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(where, ` AND `), args
+}
+
+func (r *SQLRepository) CreateEmployee(ctx context.Context, emp models.Employee) (*models.Employee, error) {
+	now := time.Now()
+	if emp.Status == "" {
+		emp.Status = "active"
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if r.ids != nil {
+		emp.ID, err = r.ids.NewID(ctx)
+	} else {
+		// The sequence row is locked until commit, so concurrent inserts
+		// cannot get the same number
+		var n uint64
+		err = tx.QueryRowContext(ctx,
+			`UPDATE id_sequences SET value = value + 1 WHERE name = 'employees' RETURNING value`).Scan(&n)
+		emp.ID = sequenceID(employeeIDPrefix, n)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выдачи идентификатора: %w", err)
+	}
+
+	// The archived check is part of the insert, see ArchiveDepartment
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO employees (id, full_name, gender, age, education, position, position_id, passport,
+			department_id, status, created_at, updated_at)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM departments WHERE id = ? AND archived)`,
+		emp.ID, emp.FullName, emp.Gender, emp.Age, emp.Education, emp.Position, nullString(emp.PositionID),
+		emp.Passport, emp.DepartmentID, emp.Status, now.UnixNano(), now.UnixNano(), emp.DepartmentID)
+	if err == nil {
+		var n int64
+		if n, err = res.RowsAffected(); err == nil && n == 0 {
+			return nil, ErrDepartmentArchived
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	switch {
+	case err == nil:
+	case isUniqueViolation(err, "employees.passport"):
+		return nil, ErrDuplicatePassport
+	case isUniqueViolation(err, "employees.id"):
+		return nil, fmt.Errorf("идентификатор %s уже занят", emp.ID)
+	default:
+		return nil, fmt.Errorf("ошибка создания сотрудника: %w", err)
+	}
+	emp.CreatedAt = now
+	emp.UpdatedAt = now
+	return &emp, nil
+}
+
+func (r *SQLRepository) UpdateEmployee(ctx context.Context, emp models.Employee) (*models.Employee, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE employees SET full_name = ?, gender = ?, age = ?, education = ?, position = ?,
+			position_id = ?, passport = ?, department_id = ?, updated_at = ?
+		WHERE id = ?`,
+		emp.FullName, emp.Gender, emp.Age, emp.Education, emp.Position,
+		nullString(emp.PositionID), emp.Passport, emp.DepartmentID, time.Now().UnixNano(), emp.ID)
+	if err != nil {
+		if isUniqueViolation(err, "employees.passport") {
+			return nil, ErrDuplicatePassport
+		}
+		return nil, fmt.Errorf("ошибка обновления сотрудника: %w", err)
+	}
+	if err := requireRow(res); err != nil {
+		return nil, err
+	}
+	return r.GetEmployee(ctx, emp.ID)
+}
+
+// maxStatusAttempts bounds the retries of UpdateEmployeeStatus while the
+// status keeps changing under it
+const maxStatusAttempts = 10
+
+// UpdateEmployeeStatus only updates the row while it still has the status
+// read before, retrying otherwise, so two concurrent transitions cannot
+// both report the same previous status
+func (r *SQLRepository) UpdateEmployeeStatus(ctx context.Context, id string, status string) (*models.Employee, string, error) {
+	for attempt := 0; attempt < maxStatusAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, "", err
+		}
+		current, err := r.GetEmployee(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+
+		now := time.Now()
+		var firedAt *time.Time
+		if status == "fired" {
+			firedAt = &now
+		}
+		row := r.db.QueryRowContext(ctx, `
+			UPDATE employees SET status = ?, updated_at = ?, fired_at = ?
+			WHERE id = ? AND status = ?
+			RETURNING `+employeeColumns,
+			status, now.UnixNano(), nullUnixNano(firedAt), id, current.Status)
+		updated, err := scanEmployee(row)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("ошибка обновления статуса: %w", err)
+		}
+		return &updated, current.Status, nil
+	}
+	return nil, "", fmt.Errorf("статус сотрудника %s меняется одновременно другими запросами", id)
+}
+
+func requireRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrEmployeeNotFound
+	}
+	return nil
+}
+
+func scanPosition(row rowScanner) (models.Position, error) {
+	var pos models.Position
+	err := row.Scan(&pos.ID, &pos.Title, &pos.DepartmentID, &pos.Grade, &pos.Active,
+		unixTime{&pos.CreatedAt}, unixTime{&pos.UpdatedAt})
+	return pos, err
+}
+
+func (r *SQLRepository) GetPositions(ctx context.Context) ([]models.Position, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+positionColumns+` FROM positions ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса должностей: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []models.Position
+	for rows.Next() {
+		pos, err := scanPosition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения должности: %w", err)
+		}
+		positions = append(positions, pos)
+	}
+	return positions, rows.Err()
+}
+
+func (r *SQLRepository) GetPosition(ctx context.Context, id string) (*models.Position, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+positionColumns+` FROM positions WHERE id = ?`, id)
+	pos, err := scanPosition(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPositionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса должности: %w", err)
+	}
+	return &pos, nil
+}
+
+// CreatePosition takes the number from the positions row of id_sequences,
+// which is never decremented, so IDs of deleted positions are not reused
+func (r *SQLRepository) CreatePosition(ctx context.Context, pos models.Position) (*models.Position, error) {
+	now := time.Now()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var n uint64
+	err = tx.QueryRowContext(ctx,
+		`UPDATE id_sequences SET value = value + 1 WHERE name = 'positions' RETURNING value`).Scan(&n)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выдачи идентификатора: %w", err)
+	}
+	pos.ID = sequenceID(positionIDPrefix, n)
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO positions (id, title, department_id, grade, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		pos.ID, pos.Title, pos.DepartmentID, pos.Grade, pos.Active, now.UnixNano(), now.UnixNano())
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		if isUniqueViolation(err, "positions.title") {
+			return nil, ErrDuplicatePosition
+		}
+		return nil, fmt.Errorf("ошибка создания должности: %w", err)
+	}
+	pos.CreatedAt = now
+	pos.UpdatedAt = now
+	return &pos, nil
+}
+
+// UpdatePosition updates the position and the title stored on the
+// employees holding it in one transaction
+func (r *SQLRepository) UpdatePosition(ctx context.Context, pos models.Position) (*models.Position, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE positions SET title = ?, department_id = ?, grade = ?, active = ?, updated_at = ? WHERE id = ?`,
+		pos.Title, pos.DepartmentID, pos.Grade, pos.Active, time.Now().UnixNano(), pos.ID)
+	if err != nil {
+		if isUniqueViolation(err, "positions.title") {
+			return nil, ErrDuplicatePosition
+		}
+		return nil, fmt.Errorf("ошибка обновления должности: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrPositionNotFound
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE employees SET position = ? WHERE position_id = ? AND position <> ?`, pos.Title, pos.ID, pos.Title)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка обновления должности сотрудников: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetPosition(ctx, pos.ID)
+}
+
+func (r *SQLRepository) DeletePosition(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM positions
+		WHERE id = ? AND NOT EXISTS (SELECT 1 FROM employees WHERE position_id = ?)`,
+		id, id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления должности: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		if _, err := r.GetPosition(ctx, id); err != nil {
+			return err
+		}
+		return ErrPositionInUse
+	}
+	return nil
+}
+
+func (r *SQLRepository) GetEmployeeStats(ctx context.Context) (map[string]interface{}, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT status, department_id, COUNT(*) FROM employees GROUP BY status, department_id`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка расчета статистики: %w", err)
+	}
+	defer rows.Close()
+
+	statusCount := make(map[string]int)
+	deptCount := make(map[string]int)
+	total := 0
+	for rows.Next() {
+		var status, dept string
+		var n int
+		if err := rows.Scan(&status, &dept, &n); err != nil {
+			return nil, fmt.Errorf("ошибка расчета статистики: %w", err)
+		}
+		total += n
+		statusCount[status] += n
+		deptCount[dept] += n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"total":         total,
+		"by_status":     statusCount,
+		"by_department": deptCount,
+	}, nil
+}
+
+// isUniqueViolation reports whether err comes from a unique or primary key
+// constraint covering column, given as table.column the way SQLite names it
+func isUniqueViolation(err error, column string) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	if code := sqliteErr.Code(); code != sqlite3.SQLITE_CONSTRAINT_UNIQUE && code != sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+		return false
+	}
+	// The columns are only named in the message:
+	// "constraint failed: UNIQUE constraint failed: employees.passport (2067)"
+	_, columns, ok := strings.Cut(sqliteErr.Error(), " constraint failed: ")
+	if !ok {
+		return false
+	}
+	columns, _, _ = strings.Cut(columns, " (")
+	for _, c := range strings.Split(columns, ",") {
+		if strings.TrimSpace(c) == column {
+			return true
+		}
+	}
+	return false
+}
+
+// SeedDemoData fills an empty database with the demo data MemoryRepository
+// starts with. It reports whether anything was added; a database that has
+// departments is left alone.
+func (r *SQLRepository) SeedDemoData(ctx context.Context) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM departments`).Scan(&n); err != nil {
+		return false, fmt.Errorf("ошибка проверки демонстрационных данных: %w", err)
+	}
+	if n > 0 {
+		return false, nil
+	}
+
+	departments, employees := demoData(time.Now())
+	var lastID uint64
+	for _, dept := range departments {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO departments (id, name, description, parent_id, archived, created_at, updated_at)
+			VALUES (?, ?, ?, ?, FALSE, ?, ?)`,
+			dept.ID, dept.Name, dept.Description, nullString(dept.ParentID), dept.CreatedAt.UnixNano(), dept.UpdatedAt.UnixNano())
+		if err != nil {
+			return false, fmt.Errorf("ошибка добавления демонстрационных данных: %w", err)
+		}
+	}
+	for _, emp := range employees {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO employees (id, full_name, gender, age, education, position, position_id, passport,
+				department_id, status, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			emp.ID, emp.FullName, emp.Gender, emp.Age, emp.Education, emp.Position, nullString(emp.PositionID),
+			emp.Passport, emp.DepartmentID, emp.Status, emp.CreatedAt.UnixNano(), emp.UpdatedAt.UnixNano())
+		if err != nil {
+			return false, fmt.Errorf("ошибка добавления демонстрационных данных: %w", err)
+		}
+		if n, ok := sequenceNumber(employeeIDPrefix, emp.ID); ok && n > lastID {
+			lastID = n
+		}
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE id_sequences SET value = MAX(value, ?) WHERE name = 'employees'`, lastID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка добавления демонстрационных данных: %w", err)
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"employee-management/internal/models"
+)
+
+// newTestSQLRepository returns a repository over a migrated database with
+// the demo data
+func newTestSQLRepository(t testing.TB) *SQLRepository {
+	t.Helper()
+	ctx := context.Background()
+	db := newTestDB(t)
+	migrator, err := NewMigrator(db)
+	if err == nil {
+		err = migrator.Up(ctx)
+	}
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := NewSQLRepository(db)
+	if _, err := repo.SeedDemoData(ctx); err != nil {
+		t.Fatalf("SeedDemoData: %v", err)
+	}
+	return repo
+}
+
+type fixedIDGenerator string
+
+func (g fixedIDGenerator) NewID(ctx context.Context) (string, error) { return string(g), nil }
+
+func TestSQLSeedDemoData(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLRepository(t)
+
+	if seeded, err := repo.SeedDemoData(ctx); err != nil || seeded {
+		t.Errorf("second SeedDemoData = %v, %v, want the database left alone", seeded, err)
+	}
+	emp, err := repo.GetEmployee(ctx, "emp1")
+	if err != nil {
+		t.Fatalf("GetEmployee: %v", err)
+	}
+	if emp.PositionID != "pos1" || emp.DepartmentID != "dept1" {
+		t.Errorf("emp1 = %+v, want the demo employee", emp)
+	}
+
+	created, err := repo.CreateEmployee(ctx, newTestEmployee("9999 000001"))
+	if err != nil {
+		t.Fatalf("CreateEmployee: %v", err)
+	}
+	if created.ID != "emp5" {
+		t.Errorf("new employee ID = %s, want emp5 after the seeded emp4", created.ID)
+	}
+}
+
+func TestSQLPassportIsUnique(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLRepository(t)
+
+	if _, err := repo.CreateEmployee(ctx, newTestEmployee("1234 567890")); !errors.Is(err, ErrDuplicatePassport) {
+		t.Errorf("CreateEmployee with a taken passport: %v, want ErrDuplicatePassport", err)
+	}
+
+	emp2, _ := repo.GetEmployee(ctx, "emp2")
+	emp2.Passport = "1234 567890"
+	if _, err := repo.UpdateEmployee(ctx, *emp2); !errors.Is(err, ErrDuplicatePassport) {
+		t.Errorf("UpdateEmployee with a taken passport: %v, want ErrDuplicatePassport", err)
+	}
+
+	// Keeping the own passport is not a conflict
+	emp1, _ := repo.GetEmployee(ctx, "emp1")
+	emp1.Age++
+	if _, err := repo.UpdateEmployee(ctx, *emp1); err != nil {
+		t.Errorf("UpdateEmployee keeping the passport: %v", err)
+	}
+}
+
+func TestSQLIDCollisionIsNotDuplicatePassport(t *testing.T) {
+	repo := newTestSQLRepository(t)
+	repo.SetIDGenerator(fixedIDGenerator("emp1"))
+
+	_, err := repo.CreateEmployee(context.Background(), newTestEmployee("9999 000002"))
+	if err == nil || errors.Is(err, ErrDuplicatePassport) {
+		t.Errorf("CreateEmployee with a taken ID: %v, want an ID error", err)
+	}
+}
+
+func TestSQLSearchIsParameterized(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLRepository(t)
+
+	for _, req := range []models.EmployeeSearchRequest{
+		{Position: "Программист' OR '1'='1"},
+		{Gender: "male'; DROP TABLE employees; --"},
+		{Education: `higher" OR 1=1 --`},
+	} {
+		found, err := repo.SearchEmployees(ctx, req)
+		if err != nil {
+			t.Fatalf("SearchEmployees(%+v): %v", req, err)
+		}
+		if len(found) != 0 {
+			t.Errorf("SearchEmployees(%+v) = %d employees, want the value matched literally", req, len(found))
+		}
+	}
+
+	ageFrom, ageTo := 30, 40
+	found, err := repo.SearchEmployees(ctx, models.EmployeeSearchRequest{Gender: "male", AgeFrom: &ageFrom, AgeTo: &ageTo})
+	if err != nil {
+		t.Fatalf("SearchEmployees: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != "emp1" {
+		t.Errorf("male employees aged 30-40 = %v, want emp1", found)
+	}
+}
+
+func TestSQLUpdateEmployeeStatusReturnsPrevious(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLRepository(t)
+
+	updated, previous, err := repo.UpdateEmployeeStatus(ctx, "emp2", "fired")
+	if err != nil {
+		t.Fatalf("UpdateEmployeeStatus: %v", err)
+	}
+	if previous != "vacation" || updated.Status != "fired" || updated.FiredAt == nil {
+		t.Errorf("UpdateEmployeeStatus = %s (fired at %v), previous %s; want fired from vacation",
+			updated.Status, updated.FiredAt, previous)
+	}
+	if _, _, err := repo.UpdateEmployeeStatus(ctx, "emp99", "fired"); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Errorf("UpdateEmployeeStatus of a missing employee: %v, want ErrEmployeeNotFound", err)
+	}
+}
+
+func TestSQLStoresTimestampsAsUnixNanos(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLRepository(t)
+	created, err := repo.CreateEmployee(ctx, newTestEmployee("9999 000001"))
+	if err != nil {
+		t.Fatalf("CreateEmployee: %v", err)
+	}
+
+	for _, table := range []string{"employees", "departments", "positions"} {
+		var other int
+		err := repo.db.QueryRow(`SELECT COUNT(*) FROM ` + table + ` WHERE typeof(created_at) <> 'integer'`).Scan(&other)
+		if err != nil {
+			t.Fatalf("query %s: %v", table, err)
+		}
+		if other != 0 {
+			t.Errorf("%s has %d timestamps not stored as integers", table, other)
+		}
+	}
+	got, err := repo.GetEmployee(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetEmployee: %v", err)
+	}
+	if !got.CreatedAt.Equal(created.CreatedAt) || !got.UpdatedAt.Equal(created.UpdatedAt) {
+		t.Errorf("read back %v/%v, want %v", got.CreatedAt, got.UpdatedAt, created.CreatedAt)
+	}
+}
+
+func TestSQLUpdateEmployeeStatusStopsWhenCanceled(t *testing.T) {
+	repo := newTestSQLRepository(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := repo.UpdateEmployeeStatus(ctx, "emp1", "vacation"); !errors.Is(err, context.Canceled) {
+		t.Errorf("UpdateEmployeeStatus with a canceled context: %v, want context.Canceled", err)
+	}
+}
+
+func TestSQLSequenceGeneratorSkipsNoNumbers(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestSQLRepository(t)
+	gen, err := NewIDGenerator(IDGeneratorSequence, repo)
+	if err != nil {
+		t.Fatalf("NewIDGenerator: %v", err)
+	}
+	repo.SetIDGenerator(gen)
+
+	// The number is taken in the transaction of the failed insert
+	if _, err := repo.CreateEmployee(ctx, newTestEmployee("1234 567890")); !errors.Is(err, ErrDuplicatePassport) {
+		t.Fatalf("CreateEmployee with a taken passport: %v, want ErrDuplicatePassport", err)
+	}
+	created, err := repo.CreateEmployee(ctx, newTestEmployee("9999 000001"))
+	if err != nil {
+		t.Fatalf("CreateEmployee: %v", err)
+	}
+	if created.ID != "emp5" {
+		t.Errorf("ID = %s, want emp5", created.ID)
+	}
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Frames are a little-endian payload length and CRC-32C of the payload
+// followed by the payload itself
+const (
+	frameHeaderSize = 8
+	maxFrameSize    = 64 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errCorruptFrame marks a truncated frame or a checksum mismatch
+var errCorruptFrame = errors.New("поврежденная запись")
+
+func encodeFrame(payload []byte) []byte {
+	frame := make([]byte, frameHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.Checksum(payload, crcTable))
+	copy(frame[frameHeaderSize:], payload)
+	return frame
+}
+
+// readFrame returns the next payload, io.EOF at a clean end of input and
+// errCorruptFrame when the rest of the input is not a valid frame
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	var header [frameHeaderSize]byte
+	n, err := io.ReadFull(r, header[:])
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil || n < frameHeaderSize {
+		return nil, errCorruptFrame
+	}
+
+	size := binary.LittleEndian.Uint32(header[0:4])
+	if size > maxFrameSize {
+		return nil, errCorruptFrame
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, errCorruptFrame
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, errCorruptFrame
+	}
+	return payload, nil
+}
+
+// writeFileAtomic replaces path with data through a synced temporary file,
+// so a crash leaves either the old or the new content
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir makes a rename in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("синхронизация каталога %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"employee-management/internal/models"
+	"employee-management/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GetDepartments returns departments, archived ones only with includeArchived
+func (s *EmployeeService) GetDepartments(ctx context.Context, includeArchived bool) ([]models.Department, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.GetDepartments",
+		trace.WithAttributes(attribute.Bool("include_archived", includeArchived)))
+	defer span.End()
+
+	slog.DebugContext(ctx, "getting departments")
+	departments, err := s.repo.GetDepartments(ctx)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	if !includeArchived {
+		departments = activeDepartments(departments)
+	}
+	span.SetAttributes(attribute.Int("result.count", len(departments)))
+	return departments, nil
+}
+
+func (s *EmployeeService) GetDepartment(ctx context.Context, id string) (*models.Department, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.GetDepartment",
+		trace.WithAttributes(attribute.String("department_id", id)))
+	defer span.End()
+
+	dept, err := s.repo.GetDepartment(ctx, id)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	return dept, nil
+}
+
+func (s *EmployeeService) CreateDepartment(ctx context.Context, dept models.Department) (*models.Department, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.CreateDepartment",
+		trace.WithAttributes(attribute.String("department.parent_id", dept.ParentID)))
+	defer span.End()
+
+	slog.DebugContext(ctx, "creating department", "name", dept.Name)
+	if err := s.validateDepartment(ctx, dept); err != nil {
+		return nil, checkFailed(span, err)
+	}
+	created, err := s.repo.CreateDepartment(ctx, dept)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	span.SetAttributes(attribute.String("department_id", created.ID))
+	return created, nil
+}
+
+func (s *EmployeeService) UpdateDepartment(ctx context.Context, dept models.Department) (*models.Department, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.UpdateDepartment", trace.WithAttributes(
+		attribute.String("department_id", dept.ID),
+		attribute.String("department.parent_id", dept.ParentID),
+	))
+	defer span.End()
+
+	slog.DebugContext(ctx, "updating department", "department_id", dept.ID)
+	if _, err := s.repo.GetDepartment(ctx, dept.ID); err != nil {
+		return nil, recordError(span, err)
+	}
+	if err := s.validateDepartment(ctx, dept); err != nil {
+		return nil, checkFailed(span, err)
+	}
+	updated, err := s.repo.UpdateDepartment(ctx, dept)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	return updated, nil
+}
+
+// ArchiveDepartment archives a department that has no working employees
+// and no active subdepartments
+func (s *EmployeeService) ArchiveDepartment(ctx context.Context, id string) (*models.Department, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.ArchiveDepartment",
+		trace.WithAttributes(attribute.String("department_id", id)))
+	defer span.End()
+
+	slog.DebugContext(ctx, "archiving department", "department_id", id)
+	archived, err := s.repo.ArchiveDepartment(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrDepartmentHasEmployees):
+			span.AddEvent("department_has_employees")
+		case errors.Is(err, repository.ErrDepartmentHasChildren):
+			span.AddEvent("department_has_children")
+		}
+		return nil, recordError(span, err)
+	}
+	return archived, nil
+}
+
+// GetDepartmentTree returns root departments with their subdepartments and
+// headcounts. Employees who are fired are not counted.
+func (s *EmployeeService) GetDepartmentTree(ctx context.Context, includeArchived bool) ([]*models.DepartmentNode, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.GetDepartmentTree",
+		trace.WithAttributes(attribute.Bool("include_archived", includeArchived)))
+	defer span.End()
+
+	departments, err := s.repo.GetDepartments(ctx)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	if !includeArchived {
+		departments = activeDepartments(departments)
+	}
+	employees, err := s.repo.SearchEmployees(ctx, models.EmployeeSearchRequest{})
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+
+	headcount := make(map[string]int)
+	for _, emp := range employees {
+		if emp.Status != "fired" {
+			headcount[emp.DepartmentID]++
+		}
+	}
+
+	nodes := make(map[string]*models.DepartmentNode, len(departments))
+	for _, dept := range departments {
+		nodes[dept.ID] = &models.DepartmentNode{Department: dept, Headcount: headcount[dept.ID]}
+	}
+	var roots []*models.DepartmentNode
+	for _, dept := range departments {
+		node := nodes[dept.ID]
+		if parent, ok := nodes[dept.ParentID]; ok && dept.ParentID != dept.ID {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	sortDepartmentNodes(roots)
+	for _, root := range roots {
+		sumHeadcount(root)
+	}
+
+	span.SetAttributes(attribute.Int("result.count", len(departments)))
+	return roots, nil
+}
+
+func sortDepartmentNodes(nodes []*models.DepartmentNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	for _, node := range nodes {
+		sortDepartmentNodes(node.Children)
+	}
+}
+
+// sumHeadcount fills TotalHeadcount of node and its subtree
+func sumHeadcount(node *models.DepartmentNode) int {
+	node.TotalHeadcount = node.Headcount
+	for _, child := range node.Children {
+		node.TotalHeadcount += sumHeadcount(child)
+	}
+	return node.TotalHeadcount
+}
+
+func activeDepartments(departments []models.Department) []models.Department {
+	active := departments[:0:0]
+	for _, dept := range departments {
+		if !dept.Archived {
+			active = append(active, dept)
+		}
+	}
+	return active
+}
+
+// validateDepartment checks the name and that the parent exists, is not
+// archived and does not make the hierarchy cyclic
+func (s *EmployeeService) validateDepartment(ctx context.Context, dept models.Department) error {
+	if strings.TrimSpace(dept.Name) == "" {
+		return fmt.Errorf("название департамента обязательно")
+	}
+	if dept.ParentID == "" {
+		return nil
+	}
+	if dept.ParentID == dept.ID {
+		return fmt.Errorf("департамент не может быть родителем самого себя")
+	}
+
+	departments, err := s.repo.GetDepartments(ctx)
+	if err != nil {
+		return &storageError{err}
+	}
+	byID := make(map[string]models.Department, len(departments))
+	for _, d := range departments {
+		byID[d.ID] = d
+	}
+	parent, ok := byID[dept.ParentID]
+	if !ok {
+		return fmt.Errorf("родительский департамент не найден: %s", dept.ParentID)
+	}
+	if parent.Archived {
+		return fmt.Errorf("родительский департамент в архиве: %s", dept.ParentID)
+	}
+	for id, steps := parent.ParentID, 0; id != "" && steps < len(byID); id, steps = byID[id].ParentID, steps+1 {
+		if dept.ID != "" && id == dept.ID {
+			return fmt.Errorf("департамент %s не может быть вложен в собственный поддепартамент", dept.ID)
+		}
+	}
+	return nil
+}
+
+// checkDepartmentOpen checks that employees can be assigned to the department
+func (s *EmployeeService) checkDepartmentOpen(ctx context.Context, id string) error {
+	dept, err := s.repo.GetDepartment(ctx, id)
+	if errors.Is(err, repository.ErrDepartmentNotFound) {
+		return fmt.Errorf("департамент не найден: %s", id)
+	}
+	if err != nil {
+		return &storageError{err}
+	}
+	if dept.Archived {
+		return fmt.Errorf("департамент в архиве: %s", dept.Name)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"employee-management/internal/models"
+	"employee-management/internal/repository"
+	"employee-management/internal/telemetry"
+)
+
+var errStorage = errors.New("хранилище недоступно")
+
+// failingDepartmentsRepository fails every read of departments
+type failingDepartmentsRepository struct {
+	repository.Repository
+}
+
+func (failingDepartmentsRepository) GetDepartments(ctx context.Context) ([]models.Department, error) {
+	return nil, errStorage
+}
+
+func (failingDepartmentsRepository) GetDepartment(ctx context.Context, id string) (*models.Department, error) {
+	return nil, errStorage
+}
+
+func TestDepartmentChecksReturnStorageErrors(t *testing.T) {
+	ctx := context.Background()
+	svc := NewEmployeeService(failingDepartmentsRepository{repository.NewMemoryRepository()},
+		telemetry.NewMetrics(telemetry.BuildInfo{}))
+
+	_, createDeptErr := svc.CreateDepartment(ctx, models.Department{Name: "Новый отдел", ParentID: "dept1"})
+	_, createEmpErr := svc.CreateEmployee(ctx, models.Employee{
+		FullName: "Смирнов Олег Петрович", Gender: "male", Age: 30, Education: "higher",
+		Position: "Тестировщик", Passport: "9999 000001", DepartmentID: "dept1",
+	})
+	for name, err := range map[string]error{"CreateDepartment": createDeptErr, "CreateEmployee": createEmpErr} {
+		var validationErr *ValidationError
+		if !errors.Is(err, errStorage) || errors.As(err, &validationErr) {
+			t.Errorf("%s error = %v (%T), want the storage error, not a ValidationError", name, err, err)
+		}
+	}
+
+	_, err := svc.CreateDepartment(ctx, models.Department{Name: " "})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("CreateDepartment without a name: %v, want a ValidationError", err)
+	}
+}
+
+func TestPositionChecksReturnStorageErrors(t *testing.T) {
+	svc := NewEmployeeService(failingDepartmentsRepository{repository.NewMemoryRepository()},
+		telemetry.NewMetrics(telemetry.BuildInfo{}))
+
+	_, err := svc.CreatePosition(context.Background(), models.Position{Title: "Архитектор", Grade: 3, DepartmentID: "dept1"})
+	var validationErr *ValidationError
+	if !errors.Is(err, errStorage) || errors.As(err, &validationErr) {
+		t.Errorf("CreatePosition error = %v (%T), want the storage error, not a ValidationError", err, err)
+	}
+}
@@ -2,73 +2,305 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
 	"employee-management/internal/models"
 	"employee-management/internal/repository"
+	"employee-management/internal/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // EmployeeService handles business logic for employees
 type EmployeeService struct {
-	repo repository.Repository
+	repo    repository.Repository
+	metrics *telemetry.Metrics
+	tracer  trace.Tracer
+
+	// refreshMu makes each refresh read and publish the stats in one
+	// step, so a refresh that read older stats cannot publish them after
+	// a newer one
+	refreshMu sync.Mutex
 }
 
 // NewEmployeeService creates a new employee service
-func NewEmployeeService(repo repository.Repository) *EmployeeService {
-	return &EmployeeService{repo: repo}
+func NewEmployeeService(repo repository.Repository, metrics *telemetry.Metrics) *EmployeeService {
+	return &EmployeeService{repo: repo, metrics: metrics, tracer: otel.Tracer("employee-service")}
 }
 
-func (s *EmployeeService) GetDepartments(ctx context.Context) ([]models.Department, error) {
-	slog.DebugContext(ctx, "getting departments")
-	return s.repo.GetDepartments(ctx)
-}
+func (s *EmployeeService) GetEmployeesByDepartment(ctx context.Context, departmentID string, req models.PageRequest) (*models.EmployeePage, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.GetEmployeesByDepartment", trace.WithAttributes(
+		attribute.String("department_id", departmentID),
+		attribute.String("page.sort", req.Sort),
+		attribute.Bool("page.continued", req.Cursor != ""),
+	))
+	defer span.End()
 
-func (s *EmployeeService) GetEmployeesByDepartment(ctx context.Context, departmentID string) ([]models.Employee, error) {
 	slog.DebugContext(ctx, "getting employees by department", "department_id", departmentID)
-	return s.repo.GetEmployeesByDepartment(ctx, departmentID)
+	spec, err := parsePage(req, false)
+	if err != nil {
+		return nil, validationFailed(span, err)
+	}
+	if req.Limit == 0 && req.Cursor == "" {
+		// Clients from before paging get the whole department
+		spec.limit = 0
+	}
+	employees, total, err := s.repo.SearchEmployeesPage(ctx,
+		models.EmployeeSearchRequest{DepartmentID: departmentID}, spec.query())
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	page := spec.page(employees, total)
+	if page.Headcount, err = s.headcount(ctx, departmentID); err != nil {
+		return nil, recordError(span, err)
+	}
+	span.SetAttributes(attribute.Int("result.count", len(page.Items)), attribute.Int("result.total", page.Total))
+	return page, nil
 }
 
-func (s *EmployeeService) SearchEmployees(ctx context.Context, req models.EmployeeSearchRequest) ([]models.Employee, error) {
+// headcount counts the employees of a department who are not fired
+func (s *EmployeeService) headcount(ctx context.Context, departmentID string) (int, error) {
+	n := 0
+	for _, status := range []string{"active", "vacation"} {
+		_, total, err := s.repo.SearchEmployeesPage(ctx,
+			models.EmployeeSearchRequest{DepartmentID: departmentID, Status: status},
+			repository.PageQuery{Sort: "name", Limit: 1})
+		if err != nil {
+			return 0, err
+		}
+		n += total
+	}
+	return n, nil
+}
+
+// SearchEmployees returns a page of employees matching req. Name searches
+// are ordered by relevance unless req sets another order.
+func (s *EmployeeService) SearchEmployees(ctx context.Context, req models.EmployeeSearchRequest) (*models.EmployeePage, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.SearchEmployees", trace.WithAttributes(
+		append(repository.SearchAttributes(req),
+			attribute.String("page.sort", req.Sort),
+			attribute.Bool("page.continued", req.Cursor != ""),
+		)...))
+	defer span.End()
+
 	slog.DebugContext(ctx, "searching employees", "filters", req)
-	return s.repo.SearchEmployees(ctx, req)
+	spec, err := parsePage(req.PageRequest, req.FullName != "")
+	if err != nil {
+		return nil, validationFailed(span, err)
+	}
+	var page *models.EmployeePage
+	if spec.sort == sortRelevance {
+		employees, err := s.repo.SearchEmployees(ctx, req)
+		if err != nil {
+			return nil, recordError(span, err)
+		}
+		page = spec.rankedPage(employees, req.FullName)
+	} else {
+		employees, total, err := s.repo.SearchEmployeesPage(ctx, req, spec.query())
+		if err != nil {
+			return nil, recordError(span, err)
+		}
+		page = spec.page(employees, total)
+	}
+	span.SetAttributes(attribute.Int("result.count", len(page.Items)), attribute.Int("result.total", page.Total))
+	return page, nil
 }
 
 func (s *EmployeeService) CreateEmployee(ctx context.Context, emp models.Employee) (*models.Employee, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.CreateEmployee",
+		trace.WithAttributes(attribute.String("department_id", emp.DepartmentID)))
+	defer span.End()
+
 	slog.DebugContext(ctx, "creating employee", "employee", emp.FullName)
 	if err := s.validateEmployee(emp); err != nil {
-		return nil, err
+		return nil, validationFailed(span, err)
 	}
-	return s.repo.CreateEmployee(ctx, emp)
+	if err := s.checkDepartmentOpen(ctx, emp.DepartmentID); err != nil {
+		return nil, checkFailed(span, err)
+	}
+	if err := s.resolvePosition(ctx, &emp, nil); err != nil {
+		return nil, checkFailed(span, err)
+	}
+	created, err := s.repo.CreateEmployee(ctx, emp)
+	if errors.Is(err, repository.ErrDepartmentArchived) {
+		// Archived after checkDepartmentOpen
+		return nil, validationFailed(span, err)
+	}
+	if err != nil {
+		return nil, storageFailed(span, err)
+	}
+	span.SetAttributes(attribute.String("employee_id", created.ID))
+	s.metrics.RecordHire(created.DepartmentID, created.Position)
+	s.RefreshMetrics(ctx)
+	return created, nil
 }
 
 func (s *EmployeeService) UpdateEmployee(ctx context.Context, emp models.Employee) (*models.Employee, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.UpdateEmployee", trace.WithAttributes(
+		attribute.String("employee_id", emp.ID),
+		attribute.String("department_id", emp.DepartmentID),
+	))
+	defer span.End()
+
 	slog.DebugContext(ctx, "updating employee", "employee_id", emp.ID)
 	if emp.ID == "" {
-		return nil, fmt.Errorf("ID сотрудника обязателен")
+		return nil, validationFailed(span, fmt.Errorf("ID сотрудника обязателен"))
 	}
 	if err := s.validateEmployee(emp); err != nil {
-		return nil, err
+		return nil, validationFailed(span, err)
+	}
+	previous, err := s.repo.GetEmployee(ctx, emp.ID)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	if previous.DepartmentID != emp.DepartmentID {
+		if err := s.checkDepartmentOpen(ctx, emp.DepartmentID); err != nil {
+			return nil, checkFailed(span, err)
+		}
 	}
-	return s.repo.UpdateEmployee(ctx, emp)
+	if err := s.resolvePosition(ctx, &emp, previous); err != nil {
+		return nil, checkFailed(span, err)
+	}
+	updated, err := s.repo.UpdateEmployee(ctx, emp)
+	if err != nil {
+		return nil, storageFailed(span, err)
+	}
+	s.RefreshMetrics(ctx)
+	return updated, nil
 }
 
 func (s *EmployeeService) UpdateEmployeeStatus(ctx context.Context, id string, status string) (*models.Employee, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.UpdateEmployeeStatus", trace.WithAttributes(
+		attribute.String("employee_id", id),
+		attribute.String("employee.status", status),
+	))
+	defer span.End()
+
 	slog.DebugContext(ctx, "updating employee status", "employee_id", id, "status", status)
 	validStatuses := map[string]bool{"active": true, "vacation": true, "fired": true}
 	if !validStatuses[status] {
-		return nil, fmt.Errorf("неверный статус: %s", status)
+		return nil, validationFailed(span, fmt.Errorf("неверный статус: %s", status))
+	}
+	// The previous status comes from the update itself: read separately, two
+	// concurrent transitions could both count the same change
+	updated, previous, err := s.repo.UpdateEmployeeStatus(ctx, id, status)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	span.SetAttributes(attribute.String("employee.previous_status", previous))
+	s.recordStatusChange(previous, *updated)
+	s.RefreshMetrics(ctx)
+	return updated, nil
+}
+
+func (s *EmployeeService) GetEmployeeStats(ctx context.Context) (map[string]interface{}, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.GetEmployeeStats")
+	defer span.End()
+
+	stats, err := s.repo.GetEmployeeStats(ctx)
+	if err != nil {
+		return nil, recordError(span, err)
 	}
-	return s.repo.UpdateEmployeeStatus(ctx, id, status)
+	return stats, nil
 }
 
-func (s *EmployeeService) GetPositions(ctx context.Context) ([]string, error) {
-	slog.DebugContext(ctx, "getting positions")
-	return s.repo.GetPositions(ctx)
+// RefreshMetrics updates headcount gauges from the current repository stats
+func (s *EmployeeService) RefreshMetrics(ctx context.Context) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.RefreshMetrics")
+	defer span.End()
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	stats, err := s.repo.GetEmployeeStats(ctx)
+	if err != nil {
+		recordError(span, err)
+		slog.ErrorContext(ctx, "failed to refresh employee metrics", "error", err)
+		return
+	}
+	s.metrics.UpdateEmployeeMetrics(stats)
 }
 
-func (s *EmployeeService) GetEmployeeStats(ctx context.Context) (map[string]interface{}, error) {
-	return s.repo.GetEmployeeStats(ctx)
+// recordError marks the span as failed and returns err
+func recordError(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+// ValidationError reports invalid input, as opposed to storage failures
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// validationFailed adds a validation_failed event before recording err,
+// which is returned as a ValidationError
+func validationFailed(span trace.Span, err error) error {
+	span.AddEvent("validation_failed", trace.WithAttributes(attribute.String("validation.error", err.Error())))
+	return recordError(span, &ValidationError{Err: err})
+}
+
+// storageError marks a repository failure met while checking input, so it
+// is not reported as invalid input
+type storageError struct {
+	err error
+}
+
+func (e *storageError) Error() string { return e.err.Error() }
+
+func (e *storageError) Unwrap() error { return e.err }
+
+// checkFailed records err from a check that reads the repository: storage
+// errors are returned as they are, anything else as a ValidationError
+func checkFailed(span trace.Span, err error) error {
+	var storageErr *storageError
+	if errors.As(err, &storageErr) {
+		return recordError(span, storageErr.err)
+	}
+	return validationFailed(span, err)
+}
+
+// storageFailed adds a duplicate_passport event for passport conflicts
+// before recording err
+func storageFailed(span trace.Span, err error) error {
+	if errors.Is(err, repository.ErrDuplicatePassport) {
+		span.AddEvent("duplicate_passport")
+	}
+	return recordError(span, err)
+}
+
+// recordStatusChange counts HR events caused by a status transition
+func (s *EmployeeService) recordStatusChange(from string, emp models.Employee) {
+	if from == emp.Status {
+		return
+	}
+	if from == "vacation" {
+		s.metrics.RecordVacationEnd(emp.DepartmentID, emp.Position)
+	}
+	switch emp.Status {
+	case "vacation":
+		s.metrics.RecordVacationStart(emp.DepartmentID, emp.Position)
+	case "fired":
+		tenure := time.Since(emp.CreatedAt)
+		if emp.FiredAt != nil {
+			tenure = emp.FiredAt.Sub(emp.CreatedAt)
+		}
+		s.metrics.RecordTermination(emp.DepartmentID, emp.Position, tenure)
+	case "active":
+		if from == "fired" {
+			s.metrics.RecordHire(emp.DepartmentID, emp.Position)
+		}
+	}
 }
 
 func (s *EmployeeService) validateEmployee(emp models.Employee) error {
@@ -84,7 +316,7 @@ func (s *EmployeeService) validateEmployee(emp models.Employee) error {
 	if emp.Education == "" {
 		return fmt.Errorf("образование обязательно")
 	}
-	if emp.Position == "" {
+	if emp.Position == "" && emp.PositionID == "" {
 		return fmt.Errorf("должность обязательна")
 	}
 	if emp.Passport == "" {
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"employee-management/internal/models"
+	"employee-management/internal/repository"
+	"employee-management/internal/telemetry"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// staleStatsRepository holds back the stats its first GetEmployeeStats
+// call read until release is closed
+type staleStatsRepository struct {
+	repository.Repository
+	calls   atomic.Int32
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (r *staleStatsRepository) GetEmployeeStats(ctx context.Context) (map[string]interface{}, error) {
+	stats, err := r.Repository.GetEmployeeStats(ctx)
+	if r.calls.Add(1) == 1 {
+		close(r.read)
+		<-r.release
+	}
+	return stats, err
+}
+
+func TestRefreshMetricsDoesNotPublishOlderStatsLast(t *testing.T) {
+	ctx := context.Background()
+	repo := &staleStatsRepository{
+		Repository: repository.NewMemoryRepository(),
+		read:       make(chan struct{}),
+		release:    make(chan struct{}),
+	}
+	metrics := telemetry.NewMetrics(telemetry.BuildInfo{})
+	svc := NewEmployeeService(repo, metrics)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := svc.CreateEmployee(ctx, models.Employee{
+			FullName: "Смирнов Олег Петрович", Gender: "male", Age: 30, Education: "higher",
+			PositionID: "pos3", Passport: "9999 000001", DepartmentID: "dept1",
+		})
+		if err != nil {
+			t.Errorf("CreateEmployee: %v", err)
+		}
+	}()
+	<-repo.read // the refresh of the hire holds stats without the next change
+
+	go func() {
+		defer wg.Done()
+		if _, err := svc.UpdateEmployeeStatus(ctx, "emp1", "vacation"); err != nil {
+			t.Errorf("UpdateEmployeeStatus: %v", err)
+		}
+	}()
+	// Give the status change time to commit and start its refresh
+	time.Sleep(50 * time.Millisecond)
+	close(repo.release)
+	wg.Wait()
+
+	if got := testutil.ToFloat64(metrics.EmployeesByStatus.WithLabelValues("vacation")); got != 2 {
+		t.Errorf("employees on vacation = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(metrics.EmployeesByStatus.WithLabelValues("active")); got != 3 {
+		t.Errorf("active employees = %v, want 3", got)
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"employee-management/internal/models"
+	"employee-management/internal/repository"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+
+	// sortRelevance keeps the order of a ranked name search
+	sortRelevance = "relevance"
+)
+
+// pageCursor is the position after the last item of a page. It holds that
+// item's sort key and ID, the relevance score and name for a ranked
+// search, so inserts and deletes between requests neither repeat nor skip
+// items.
+type pageCursor struct {
+	Sort     string     `json:"s"`
+	Order    string     `json:"o"`
+	ID       string     `json:"id,omitempty"`
+	Name     string     `json:"n,omitempty"`
+	Position string     `json:"p,omitempty"`
+	Age      int        `json:"a,omitempty"`
+	Created  *time.Time `json:"c,omitempty"`
+	Updated  *time.Time `json:"u,omitempty"`
+	Score    float64    `json:"sc,omitempty"`
+}
+
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return c, fmt.Errorf("неверный курсор")
+	}
+	return c, nil
+}
+
+// employee returns a stand-in for the item the cursor points after
+func (c pageCursor) employee() *models.Employee {
+	emp := &models.Employee{ID: c.ID, FullName: c.Name, Position: c.Position, Age: c.Age}
+	if c.Created != nil {
+		emp.CreatedAt = *c.Created
+	}
+	if c.Updated != nil {
+		emp.UpdatedAt = *c.Updated
+	}
+	return emp
+}
+
+// cursorAfter holds the sort key and the ID of emp
+func cursorAfter(sortBy, order string, emp *models.Employee) pageCursor {
+	c := pageCursor{Sort: sortBy, Order: order, ID: emp.ID}
+	switch sortBy {
+	case "name":
+		c.Name = emp.FullName
+	case "position":
+		c.Position = emp.Position
+	case "age":
+		c.Age = emp.Age
+	case "created_at":
+		c.Created = &emp.CreatedAt
+	case "updated_at":
+		c.Updated = &emp.UpdatedAt
+	}
+	return c
+}
+
+// pageSpec is a validated PageRequest
+type pageSpec struct {
+	sort   string
+	order  string
+	limit  int
+	cursor *pageCursor
+}
+
+// parsePage validates req. ranked tells that the search is ordered by
+// name relevance, which is then the default order.
+func parsePage(req models.PageRequest, ranked bool) (pageSpec, error) {
+	sortBy := req.Sort
+	if sortBy == "" {
+		sortBy = "name"
+		if ranked {
+			sortBy = sortRelevance
+		}
+	}
+	if !repository.IsSortField(sortBy) && (sortBy != sortRelevance || !ranked) {
+		return pageSpec{}, fmt.Errorf("неверное поле сортировки: %s", req.Sort)
+	}
+	order := req.Order
+	if order == "" {
+		order = "asc"
+	}
+	if order != "asc" && order != "desc" {
+		return pageSpec{}, fmt.Errorf("неверное направление сортировки: %s", req.Order)
+	}
+	limit := req.Limit
+	if limit < 0 {
+		return pageSpec{}, fmt.Errorf("limit не может быть отрицательным")
+	}
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+	spec := pageSpec{sort: sortBy, order: order, limit: min(limit, maxPageLimit)}
+
+	if req.Cursor != "" {
+		c, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return pageSpec{}, err
+		}
+		if c.Sort != sortBy || c.Order != order {
+			return pageSpec{}, fmt.Errorf("курсор получен для другой сортировки")
+		}
+		spec.cursor = &c
+	}
+	return spec, nil
+}
+
+// query selects the page in the repository. It asks for one item more
+// than the page holds to learn whether another page follows; a limit of
+// 0 selects everything.
+func (p pageSpec) query() repository.PageQuery {
+	q := repository.PageQuery{Sort: p.sort, Desc: p.order == "desc"}
+	if p.limit > 0 {
+		q.Limit = p.limit + 1
+	}
+	if p.cursor != nil {
+		q.After = p.cursor.employee()
+	}
+	return q
+}
+
+// page makes the page of the employees the query returned
+func (p pageSpec) page(employees []models.Employee, total int) *models.EmployeePage {
+	page := &models.EmployeePage{Items: employees, Total: total}
+	if p.limit > 0 && len(employees) > p.limit {
+		page.Items = employees[:p.limit]
+		page.NextCursor = cursorAfter(p.sort, p.order, &page.Items[p.limit-1]).encode()
+	}
+	if page.Items == nil {
+		page.Items = []models.Employee{}
+	}
+	return page
+}
+
+// rankedPage cuts the page out of employees ranked by their relevance to
+// query, seeking past the score, name and ID of the cursor
+func (p pageSpec) rankedPage(employees []models.Employee, query string) *models.EmployeePage {
+	scores := make([]float64, len(employees))
+	for i := range employees {
+		scores[i] = repository.NameScore(query, employees[i].FullName)
+	}
+	desc := p.order == "desc"
+	if desc {
+		slices.Reverse(employees)
+		slices.Reverse(scores)
+	}
+	start := 0
+	if c := p.cursor; c != nil {
+		start = sort.Search(len(employees), func(i int) bool {
+			cmp := compareRanked(scores[i], &employees[i], c.Score, c.Name, c.ID)
+			if desc {
+				cmp = -cmp
+			}
+			return cmp > 0
+		})
+	}
+	end := min(start+p.limit, len(employees))
+	page := &models.EmployeePage{
+		Items: employees[start:end],
+		Total: len(employees),
+	}
+	if page.Items == nil {
+		page.Items = []models.Employee{}
+	}
+	if end < len(employees) {
+		last := &employees[end-1]
+		page.NextCursor = pageCursor{
+			Sort: p.sort, Order: p.order, Score: scores[end-1], Name: last.FullName, ID: last.ID,
+		}.encode()
+	}
+	return page
+}
+
+// compareRanked compares emp, scoring score, with the item a ranked
+// cursor holds, in the order of ranked searches: higher scores first,
+// then by name and ID
+func compareRanked(score float64, emp *models.Employee, afterScore float64, afterName, afterID string) int {
+	switch {
+	case score > afterScore:
+		return -1
+	case score < afterScore:
+		return 1
+	}
+	if c := strings.Compare(emp.FullName, afterName); c != 0 {
+		return c
+	}
+	return strings.Compare(emp.ID, afterID)
+}
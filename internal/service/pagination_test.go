@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"employee-management/internal/models"
+	"employee-management/internal/repository"
+	"employee-management/internal/telemetry"
+)
+
+func TestCursorHoldsOnlyTheSortKey(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 123, time.UTC)
+	emp := &models.Employee{
+		ID: "emp7", FullName: "Иванов Иван", Position: "Аналитик", Age: 40,
+		CreatedAt: created, UpdatedAt: created.Add(time.Hour),
+	}
+
+	tests := []struct {
+		sort string
+		keys []string
+	}{
+		{"name", []string{"id", "n", "o", "s"}},
+		{"age", []string{"a", "id", "o", "s"}},
+		{"created_at", []string{"c", "id", "o", "s"}},
+		{"updated_at", []string{"id", "o", "s", "u"}},
+	}
+	for _, tt := range tests {
+		encoded := cursorAfter(tt.sort, "asc", emp).encode()
+		data, _ := base64.RawURLEncoding.DecodeString(encoded)
+		var fields map[string]any
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatalf("cursor %s: %v", data, err)
+		}
+		var keys []string
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		if !slices.Equal(keys, tt.keys) {
+			t.Errorf("cursor of sort %s has %v, want %v", tt.sort, keys, tt.keys)
+		}
+
+		c, err := decodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("decodeCursor: %v", err)
+		}
+		after := c.employee()
+		if tt.sort == "created_at" && !after.CreatedAt.Equal(created) {
+			t.Errorf("cursor time = %v, want %v", after.CreatedAt, created)
+		}
+	}
+}
+
+func TestSearchEmployeesPages(t *testing.T) {
+	ctx := context.Background()
+	svc := NewEmployeeService(repository.NewMemoryRepository(), telemetry.NewMetrics(telemetry.BuildInfo{}))
+
+	var got []string
+	req := models.EmployeeSearchRequest{PageRequest: models.PageRequest{Sort: "age", Order: "desc", Limit: 3}}
+	for pages := 0; ; pages++ {
+		page, err := svc.SearchEmployees(ctx, req)
+		if err != nil {
+			t.Fatalf("SearchEmployees: %v", err)
+		}
+		if page.Total != 4 {
+			t.Errorf("total = %d, want 4", page.Total)
+		}
+		for _, emp := range page.Items {
+			got = append(got, emp.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		if pages > 4 {
+			t.Fatal("pages do not end")
+		}
+		req.Cursor = page.NextCursor
+	}
+	if want := []string{"emp3", "emp1", "emp4", "emp2"}; !slices.Equal(got, want) {
+		t.Errorf("employees by age desc = %v, want %v", got, want)
+	}
+
+	req = models.EmployeeSearchRequest{PageRequest: models.PageRequest{Sort: "name", Cursor: req.Cursor}}
+	if _, err := svc.SearchEmployees(ctx, req); err == nil {
+		t.Error("a cursor of another sort was accepted")
+	}
+}
+
+func TestRankedSearchPagesByKey(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	svc := NewEmployeeService(repo, telemetry.NewMetrics(telemetry.BuildInfo{}))
+	hire := func(name, passport string) {
+		t.Helper()
+		_, err := repo.CreateEmployee(ctx, models.Employee{
+			FullName: name, Gender: "male", Age: 30, Education: "higher", Position: "Аналитик",
+			Passport: passport, DepartmentID: "dept1",
+		})
+		if err != nil {
+			t.Fatalf("CreateEmployee: %v", err)
+		}
+	}
+	for i, name := range []string{"Смирнов Олег", "Смирнова Ольга", "Смирнов Олег Петрович"} {
+		hire(name, fmt.Sprintf("9999 00000%d", i))
+	}
+
+	all, err := svc.SearchEmployees(ctx, models.EmployeeSearchRequest{FullName: "Смирнов Олег"})
+	if err != nil {
+		t.Fatalf("SearchEmployees: %v", err)
+	}
+	if len(all.Items) < 2 {
+		t.Fatalf("name search matched %d employees, want at least 2", len(all.Items))
+	}
+
+	first, err := svc.SearchEmployees(ctx, models.EmployeeSearchRequest{FullName: "Смирнов Олег", PageRequest: models.PageRequest{Limit: 1}})
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	// Ranks above the first page: an offset would repeat its item
+	hire("Олег Смирнов", "9999 000009")
+	second, err := svc.SearchEmployees(ctx, models.EmployeeSearchRequest{
+		FullName: "Смирнов Олег", PageRequest: models.PageRequest{Limit: 1, Cursor: first.NextCursor},
+	})
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	if first.Items[0].ID != all.Items[0].ID || second.Items[0].ID != all.Items[1].ID {
+		t.Errorf("pages = %s, %s, want %s, %s", first.Items[0].ID, second.Items[0].ID, all.Items[0].ID, all.Items[1].ID)
+	}
+}
+
+func TestDepartmentListingWithoutPagingReturnsEverything(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	svc := NewEmployeeService(repo, telemetry.NewMetrics(telemetry.BuildInfo{}))
+	for i := 0; i < defaultPageLimit+10; i++ {
+		_, err := repo.CreateEmployee(ctx, models.Employee{
+			FullName: fmt.Sprintf("Сотрудник %d", i), Gender: "female", Age: 30, Education: "higher",
+			Position: "Аналитик", Passport: fmt.Sprintf("9999 %06d", i), DepartmentID: "dept1",
+		})
+		if err != nil {
+			t.Fatalf("CreateEmployee: %v", err)
+		}
+	}
+	if _, _, err := repo.UpdateEmployeeStatus(ctx, "emp1", "fired"); err != nil {
+		t.Fatalf("UpdateEmployeeStatus: %v", err)
+	}
+	// dept1 starts with emp1 and emp2
+	want := defaultPageLimit + 12
+
+	page, err := svc.GetEmployeesByDepartment(ctx, "dept1", models.PageRequest{})
+	if err != nil {
+		t.Fatalf("GetEmployeesByDepartment: %v", err)
+	}
+	if len(page.Items) != want || page.Total != want || page.NextCursor != "" {
+		t.Errorf("unpaged listing has %d of %d items, next cursor %q; want all %d",
+			len(page.Items), page.Total, page.NextCursor, want)
+	}
+	if page.Headcount != want-1 {
+		t.Errorf("headcount = %d, want %d without the fired employee", page.Headcount, want-1)
+	}
+
+	page, err = svc.GetEmployeesByDepartment(ctx, "dept1", models.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetEmployeesByDepartment: %v", err)
+	}
+	if len(page.Items) != 10 || page.NextCursor == "" || page.Headcount != want-1 {
+		t.Errorf("paged listing has %d items, next cursor %q, headcount %d", len(page.Items), page.NextCursor, page.Headcount)
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"employee-management/internal/models"
+	"employee-management/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GetPositions returns positions ordered by title, inactive ones only with
+// includeInactive
+func (s *EmployeeService) GetPositions(ctx context.Context, includeInactive bool) ([]models.Position, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.GetPositions",
+		trace.WithAttributes(attribute.Bool("include_inactive", includeInactive)))
+	defer span.End()
+
+	slog.DebugContext(ctx, "getting positions")
+	positions, err := s.repo.GetPositions(ctx)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	if !includeInactive {
+		active := positions[:0:0]
+		for _, pos := range positions {
+			if pos.Active {
+				active = append(active, pos)
+			}
+		}
+		positions = active
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Title != positions[j].Title {
+			return positions[i].Title < positions[j].Title
+		}
+		return positions[i].DepartmentID < positions[j].DepartmentID
+	})
+	span.SetAttributes(attribute.Int("result.count", len(positions)))
+	return positions, nil
+}
+
+func (s *EmployeeService) GetPosition(ctx context.Context, id string) (*models.Position, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.GetPosition",
+		trace.WithAttributes(attribute.String("position_id", id)))
+	defer span.End()
+
+	pos, err := s.repo.GetPosition(ctx, id)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	return pos, nil
+}
+
+func (s *EmployeeService) CreatePosition(ctx context.Context, pos models.Position) (*models.Position, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.CreatePosition",
+		trace.WithAttributes(attribute.String("position.department_id", pos.DepartmentID)))
+	defer span.End()
+
+	slog.DebugContext(ctx, "creating position", "title", pos.Title)
+	pos.Title = strings.TrimSpace(pos.Title)
+	if err := s.validatePosition(ctx, pos); err != nil {
+		return nil, checkFailed(span, err)
+	}
+	created, err := s.repo.CreatePosition(ctx, pos)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	span.SetAttributes(attribute.String("position_id", created.ID))
+	return created, nil
+}
+
+// UpdatePosition updates a position. A new title is carried over to the
+// employees holding the position.
+func (s *EmployeeService) UpdatePosition(ctx context.Context, pos models.Position) (*models.Position, error) {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.UpdatePosition",
+		trace.WithAttributes(attribute.String("position_id", pos.ID)))
+	defer span.End()
+
+	slog.DebugContext(ctx, "updating position", "position_id", pos.ID)
+	previous, err := s.repo.GetPosition(ctx, pos.ID)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	pos.Title = strings.TrimSpace(pos.Title)
+	if err := s.validatePosition(ctx, pos); err != nil {
+		return nil, checkFailed(span, err)
+	}
+	updated, err := s.repo.UpdatePosition(ctx, pos)
+	if err != nil {
+		return nil, recordError(span, err)
+	}
+	if previous.Title != updated.Title {
+		span.AddEvent("position_renamed", trace.WithAttributes(
+			attribute.String("position.previous_title", previous.Title),
+			attribute.String("position.title", updated.Title),
+		))
+	}
+	return updated, nil
+}
+
+// DeletePosition deletes a position no employee holds, fired ones included;
+// positions still referenced can be deactivated instead
+func (s *EmployeeService) DeletePosition(ctx context.Context, id string) error {
+	ctx, span := s.tracer.Start(ctx, "EmployeeService.DeletePosition",
+		trace.WithAttributes(attribute.String("position_id", id)))
+	defer span.End()
+
+	slog.DebugContext(ctx, "deleting position", "position_id", id)
+	if err := s.repo.DeletePosition(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrPositionInUse) {
+			span.AddEvent("position_in_use")
+		}
+		return recordError(span, err)
+	}
+	return nil
+}
+
+// validatePosition checks the title, the grade and that the department the
+// position is limited to exists and is not archived
+func (s *EmployeeService) validatePosition(ctx context.Context, pos models.Position) error {
+	if pos.Title == "" {
+		return fmt.Errorf("название должности обязательно")
+	}
+	if pos.Grade < 1 {
+		return fmt.Errorf("грейд должен быть не меньше 1")
+	}
+	if pos.DepartmentID == "" {
+		return nil
+	}
+	return s.checkDepartmentOpen(ctx, pos.DepartmentID)
+}
+
+// resolvePosition finds the position of emp by PositionID or, for clients
+// that only send a title, by Position, and sets both fields. PositionID
+// wins when both are set. The position must be active unless the employee
+// already holds it, and must be available in the employee's department.
+func (s *EmployeeService) resolvePosition(ctx context.Context, emp *models.Employee, previous *models.Employee) error {
+	var pos *models.Position
+	if emp.PositionID != "" {
+		found, err := s.repo.GetPosition(ctx, emp.PositionID)
+		if errors.Is(err, repository.ErrPositionNotFound) {
+			return fmt.Errorf("должность не найдена: %s", emp.PositionID)
+		}
+		if err != nil {
+			return &storageError{err}
+		}
+		pos = found
+	} else {
+		positions, err := s.repo.GetPositions(ctx)
+		if err != nil {
+			return &storageError{err}
+		}
+		// A position of the employee's department shadows a company-wide
+		// one with the same title
+		for i := range positions {
+			candidate := &positions[i]
+			if candidate.Title != emp.Position {
+				continue
+			}
+			if candidate.DepartmentID == emp.DepartmentID {
+				pos = candidate
+				break
+			}
+			if candidate.DepartmentID == "" {
+				pos = candidate
+			}
+		}
+		if pos == nil {
+			return fmt.Errorf("неизвестная должность: %s", emp.Position)
+		}
+	}
+
+	held := previous != nil && previous.PositionID == pos.ID
+	if !pos.Active && !held {
+		return fmt.Errorf("должность неактивна: %s", pos.Title)
+	}
+	if pos.DepartmentID != "" && pos.DepartmentID != emp.DepartmentID {
+		return fmt.Errorf("должность %s недоступна в департаменте %s", pos.Title, emp.DepartmentID)
+	}
+	emp.PositionID = pos.ID
+	emp.Position = pos.Title
+	return nil
+}
@@ -0,0 +1,72 @@
+package telemetry
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Employee statuses tracked by EmployeesByStatus. They are always exported,
+// so a status that drops to zero reports 0 instead of its last value.
+var knownStatuses = []string{"active", "vacation", "fired"}
+
+// initBusinessMetrics creates HR business metrics
+func (m *Metrics) initBusinessMetrics() {
+	m.EmployeesHiredTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "employees_hired_total",
+		Help: "Total number of hired employees",
+	}, businessLabels)
+
+	m.EmployeesTerminatedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "employees_terminated_total",
+		Help: "Total number of terminated employees",
+	}, businessLabels)
+
+	m.VacationsStartedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "employee_vacations_started_total",
+		Help: "Total number of started vacations",
+	}, businessLabels)
+
+	m.VacationsEndedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "employee_vacations_ended_total",
+		Help: "Total number of ended vacations",
+	}, businessLabels)
+
+	m.TenureAtTermination = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "employee_tenure_at_termination_days",
+		Help:    "Employee tenure at termination in days",
+		Buckets: []float64{30, 90, 180, 365, 730, 1095, 1825, 3650},
+	}, departmentLabels)
+}
+
+// RecordHire counts a hired employee
+func (m *Metrics) RecordHire(department, position string) {
+	labels := m.guard.apply("employees_hired_total", businessLabels, department, position)
+	m.EmployeesHiredTotal.WithLabelValues(labels...).Inc()
+	m.statsd.Count("employees.hired", 1, "department", labels[0], "position", labels[1])
+}
+
+// RecordTermination counts a terminated employee and observes their tenure
+func (m *Metrics) RecordTermination(department, position string, tenure time.Duration) {
+	labels := m.guard.apply("employees_terminated_total", businessLabels, department, position)
+	m.EmployeesTerminatedTotal.WithLabelValues(labels...).Inc()
+	m.statsd.Count("employees.terminated", 1, "department", labels[0], "position", labels[1])
+
+	department = m.guard.apply("employee_tenure_at_termination_days", departmentLabels, department)[0]
+	m.TenureAtTermination.WithLabelValues(department).Observe(tenure.Hours() / 24)
+	m.statsd.Histogram("employees.tenure_at_termination_days", tenure.Hours()/24, "department", department)
+}
+
+// RecordVacationStart counts an employee leaving for vacation
+func (m *Metrics) RecordVacationStart(department, position string) {
+	labels := m.guard.apply("employee_vacations_started_total", businessLabels, department, position)
+	m.VacationsStartedTotal.WithLabelValues(labels...).Inc()
+	m.statsd.Count("employees.vacations_started", 1, "department", labels[0], "position", labels[1])
+}
+
+// RecordVacationEnd counts an employee returning from vacation
+func (m *Metrics) RecordVacationEnd(department, position string) {
+	labels := m.guard.apply("employee_vacations_ended_total", businessLabels, department, position)
+	m.VacationsEndedTotal.WithLabelValues(labels...).Inc()
+	m.statsd.Count("employees.vacations_ended", 1, "department", labels[0], "position", labels[1])
+}
@@ -0,0 +1,120 @@
+package telemetry_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"employee-management/internal/models"
+	"employee-management/internal/repository"
+	"employee-management/internal/service"
+	"employee-management/internal/telemetry"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newBusinessService returns a service over the demo data of
+// MemoryRepository and metrics on a fresh registry. In the demo data emp1
+// is an active Программист and emp2 an Аналитик on vacation, both in dept1.
+func newBusinessService() (*service.EmployeeService, *telemetry.Metrics) {
+	metrics := telemetry.NewMetrics(telemetry.BuildInfo{})
+	return service.NewEmployeeService(repository.NewMemoryRepository(), metrics), metrics
+}
+
+func TestCreateEmployeeCountsHire(t *testing.T) {
+	svc, metrics := newBusinessService()
+
+	_, err := svc.CreateEmployee(context.Background(), models.Employee{
+		FullName: "Смирнов Олег Петрович", Gender: "male", Age: 30, Education: "higher",
+		PositionID: "pos3", Passport: "9999 000001", DepartmentID: "dept1",
+	})
+	if err != nil {
+		t.Fatalf("CreateEmployee: %v", err)
+	}
+
+	if got := testutil.ToFloat64(metrics.EmployeesHiredTotal.WithLabelValues("dept1", "Тестировщик")); got != 1 {
+		t.Errorf("employees_hired_total = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.EmployeesTotal); got != 5 {
+		t.Errorf("employees_total = %v, want 5", got)
+	}
+}
+
+func TestStatusTransitionsCountEvents(t *testing.T) {
+	svc, metrics := newBusinessService()
+	ctx := context.Background()
+
+	steps := []struct {
+		id, status string
+	}{
+		{"emp1", "vacation"}, // vacation started
+		{"emp1", "vacation"}, // no change, nothing counted
+		{"emp1", "active"},   // vacation ended
+		{"emp2", "fired"},    // vacation ended, terminated
+		{"emp2", "active"},   // rehired
+		{"emp1", "fired"},    // terminated
+	}
+	for _, step := range steps {
+		if _, err := svc.UpdateEmployeeStatus(ctx, step.id, step.status); err != nil {
+			t.Fatalf("UpdateEmployeeStatus(%s, %s): %v", step.id, step.status, err)
+		}
+	}
+
+	counters := []struct {
+		name     string
+		value    float64
+		expected float64
+	}{
+		{"vacations started", testutil.ToFloat64(metrics.VacationsStartedTotal.WithLabelValues("dept1", "Программист")), 1},
+		{"vacations ended (emp1)", testutil.ToFloat64(metrics.VacationsEndedTotal.WithLabelValues("dept1", "Программист")), 1},
+		{"vacations ended (emp2)", testutil.ToFloat64(metrics.VacationsEndedTotal.WithLabelValues("dept1", "Аналитик")), 1},
+		{"terminated (emp1)", testutil.ToFloat64(metrics.EmployeesTerminatedTotal.WithLabelValues("dept1", "Программист")), 1},
+		{"terminated (emp2)", testutil.ToFloat64(metrics.EmployeesTerminatedTotal.WithLabelValues("dept1", "Аналитик")), 1},
+		{"rehired (emp2)", testutil.ToFloat64(metrics.EmployeesHiredTotal.WithLabelValues("dept1", "Аналитик")), 1},
+		{"hired (emp1)", testutil.ToFloat64(metrics.EmployeesHiredTotal.WithLabelValues("dept1", "Программист")), 0},
+		{"active", testutil.ToFloat64(metrics.EmployeesByStatus.WithLabelValues("active")), 3},
+		{"vacation", testutil.ToFloat64(metrics.EmployeesByStatus.WithLabelValues("vacation")), 0},
+		{"fired", testutil.ToFloat64(metrics.EmployeesByStatus.WithLabelValues("fired")), 1},
+	}
+	for _, c := range counters {
+		if c.value != c.expected {
+			t.Errorf("%s = %v, want %v", c.name, c.value, c.expected)
+		}
+	}
+
+	if n := testutil.CollectAndCount(metrics.TenureAtTermination); n != 1 {
+		t.Errorf("tenure series = %d, want 1", n)
+	}
+}
+
+func TestConcurrentTransitionsCountOnce(t *testing.T) {
+	svc, metrics := newBusinessService()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.UpdateEmployeeStatus(ctx, "emp1", "fired"); err != nil {
+				t.Errorf("UpdateEmployeeStatus: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := testutil.ToFloat64(metrics.EmployeesTerminatedTotal.WithLabelValues("dept1", "Программист")); got != 1 {
+		t.Errorf("employees_terminated_total = %v, want 1", got)
+	}
+}
+
+func TestInvalidStatusCountsNothing(t *testing.T) {
+	svc, metrics := newBusinessService()
+
+	if _, err := svc.UpdateEmployeeStatus(context.Background(), "emp1", "retired"); err == nil {
+		t.Fatal("UpdateEmployeeStatus accepted an unknown status")
+	}
+	if n := testutil.CollectAndCount(metrics.EmployeesTerminatedTotal); n != 0 {
+		t.Errorf("employees_terminated_total series = %d, want 0", n)
+	}
+}
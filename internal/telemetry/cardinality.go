@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// OtherLabelValue replaces label values beyond the limit of a metric
+const OtherLabelValue = "other"
+
+// maxLoggedOverflows caps the overflowing values remembered per label, so
+// a flood of distinct values cannot grow the guard without bound
+const maxLoggedOverflows = 1000
+
+// Label names of the guarded metrics
+var (
+	httpRequestLabels  = []string{"method", "path", "status"}
+	httpDurationLabels = []string{"method", "path"}
+	businessLabels     = []string{"department", "position"}
+	departmentLabels   = []string{"department"}
+	statusLabels       = []string{"status"}
+)
+
+// CardinalityLimits caps distinct values of each label of a metric.
+// PerMetric overrides Default by metric name; 0 means no limit.
+type CardinalityLimits struct {
+	Default   int
+	PerMetric map[string]int
+}
+
+// cardinalityGuard remembers the label values seen per metric label and
+// collapses new values into OtherLabelValue once the limit is reached
+type cardinalityGuard struct {
+	mu       sync.Mutex
+	limits   CardinalityLimits
+	seen     map[string]map[string]struct{} // metric/label -> values
+	logged   map[string]map[string]struct{} // metric/label -> overflowing values already logged
+	overflow *prometheus.CounterVec
+}
+
+func newCardinalityGuard() *cardinalityGuard {
+	return &cardinalityGuard{
+		seen:   make(map[string]map[string]struct{}),
+		logged: make(map[string]map[string]struct{}),
+		overflow: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "telemetry_label_overflow_total",
+			Help: "Label values replaced with \"other\" because of the cardinality limit",
+		}, []string{"metric", "label"}),
+	}
+}
+
+// LimitCardinality sets label value limits. It must be called before
+// metrics are recorded.
+func (m *Metrics) LimitCardinality(limits CardinalityLimits) {
+	m.guard.mu.Lock()
+	defer m.guard.mu.Unlock()
+	m.guard.limits = limits
+}
+
+// apply returns values with those over the limit of metric replaced
+func (g *cardinalityGuard) apply(metric string, names []string, values ...string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	limit, ok := g.limits.PerMetric[metric]
+	if !ok {
+		limit = g.limits.Default
+	}
+	if limit <= 0 {
+		return values
+	}
+
+	for i, value := range values {
+		key := metric + "/" + names[i]
+		seen, ok := g.seen[key]
+		if !ok {
+			seen = make(map[string]struct{})
+			g.seen[key] = seen
+		}
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		if len(seen) < limit {
+			seen[value] = struct{}{}
+			continue
+		}
+
+		g.logOverflow(key, metric, names[i], value, limit)
+		g.overflow.WithLabelValues(metric, names[i]).Inc()
+		values[i] = OtherLabelValue
+	}
+	return values
+}
+
+// logOverflow logs each value collapsed into OtherLabelValue once, up to
+// maxLoggedOverflows values per label
+func (g *cardinalityGuard) logOverflow(key, metric, label, value string, limit int) {
+	logged, ok := g.logged[key]
+	if !ok {
+		logged = make(map[string]struct{})
+		g.logged[key] = logged
+	}
+	if _, ok := logged[value]; ok || len(logged) > maxLoggedOverflows {
+		return
+	}
+	logged[value] = struct{}{}
+	if len(logged) > maxLoggedOverflows {
+		slog.Warn("Слишком много значений метки сверх лимита, дальнейшие не записываются в лог",
+			"metric", metric, "label", label, "logged", maxLoggedOverflows)
+		return
+	}
+	slog.Warn("Превышен лимит значений метки, новое значение заменяется на \"other\"",
+		"metric", metric, "label", label, "limit", limit, "value", value)
+}
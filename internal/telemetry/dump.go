@@ -0,0 +1,342 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// MetricsFormat selects how metrics dumps are written to the metrics file
+type MetricsFormat string
+
+const (
+	FormatPrometheus  MetricsFormat = "prometheus"
+	FormatOpenMetrics MetricsFormat = "openmetrics"
+	FormatJSONLines   MetricsFormat = "jsonl"
+	FormatCSV         MetricsFormat = "csv"
+)
+
+// DumpHeaderPrefix starts every dump in the Prometheus text format
+const DumpHeaderPrefix = "# DUMP "
+
+// CSVHeader is the first row of a CSV metrics file
+var CSVHeader = []string{"timestamp", "name", "type", "labels", "value"}
+
+// ParseMetricsFormat validates a format name from configuration
+func ParseMetricsFormat(s string) (MetricsFormat, error) {
+	switch f := MetricsFormat(strings.ToLower(s)); f {
+	case FormatPrometheus, FormatOpenMetrics, FormatJSONLines, FormatCSV:
+		return f, nil
+	case "":
+		return FormatPrometheus, nil
+	default:
+		return "", fmt.Errorf("неизвестный формат метрик: %s", s)
+	}
+}
+
+// DumpFraming returns what a file of dumps in format starts and ends with:
+// CSV files start with the header row, OpenMetrics files end with "# EOF".
+// Dumps written by EncodeDump go in between.
+func DumpFraming(format MetricsFormat) (header, trailer []byte) {
+	switch format {
+	case FormatCSV:
+		return []byte(strings.Join(CSVHeader, ",") + "\n"), nil
+	case FormatOpenMetrics:
+		return nil, []byte("# EOF\n")
+	default:
+		return nil, nil
+	}
+}
+
+// EncodeDump writes one dump of metric families taken at ts in the given
+// format, without the file framing of DumpFraming
+func EncodeDump(w io.Writer, format MetricsFormat, families []*dto.MetricFamily, ts time.Time) error {
+	switch format {
+	case FormatPrometheus:
+		return encodePrometheus(w, families, ts)
+	case FormatOpenMetrics:
+		return encodeOpenMetrics(w, families, ts)
+	case FormatJSONLines:
+		return encodeJSONLines(w, families, ts)
+	case FormatCSV:
+		return encodeCSV(w, families, ts)
+	default:
+		return fmt.Errorf("неизвестный формат метрик: %s", format)
+	}
+}
+
+// withTimestamp returns copies of families with every sample stamped with ts
+func withTimestamp(families []*dto.MetricFamily, ts time.Time) []*dto.MetricFamily {
+	ms := ts.UnixMilli()
+	stamped := make([]*dto.MetricFamily, 0, len(families))
+	for _, mf := range families {
+		c := proto.Clone(mf).(*dto.MetricFamily)
+		for _, m := range c.Metric {
+			m.TimestampMs = proto.Int64(ms)
+		}
+		stamped = append(stamped, c)
+	}
+	return stamped
+}
+
+func encodePrometheus(w io.Writer, families []*dto.MetricFamily, ts time.Time) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s%s\n", DumpHeaderPrefix, ts.Format(time.RFC3339Nano))
+	for _, mf := range withTimestamp(families, ts) {
+		if _, err := expfmt.MetricFamilyToText(&buf, mf); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func encodeOpenMetrics(w io.Writer, families []*dto.MetricFamily, ts time.Time) error {
+	var buf bytes.Buffer
+	for _, mf := range withTimestamp(families, ts) {
+		if _, err := expfmt.MetricFamilyToOpenMetrics(&buf, mf); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// JSONFloat is a float64 that encodes NaN and infinities as strings
+type JSONFloat float64
+
+// MarshalJSON implements json.Marshaler
+func (f JSONFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return json.Marshal(formatFloat(v))
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (f *JSONFloat) UnmarshalJSON(data []byte) error {
+	var v float64
+	if err := json.Unmarshal(data, &v); err == nil {
+		*f = JSONFloat(v)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*f = JSONFloat(v)
+	return nil
+}
+
+// JSONBucket is a cumulative histogram bucket in a JSON lines dump
+type JSONBucket struct {
+	UpperBound JSONFloat `json:"le"`
+	Count      uint64    `json:"count"`
+}
+
+// JSONQuantile is a summary quantile in a JSON lines dump
+type JSONQuantile struct {
+	Quantile JSONFloat `json:"quantile"`
+	Value    JSONFloat `json:"value"`
+}
+
+// JSONSeries is one line of a JSON lines dump
+type JSONSeries struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Help      string            `json:"help,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Value     *JSONFloat        `json:"value,omitempty"`
+	Count     *uint64           `json:"count,omitempty"`
+	Sum       *JSONFloat        `json:"sum,omitempty"`
+	Buckets   []JSONBucket      `json:"buckets,omitempty"`
+	Quantiles []JSONQuantile    `json:"quantiles,omitempty"`
+}
+
+func encodeJSONLines(w io.Writer, families []*dto.MetricFamily, ts time.Time) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			series := JSONSeries{
+				Timestamp: ts,
+				Name:      mf.GetName(),
+				Type:      strings.ToLower(mf.GetType().String()),
+				Help:      mf.GetHelp(),
+				Labels:    labelMap(m),
+			}
+			switch {
+			case m.Counter != nil:
+				v := JSONFloat(m.Counter.GetValue())
+				series.Value = &v
+			case m.Gauge != nil:
+				v := JSONFloat(m.Gauge.GetValue())
+				series.Value = &v
+			case m.Untyped != nil:
+				v := JSONFloat(m.Untyped.GetValue())
+				series.Value = &v
+			case m.Histogram != nil:
+				h := m.Histogram
+				count, sum := h.GetSampleCount(), JSONFloat(h.GetSampleSum())
+				series.Count, series.Sum = &count, &sum
+				for _, b := range h.GetBucket() {
+					series.Buckets = append(series.Buckets, JSONBucket{
+						UpperBound: JSONFloat(b.GetUpperBound()),
+						Count:      b.GetCumulativeCount(),
+					})
+				}
+				series.Buckets = append(series.Buckets, JSONBucket{UpperBound: JSONFloat(math.Inf(1)), Count: count})
+			case m.Summary != nil:
+				s := m.Summary
+				count, sum := s.GetSampleCount(), JSONFloat(s.GetSampleSum())
+				series.Count, series.Sum = &count, &sum
+				for _, q := range s.GetQuantile() {
+					series.Quantiles = append(series.Quantiles, JSONQuantile{
+						Quantile: JSONFloat(q.GetQuantile()),
+						Value:    JSONFloat(q.GetValue()),
+					})
+				}
+			}
+			if err := enc.Encode(series); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// encodeCSV writes flattened samples, with histogram and summary series
+// expanded into _bucket, _sum, _count and quantile rows as in the text format
+func encodeCSV(w io.Writer, families []*dto.MetricFamily, ts time.Time) error {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+
+	stamp := ts.Format(time.RFC3339Nano)
+	for _, mf := range families {
+		typ := strings.ToLower(mf.GetType().String())
+		for _, s := range FlattenFamily(mf) {
+			row := []string{stamp, s.Name, typ, FormatLabels(s.Labels), formatFloat(s.Value)}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Sample is a single flattened time series value
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// FlattenFamily expands a metric family into samples the way the Prometheus
+// text format does
+func FlattenFamily(mf *dto.MetricFamily) []Sample {
+	var samples []Sample
+	name := mf.GetName()
+	for _, m := range mf.GetMetric() {
+		labels := labelMap(m)
+		switch {
+		case m.Counter != nil:
+			samples = append(samples, Sample{name, labels, m.Counter.GetValue()})
+		case m.Gauge != nil:
+			samples = append(samples, Sample{name, labels, m.Gauge.GetValue()})
+		case m.Untyped != nil:
+			samples = append(samples, Sample{name, labels, m.Untyped.GetValue()})
+		case m.Histogram != nil:
+			h := m.Histogram
+			for _, b := range h.GetBucket() {
+				samples = append(samples, Sample{name + "_bucket", withLabel(labels, "le", formatFloat(b.GetUpperBound())), float64(b.GetCumulativeCount())})
+			}
+			samples = append(samples,
+				Sample{name + "_bucket", withLabel(labels, "le", "+Inf"), float64(h.GetSampleCount())},
+				Sample{name + "_sum", labels, h.GetSampleSum()},
+				Sample{name + "_count", labels, float64(h.GetSampleCount())},
+			)
+		case m.Summary != nil:
+			s := m.Summary
+			for _, q := range s.GetQuantile() {
+				samples = append(samples, Sample{name, withLabel(labels, "quantile", formatFloat(q.GetQuantile())), q.GetValue()})
+			}
+			samples = append(samples,
+				Sample{name + "_sum", labels, s.GetSampleSum()},
+				Sample{name + "_count", labels, float64(s.GetSampleCount())},
+			)
+		}
+	}
+	return samples
+}
+
+// FormatLabels renders labels as sorted k=v pairs separated by semicolons
+func FormatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+labels[k])
+	}
+	return strings.Join(pairs, ";")
+}
+
+func labelMap(m *dto.Metric) map[string]string {
+	if len(m.GetLabel()) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(m.GetLabel()))
+	for _, l := range m.GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+	return labels
+}
+
+func withLabel(labels map[string]string, name, value string) map[string]string {
+	out := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	out[name] = value
+	return out
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
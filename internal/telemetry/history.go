@@ -0,0 +1,488 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HistoryStore periodically snapshots a registry into an in-memory ring,
+// keeping Retention of history at Resolution. All series share one ring of
+// timestamps, and a missing value is stored as NaN.
+type HistoryStore struct {
+	mu         sync.RWMutex
+	gatherer   prometheus.Gatherer
+	resolution time.Duration
+	retention  time.Duration
+	times      []int64 // unix milliseconds, ring buffer
+	head       int     // next write position
+	count      int
+	series     map[string]*historySeries
+}
+
+type historySeries struct {
+	Name     string
+	Labels   map[string]string
+	Type     string
+	Values   []float64
+	LastSeen int64
+}
+
+// HistoryQuery selects and transforms history series
+type HistoryQuery struct {
+	Name   string            // sample name, or histogram name for FuncQuantile
+	Labels map[string]string // series must have all of these labels
+	From   time.Time
+	To     time.Time
+	Step   time.Duration // raised to the store resolution when smaller
+	Func   string        // "", FuncRate or FuncQuantile
+	Q      float64       // quantile for FuncQuantile
+	Sum    bool          // sum all matching series into one
+}
+
+const (
+	FuncRate     = "rate"
+	FuncQuantile = "quantile"
+)
+
+// HistoryPoint is a value at a unix millisecond timestamp, nil when missing
+type HistoryPoint struct {
+	T int64    `json:"t"`
+	V *float64 `json:"v"`
+}
+
+// HistorySeries is a query result
+type HistorySeries struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Type   string            `json:"type"`
+	Points []HistoryPoint    `json:"points"`
+}
+
+// NewHistoryStore creates a store that keeps retention of samples at resolution
+func NewHistoryStore(gatherer prometheus.Gatherer, resolution, retention time.Duration) *HistoryStore {
+	capacity := int(retention / resolution)
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &HistoryStore{
+		gatherer:   gatherer,
+		resolution: resolution,
+		retention:  retention,
+		times:      make([]int64, capacity),
+		series:     make(map[string]*historySeries),
+	}
+}
+
+// Resolution returns the interval between snapshots
+func (h *HistoryStore) Resolution() time.Duration {
+	return h.resolution
+}
+
+// Run snapshots the registry every resolution interval and saves the store
+// to snapshotFile every snapshotInterval until ctx is done
+func (h *HistoryStore) Run(ctx context.Context, snapshotFile string, snapshotInterval time.Duration) {
+	ticker := time.NewTicker(h.resolution)
+	defer ticker.Stop()
+
+	var saveC <-chan time.Time
+	if snapshotFile != "" && snapshotInterval > 0 {
+		saveTicker := time.NewTicker(snapshotInterval)
+		defer saveTicker.Stop()
+		saveC = saveTicker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := h.Collect(now); err != nil {
+				slog.Error("Ошибка сбора истории метрик", "error", err)
+			}
+		case <-saveC:
+			if err := h.Save(snapshotFile); err != nil {
+				slog.Error("Ошибка сохранения истории метрик", "error", err)
+			}
+		}
+	}
+}
+
+// Collect gathers the registry and appends one snapshot taken at now
+func (h *HistoryStore) Collect(now time.Time) error {
+	families, err := h.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]float64)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, mf := range families {
+		typ := strings.ToLower(mf.GetType().String())
+		for _, s := range FlattenFamily(mf) {
+			key := seriesKey(s.Name, s.Labels)
+			if _, ok := h.series[key]; !ok {
+				h.series[key] = h.newSeries(s.Name, s.Labels, typ)
+			}
+			values[key] = s.Value
+		}
+	}
+	h.append(now.UnixMilli(), values)
+	return nil
+}
+
+func (h *HistoryStore) newSeries(name string, labels map[string]string, typ string) *historySeries {
+	values := make([]float64, len(h.times))
+	for i := range values {
+		values[i] = math.NaN()
+	}
+	return &historySeries{Name: name, Labels: labels, Type: typ, Values: values}
+}
+
+// append writes one snapshot into the ring. The caller holds the lock.
+func (h *HistoryStore) append(ts int64, values map[string]float64) {
+	pos := h.head
+	h.times[pos] = ts
+	for key, s := range h.series {
+		if v, ok := values[key]; ok {
+			s.Values[pos] = v
+			s.LastSeen = ts
+			continue
+		}
+		s.Values[pos] = math.NaN()
+		// Forget series that have no value left in the ring
+		if ts-s.LastSeen > h.retention.Milliseconds() {
+			delete(h.series, key)
+		}
+	}
+
+	h.head = (h.head + 1) % len(h.times)
+	if h.count < len(h.times) {
+		h.count++
+	}
+}
+
+// index returns the ring position of the i-th oldest snapshot
+func (h *HistoryStore) index(i int) int {
+	return (h.head - h.count + i + len(h.times)) % len(h.times)
+}
+
+// Query returns the series selected by q, downsampled to q.Step
+func (h *HistoryStore) Query(q HistoryQuery) ([]HistorySeries, error) {
+	if q.Name == "" {
+		return nil, fmt.Errorf("не указано имя метрики")
+	}
+	if !q.To.After(q.From) {
+		return nil, fmt.Errorf("начало периода должно быть раньше конца")
+	}
+	step := q.Step
+	if step < h.resolution {
+		step = h.resolution
+	}
+	if q.To.Sub(q.From)/step > 10000 {
+		return nil, fmt.Errorf("слишком много точек, увеличьте step")
+	}
+
+	name := q.Name
+	if q.Func == FuncQuantile {
+		name = q.Name + "_bucket"
+	}
+
+	h.mu.RLock()
+	var selected []HistorySeries
+	for _, s := range h.series {
+		if s.Name != name || !matchLabels(s.Labels, q.Labels) {
+			continue
+		}
+		selected = append(selected, HistorySeries{
+			Name:   s.Name,
+			Labels: s.Labels,
+			Type:   s.Type,
+			Points: h.downsample(s, q.From, q.To, step),
+		})
+	}
+	h.mu.RUnlock()
+
+	var result []HistorySeries
+	switch q.Func {
+	case "":
+		result = selected
+	case FuncRate:
+		for _, s := range selected {
+			s.Points = ratePoints(s.Points)
+			result = append(result, s)
+		}
+	case FuncQuantile:
+		if q.Q < 0 || q.Q > 1 {
+			return nil, fmt.Errorf("квантиль должен быть от 0 до 1")
+		}
+		result = quantileSeries(q.Name, q.Q, selected, q.Sum)
+	default:
+		return nil, fmt.Errorf("неизвестная функция: %s", q.Func)
+	}
+
+	if q.Sum && q.Func != FuncQuantile && len(result) > 0 {
+		result = []HistorySeries{sumSeries(result)}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return seriesKey(result[i].Name, result[i].Labels) < seriesKey(result[j].Name, result[j].Labels)
+	})
+	return result, nil
+}
+
+// downsample returns one point per step between from and to. Counters and
+// histogram parts keep the last value in a step, gauges the average.
+func (h *HistoryStore) downsample(s *historySeries, from, to time.Time, step time.Duration) []HistoryPoint {
+	stepMs := step.Milliseconds()
+	fromMs, toMs := from.UnixMilli(), to.UnixMilli()
+	n := int((toMs-fromMs)/stepMs) + 1
+
+	sums := make([]float64, n)
+	counts := make([]int, n)
+	lasts := make([]float64, n)
+	for i := 0; i < h.count; i++ {
+		pos := h.index(i)
+		ts, v := h.times[pos], s.Values[pos]
+		if ts < fromMs || ts > toMs || math.IsNaN(v) {
+			continue
+		}
+		b := int((ts - fromMs) / stepMs)
+		sums[b] += v
+		counts[b]++
+		lasts[b] = v
+	}
+
+	points := make([]HistoryPoint, n)
+	for b := range points {
+		points[b].T = fromMs + int64(b)*stepMs
+		if counts[b] == 0 {
+			continue
+		}
+		v := lasts[b]
+		if s.Type == "gauge" {
+			v = sums[b] / float64(counts[b])
+		}
+		points[b].V = &v
+	}
+	return points
+}
+
+// ratePoints turns counter values into per-second rates, treating a decrease as a reset
+func ratePoints(points []HistoryPoint) []HistoryPoint {
+	rates := make([]HistoryPoint, len(points))
+	var prev *HistoryPoint
+	for i, p := range points {
+		rates[i].T = p.T
+		if p.V == nil {
+			continue
+		}
+		if prev != nil {
+			d := *p.V - *prev.V
+			if d < 0 {
+				d = *p.V
+			}
+			rate := d / (float64(p.T-prev.T) / 1000)
+			rates[i].V = &rate
+		}
+		prev = &points[i]
+	}
+	return rates
+}
+
+// quantileSeries estimates a quantile per step from the increase of histogram buckets
+func quantileSeries(name string, q float64, buckets []HistorySeries, sum bool) []HistorySeries {
+	groups := make(map[string][]HistorySeries)
+	groupLabels := make(map[string]map[string]string)
+	for _, b := range buckets {
+		labels := make(map[string]string)
+		if !sum {
+			for k, v := range b.Labels {
+				if k != "le" {
+					labels[k] = v
+				}
+			}
+		}
+		key := seriesKey(name, labels)
+		groups[key] = append(groups[key], b)
+		groupLabels[key] = labels
+	}
+
+	var result []HistorySeries
+	for key, group := range groups {
+		// Sum bucket increases with the same upper bound across series
+		increases := make(map[float64][]HistoryPoint)
+		for _, b := range group {
+			le, err := strconv.ParseFloat(b.Labels["le"], 64)
+			if err != nil {
+				continue
+			}
+			rates := ratePoints(b.Points)
+			if existing, ok := increases[le]; ok {
+				increases[le] = addPoints(existing, rates)
+			} else {
+				increases[le] = rates
+			}
+		}
+
+		series := HistorySeries{Name: name, Labels: groupLabels[key], Type: "quantile"}
+		if len(group) > 0 {
+			for i, p := range group[0].Points {
+				point := HistoryPoint{T: p.T}
+				var bs []Bucket
+				for le, points := range increases {
+					if points[i].V != nil {
+						bs = append(bs, Bucket{UpperBound: le, Count: *points[i].V})
+					}
+				}
+				if v := HistogramQuantile(q, bs); !math.IsNaN(v) {
+					point.V = &v
+				}
+				series.Points = append(series.Points, point)
+			}
+		}
+		result = append(result, series)
+	}
+	return result
+}
+
+func sumSeries(series []HistorySeries) HistorySeries {
+	sum := HistorySeries{Name: series[0].Name, Type: series[0].Type, Points: series[0].Points}
+	for _, s := range series[1:] {
+		sum.Points = addPoints(sum.Points, s.Points)
+	}
+	return sum
+}
+
+// addPoints adds two aligned point slices, a missing value counts as zero
+func addPoints(a, b []HistoryPoint) []HistoryPoint {
+	out := make([]HistoryPoint, len(a))
+	for i := range a {
+		out[i].T = a[i].T
+		if a[i].V == nil && b[i].V == nil {
+			continue
+		}
+		var v float64
+		if a[i].V != nil {
+			v += *a[i].V
+		}
+		if b[i].V != nil {
+			v += *b[i].V
+		}
+		out[i].V = &v
+	}
+	return out
+}
+
+func matchLabels(labels, want map[string]string) bool {
+	for k, v := range want {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func seriesKey(name string, labels map[string]string) string {
+	return name + "{" + FormatLabels(labels) + "}"
+}
+
+// historySnapshot is the on-disk form of the store, oldest point first
+type historySnapshot struct {
+	Times  []int64
+	Series []historySeries
+}
+
+// Save writes the store to path atomically
+func (h *HistoryStore) Save(path string) error {
+	h.mu.RLock()
+	snap := historySnapshot{Times: make([]int64, h.count)}
+	for i := 0; i < h.count; i++ {
+		snap.Times[i] = h.times[h.index(i)]
+	}
+	for _, s := range h.series {
+		values := make([]float64, h.count)
+		for i := 0; i < h.count; i++ {
+			values[i] = s.Values[h.index(i)]
+		}
+		snap.Series = append(snap.Series, historySeries{
+			Name: s.Name, Labels: s.Labels, Type: s.Type, Values: values, LastSeen: s.LastSeen,
+		})
+	}
+	h.mu.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("не удалось создать директорию истории: %w", err)
+	}
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("не удалось создать файл истории: %w", err)
+	}
+	if err := gob.NewEncoder(file).Encode(snap); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("не удалось записать историю: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load restores history saved by Save. Points older than the retention are
+// dropped, so the store may be loaded with a different resolution or retention.
+// A missing file is not an error.
+func (h *HistoryStore) Load(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось открыть файл истории: %w", err)
+	}
+	defer file.Close()
+
+	var snap historySnapshot
+	if err := gob.NewDecoder(file).Decode(&snap); err != nil {
+		return fmt.Errorf("не удалось прочитать историю: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cutoff := time.Now().Add(-h.retention).UnixMilli()
+	for i, ts := range snap.Times {
+		if ts < cutoff {
+			continue
+		}
+		values := make(map[string]float64)
+		for j := range snap.Series {
+			s := &snap.Series[j]
+			if math.IsNaN(s.Values[i]) {
+				continue
+			}
+			key := seriesKey(s.Name, s.Labels)
+			if _, ok := h.series[key]; !ok {
+				h.series[key] = h.newSeries(s.Name, s.Labels, s.Type)
+			}
+			values[key] = s.Values[i]
+		}
+		h.append(ts, values)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package telemetry_test
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"employee-management/internal/telemetry"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// historyFixture is a registry with one metric of each kind and a store
+// collecting it every 10 seconds
+type historyFixture struct {
+	store     *telemetry.HistoryStore
+	gauge     prometheus.Gauge
+	counter   prometheus.Counter
+	histogram prometheus.Histogram
+	start     time.Time
+}
+
+func newHistoryFixture(t *testing.T) *historyFixture {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	f := &historyFixture{
+		gauge:     prometheus.NewGauge(prometheus.GaugeOpts{Name: "queue_length"}),
+		counter:   prometheus.NewCounter(prometheus.CounterOpts{Name: "jobs_total"}),
+		histogram: prometheus.NewHistogram(prometheus.HistogramOpts{Name: "job_seconds", Buckets: []float64{1, 2, 4}}),
+		// Load drops points older than the retention, so history is recent
+		start: time.Now().Add(-30 * time.Minute).Truncate(time.Minute),
+	}
+	reg.MustRegister(f.gauge, f.counter, f.histogram)
+	f.store = telemetry.NewHistoryStore(reg, 10*time.Second, time.Hour)
+	return f
+}
+
+// collect takes the snapshot of the i-th 10 second tick
+func (f *historyFixture) collect(t *testing.T, i int) {
+	t.Helper()
+	if err := f.store.Collect(f.at(i)); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+}
+
+func (f *historyFixture) at(i int) time.Time {
+	return f.start.Add(time.Duration(i) * 10 * time.Second)
+}
+
+func (f *historyFixture) query(t *testing.T, q telemetry.HistoryQuery) []telemetry.HistoryPoint {
+	t.Helper()
+	series, err := f.store.Query(q)
+	if err != nil {
+		t.Fatalf("Query(%+v): %v", q, err)
+	}
+	if len(series) != 1 {
+		t.Fatalf("Query(%+v) = %d series, want 1", q, len(series))
+	}
+	return series[0].Points
+}
+
+// checkPoints compares point values, NaN standing for a missing point
+func checkPoints(t *testing.T, what string, points []telemetry.HistoryPoint, want []float64) {
+	t.Helper()
+	if len(points) != len(want) {
+		t.Fatalf("%s: %d points, want %d", what, len(points), len(want))
+	}
+	for i, p := range points {
+		switch {
+		case math.IsNaN(want[i]) && p.V != nil:
+			t.Errorf("%s[%d] = %v, want missing", what, i, *p.V)
+		case !math.IsNaN(want[i]) && p.V == nil:
+			t.Errorf("%s[%d] missing, want %v", what, i, want[i])
+		case p.V != nil && math.Abs(*p.V-want[i]) > 1e-9:
+			t.Errorf("%s[%d] = %v, want %v", what, i, *p.V, want[i])
+		}
+	}
+}
+
+func TestHistoryDownsample(t *testing.T) {
+	f := newHistoryFixture(t)
+	for i, v := range []float64{1, 3, 5, 7} {
+		f.gauge.Set(v)
+		f.counter.Add(10)
+		f.collect(t, i)
+	}
+	// Nothing is collected in the last step
+	q := telemetry.HistoryQuery{From: f.at(0), To: f.at(5), Step: 20 * time.Second}
+
+	q.Name = "queue_length"
+	checkPoints(t, "gauge", f.query(t, q), []float64{2, 6, math.NaN()})
+	q.Name = "jobs_total"
+	checkPoints(t, "counter", f.query(t, q), []float64{20, 40, math.NaN()})
+
+	// A step below the resolution is raised to it
+	q.Name, q.Step = "queue_length", time.Second
+	checkPoints(t, "gauge at resolution", f.query(t, q), []float64{1, 3, 5, 7, math.NaN(), math.NaN()})
+}
+
+func TestHistoryRate(t *testing.T) {
+	f := newHistoryFixture(t)
+	reg := prometheus.NewRegistry()
+	values := prometheus.NewGauge(prometheus.GaugeOpts{Name: "restarts_total"})
+	reg.MustRegister(values)
+	store := telemetry.NewHistoryStore(reg, 10*time.Second, time.Hour)
+
+	// 30 -> 5 is a counter reset, tick 3 is missing
+	for _, tick := range []struct {
+		i int
+		v float64
+	}{{0, 0}, {1, 10}, {2, 30}, {4, 5}, {5, 25}} {
+		values.Set(tick.v)
+		if err := store.Collect(f.at(tick.i)); err != nil {
+			t.Fatalf("Collect: %v", err)
+		}
+	}
+
+	series, err := store.Query(telemetry.HistoryQuery{
+		Name: "restarts_total", From: f.at(0), To: f.at(5), Step: 10 * time.Second, Func: telemetry.FuncRate,
+	})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	// The reset counts the value after it; the rate across the gap is
+	// taken over 20 seconds
+	checkPoints(t, "rate", series[0].Points, []float64{math.NaN(), 1, 2, math.NaN(), 0.25, 2})
+}
+
+func TestHistoryQuantile(t *testing.T) {
+	f := newHistoryFixture(t)
+	f.collect(t, 0)
+	for i := 0; i < 10; i++ {
+		f.histogram.Observe(0.5)
+		f.histogram.Observe(1.5)
+	}
+	f.collect(t, 1)
+
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.5, 1},    // the top of the first bucket
+		{0.75, 1.5}, // half way through the second one
+		{1, 2},
+	}
+	for _, tt := range tests {
+		points := f.query(t, telemetry.HistoryQuery{
+			Name: "job_seconds", From: f.at(0), To: f.at(1), Step: 10 * time.Second,
+			Func: telemetry.FuncQuantile, Q: tt.q,
+		})
+		checkPoints(t, "quantile", points, []float64{math.NaN(), tt.want})
+	}
+
+	if _, err := f.store.Query(telemetry.HistoryQuery{
+		Name: "job_seconds", From: f.at(0), To: f.at(1), Func: telemetry.FuncQuantile, Q: 1.5,
+	}); err == nil {
+		t.Error("quantile 1.5 accepted")
+	}
+}
+
+func TestHistorySaveLoad(t *testing.T) {
+	f := newHistoryFixture(t)
+	for i, v := range []float64{4, 8, 15, 16} {
+		f.gauge.Set(v)
+		f.collect(t, i)
+	}
+	path := filepath.Join(t.TempDir(), "history.gob")
+	if err := f.store.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	q := telemetry.HistoryQuery{Name: "queue_length", From: f.at(0), To: f.at(3), Step: 10 * time.Second}
+
+	loaded := telemetry.NewHistoryStore(prometheus.NewRegistry(), 10*time.Second, time.Hour)
+	if err := loaded.Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	series, err := loaded.Query(q)
+	if err != nil || len(series) != 1 {
+		t.Fatalf("Query after Load = %v, %v", series, err)
+	}
+	checkPoints(t, "loaded", series[0].Points, []float64{4, 8, 15, 16})
+
+	// A shorter retention drops the points older than it
+	short := telemetry.NewHistoryStore(prometheus.NewRegistry(), 10*time.Second, time.Since(f.at(2))+5*time.Second)
+	if err := short.Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	series, err = short.Query(q)
+	if err != nil || len(series) != 1 {
+		t.Fatalf("Query after Load = %v, %v", series, err)
+	}
+	checkPoints(t, "loaded with a short retention", series[0].Points, []float64{math.NaN(), math.NaN(), 15, 16})
+
+	if err := loaded.Load(filepath.Join(t.TempDir(), "missing.gob")); err != nil {
+		t.Errorf("Load of a missing file: %v", err)
+	}
+}
@@ -1,137 +1,238 @@
 package telemetry
 
 import (
-	"fmt"
-	"log/slog"
-	"os"
-	"strings"
+	"context"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Prometheus metrics
-var (
-	HttpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "http_requests_total",
-		Help: "Total number of HTTP requests",
-	}, []string{"method", "path", "status"})
-
-	HttpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "http_request_duration_seconds",
-		Help:    "HTTP request duration in seconds",
-		Buckets: prometheus.DefBuckets,
-	}, []string{"method", "path"})
-
-	EmployeesTotal = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "employees_total",
-		Help: "Total number of employees",
-	})
-
-	EmployeesByStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "employees_by_status",
-		Help: "Number of employees by status",
-	}, []string{"status"})
-)
-
-var metricsFile *os.File
+// httpDurationBuckets are the default buckets with a 300ms bound added for latency SLOs
+var httpDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .3, .5, 1, 2.5, 5, 10}
 
-// InitMetrics initializes metrics
-func InitMetrics() {
-	// Metrics are auto-registered by promauto
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	GoVersion string
 }
 
-// UpdateEmployeeMetrics updates employee-related metrics
-func UpdateEmployeeMetrics(stats map[string]interface{}) {
-	if total, ok := stats["total"].(int); ok {
-		EmployeesTotal.Set(float64(total))
-	}
-
-	if byStatus, ok := stats["by_status"].(map[string]int); ok {
-		for status, count := range byStatus {
-			EmployeesByStatus.WithLabelValues(status).Set(float64(count))
+// NewBuildInfo fills the commit from the VCS stamp of the binary when it is
+// not set at link time
+func NewBuildInfo(version, commit string) BuildInfo {
+	if commit == "" {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, s := range info.Settings {
+				if s.Key == "vcs.revision" {
+					commit = s.Value
+				}
+			}
 		}
 	}
-}
-
-// SetupMetricsWriter sets up the metrics file writer
-func SetupMetricsWriter(metricsDir, metricsFileName string) (*os.File, error) {
-	if err := os.MkdirAll(metricsDir, 0755); err != nil {
-		return nil, fmt.Errorf("не удалось создать директорию метрик: %w", err)
+	if commit == "" {
+		commit = "unknown"
 	}
-
-	metricsFilePath := metricsDir + "/" + metricsFileName
-	file, err := os.OpenFile(metricsFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		return nil, fmt.Errorf("не удалось открыть файл метрик: %w", err)
+	if version == "" {
+		version = "dev"
 	}
+	return BuildInfo{Version: version, Commit: commit, GoVersion: runtime.Version()}
+}
 
-	metricsFile = file
-	return file, nil
+// Metrics holds the application metrics and the registry they are registered on
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	HttpRequestsTotal   *prometheus.CounterVec
+	HttpRequestDuration *prometheus.HistogramVec
+
+	EmployeesTotal           prometheus.Gauge
+	EmployeesByStatus        *prometheus.GaugeVec
+	EmployeesHiredTotal      *prometheus.CounterVec
+	EmployeesTerminatedTotal *prometheus.CounterVec
+	VacationsStartedTotal    *prometheus.CounterVec
+	VacationsEndedTotal      *prometheus.CounterVec
+	TenureAtTermination      *prometheus.HistogramVec
+
+	RepositoryCallsTotal   *prometheus.CounterVec
+	RepositoryErrorsTotal  *prometheus.CounterVec
+	RepositoryCallDuration *prometheus.HistogramVec
+	RepositoryResultSize   *prometheus.HistogramVec
+
+	TailSampledTraces  *prometheus.CounterVec
+	TailDroppedSpans   prometheus.Counter
+	TailBufferedTraces prometheus.Gauge
+	TailBufferedSpans  prometheus.Gauge
+
+	SLOObjectiveRatio       *prometheus.GaugeVec
+	SLOComplianceRatio      *prometheus.GaugeVec
+	SLOErrorBudgetRemaining *prometheus.GaugeVec
+	SLOBurnRate             *prometheus.GaugeVec
+	SLOBurnRateAlerting     *prometheus.GaugeVec
+
+	guard  *cardinalityGuard
+	statsd *StatsD
+
+	statusMu     sync.Mutex
+	statusSeries map[string]struct{} // EmployeesByStatus labels set so far
 }
 
-// WriteMetricsToFile writes current metrics to file
-func WriteMetricsToFile() {
-	if metricsFile == nil {
-		return
-	}
+// NewMetrics creates the application metrics on a new registry together with
+// Go runtime, process, build info and uptime collectors
+func NewMetrics(build BuildInfo) *Metrics {
+	reg := prometheus.NewRegistry()
+	m := newMetrics(reg)
+
+	startedAt := time.Now()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "build_info",
+			Help: "Build information of the running binary",
+			ConstLabels: prometheus.Labels{
+				"version":    build.Version,
+				"commit":     build.Commit,
+				"go_version": build.GoVersion,
+			},
+		}, func() float64 { return 1 }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "uptime_seconds",
+			Help: "Time since the service started in seconds",
+		}, func() float64 { return time.Since(startedAt).Seconds() }),
+	)
+	return m
+}
 
-	metrics, err := prometheus.DefaultGatherer.Gather()
-	if err != nil {
-		slog.Error("Ошибка сбора метрик", "error", err)
-		return
-	}
+func newMetrics(reg *prometheus.Registry) *Metrics {
+	m := &Metrics{
+		Registry: reg,
 
-	timestamp := time.Now().Format(time.RFC3339)
-	metricsFile.WriteString(fmt.Sprintf("=== METRICS DUMP %s ===\n", timestamp))
-
-	for _, metric := range metrics {
-		metricsFile.WriteString(fmt.Sprintf("Metric: %s\n", metric.GetName()))
-		metricsFile.WriteString(fmt.Sprintf("Help: %s\n", metric.GetHelp()))
-		metricsFile.WriteString(fmt.Sprintf("Type: %v\n", metric.GetType()))
-
-		for _, m := range metric.GetMetric() {
-			var value float64
-
-			if counter := m.GetCounter(); counter != nil {
-				value = counter.GetValue()
-			} else if gauge := m.GetGauge(); gauge != nil {
-				value = gauge.GetValue()
-			} else if histogram := m.GetHistogram(); histogram != nil {
-				value = histogram.GetSampleSum()
-			} else if summary := m.GetSummary(); summary != nil {
-				value = summary.GetSampleSum()
-			} else if untyped := m.GetUntyped(); untyped != nil {
-				value = untyped.GetValue()
-			}
+		HttpRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests",
+		}, httpRequestLabels),
 
-			labels := make([]string, 0, len(m.GetLabel()))
-			for _, label := range m.GetLabel() {
-				labels = append(labels, fmt.Sprintf("%s=%s", label.GetName(), label.GetValue()))
-			}
+		HttpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request duration in seconds",
+			Buckets: httpDurationBuckets,
+		}, httpDurationLabels),
 
-			labelStr := ""
-			if len(labels) > 0 {
-				labelStr = fmt.Sprintf(" {%s}", strings.Join(labels, ", "))
-			}
+		EmployeesTotal: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "employees_total",
+			Help: "Total number of employees",
+		}),
 
-			metricsFile.WriteString(fmt.Sprintf("  %s%s: %f\n", metric.GetName(), labelStr, value))
-		}
-		metricsFile.WriteString("\n")
-	}
-	metricsFile.WriteString("=== END METRICS DUMP ===\n\n")
+		EmployeesByStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "employees_by_status",
+			Help: "Number of employees by status",
+		}, statusLabels),
 
-	metricsFile.Sync()
+		guard: newCardinalityGuard(),
+	}
+	m.initBusinessMetrics()
+	m.initRepositoryMetrics()
+	m.initSamplingMetrics()
+	m.initSLOMetrics()
+
+	reg.MustRegister(
+		m.HttpRequestsTotal,
+		m.HttpRequestDuration,
+		m.EmployeesTotal,
+		m.EmployeesByStatus,
+		m.EmployeesHiredTotal,
+		m.EmployeesTerminatedTotal,
+		m.VacationsStartedTotal,
+		m.VacationsEndedTotal,
+		m.TenureAtTermination,
+		m.RepositoryCallsTotal,
+		m.RepositoryErrorsTotal,
+		m.RepositoryCallDuration,
+		m.RepositoryResultSize,
+		m.TailSampledTraces,
+		m.TailDroppedSpans,
+		m.TailBufferedTraces,
+		m.TailBufferedSpans,
+		m.SLOObjectiveRatio,
+		m.SLOComplianceRatio,
+		m.SLOErrorBudgetRemaining,
+		m.SLOBurnRate,
+		m.SLOBurnRateAlerting,
+		m.guard.overflow,
+	)
+	return m
 }
 
-// StartMetricsWriter starts periodic metrics writing
-func StartMetricsWriter() {
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+// MirrorToStatsD sends HTTP and business metrics to s as well. It must be
+// called before metrics are recorded.
+func (m *Metrics) MirrorToStatsD(s *StatsD) {
+	m.statsd = s
+}
 
-	for range ticker.C {
-		WriteMetricsToFile()
+// RecordHTTPRequest counts a served HTTP request and observes its duration.
+// When ctx carries a sampled span, its trace ID is attached to the duration
+// as an exemplar.
+func (m *Metrics) RecordHTTPRequest(ctx context.Context, method, path string, status int, duration time.Duration) {
+	labels := m.guard.apply("http_requests_total", httpRequestLabels, method, path, strconv.Itoa(status))
+	m.HttpRequestsTotal.WithLabelValues(labels...).Inc()
+
+	observer := m.HttpRequestDuration.WithLabelValues(
+		m.guard.apply("http_request_duration_seconds", httpDurationLabels, method, path)...,
+	)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+		observer.(prometheus.ExemplarObserver).ObserveWithExemplar(
+			duration.Seconds(), prometheus.Labels{"trace_id": sc.TraceID().String()},
+		)
+	} else {
+		observer.Observe(duration.Seconds())
 	}
+
+	method, path, code := labels[0], labels[1], labels[2]
+	m.statsd.Count("http.requests", 1, "method", method, "path", path, "status", code)
+	m.statsd.Timing("http.request.duration", duration, "method", method, "path", path)
 }
 
+// UpdateEmployeeMetrics updates employee-related metrics
+func (m *Metrics) UpdateEmployeeMetrics(stats map[string]interface{}) {
+	if total, ok := stats["total"].(int); ok {
+		m.EmployeesTotal.Set(float64(total))
+		m.statsd.Gauge("employees.total", float64(total))
+	}
+
+	if byStatus, ok := stats["by_status"].(map[string]int); ok {
+		// Statuses collapsed by the guard share a series, so totals are
+		// summed first and each series is set once
+		totals := make(map[string]float64, len(knownStatuses)+len(byStatus))
+		for _, status := range knownStatuses {
+			totals[m.guard.apply("employees_by_status", statusLabels, status)[0]] = 0
+		}
+		for status, count := range byStatus {
+			totals[m.guard.apply("employees_by_status", statusLabels, status)[0]] += float64(count)
+		}
+
+		m.statusMu.Lock()
+		if m.statusSeries == nil {
+			m.statusSeries = make(map[string]struct{}, len(totals))
+		}
+		for status := range m.statusSeries {
+			if _, ok := totals[status]; !ok {
+				m.EmployeesByStatus.WithLabelValues(status).Set(0)
+			}
+		}
+		for status, total := range totals {
+			m.EmployeesByStatus.WithLabelValues(status).Set(total)
+			m.statusSeries[status] = struct{}{}
+		}
+		m.statusMu.Unlock()
+
+		for _, status := range knownStatuses {
+			m.statsd.Gauge("employees.by_status", float64(byStatus[status]), "status", status)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package telemetry_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"employee-management/internal/telemetry"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEmployeesByStatusSumsCollapsedStatuses(t *testing.T) {
+	metrics := telemetry.NewMetrics(telemetry.BuildInfo{})
+	metrics.LimitCardinality(telemetry.CardinalityLimits{PerMetric: map[string]int{"employees_by_status": 3}})
+
+	metrics.UpdateEmployeeMetrics(map[string]interface{}{
+		"total":     int(11),
+		"by_status": map[string]int{"active": 2, "vacation": 1, "fired": 1, "probation": 4, "suspended": 3},
+	})
+	want := map[string]float64{"active": 2, "vacation": 1, "fired": 1, telemetry.OtherLabelValue: 7}
+	for status, value := range want {
+		if got := testutil.ToFloat64(metrics.EmployeesByStatus.WithLabelValues(status)); got != value {
+			t.Errorf("employees_by_status{status=%q} = %v, want %v", status, got, value)
+		}
+	}
+
+	// Statuses that disappear drop to zero instead of keeping their last value
+	metrics.UpdateEmployeeMetrics(map[string]interface{}{
+		"by_status": map[string]int{"active": 3},
+	})
+	want = map[string]float64{"active": 3, "vacation": 0, "fired": 0, telemetry.OtherLabelValue: 0}
+	for status, value := range want {
+		if got := testutil.ToFloat64(metrics.EmployeesByStatus.WithLabelValues(status)); got != value {
+			t.Errorf("employees_by_status{status=%q} = %v, want %v", status, got, value)
+		}
+	}
+}
+
+func TestConcurrentEmployeeMetricsUpdates(t *testing.T) {
+	metrics := telemetry.NewMetrics(telemetry.BuildInfo{})
+	stats := map[string]interface{}{
+		"total":     int(4),
+		"by_status": map[string]int{"active": 3, "vacation": 1},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			metrics.UpdateEmployeeMetrics(stats)
+		}()
+	}
+	wg.Wait()
+
+	if got := testutil.ToFloat64(metrics.EmployeesByStatus.WithLabelValues("active")); got != 3 {
+		t.Errorf("employees_by_status{status=\"active\"} = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(metrics.EmployeesByStatus.WithLabelValues("vacation")); got != 1 {
+		t.Errorf("employees_by_status{status=\"vacation\"} = %v, want 1", got)
+	}
+}
+
+func TestEachLabelOverflowIsLoggedOnce(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	metrics := telemetry.NewMetrics(telemetry.BuildInfo{})
+	metrics.LimitCardinality(telemetry.CardinalityLimits{PerMetric: map[string]int{"http_requests_total": 1}})
+	for _, path := range []string{"/a", "/b", "/b", "/c", "/a", "/c"} {
+		metrics.RecordHTTPRequest(context.Background(), http.MethodGet, path, http.StatusOK, time.Millisecond)
+	}
+
+	var values []string
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if strings.Contains(line, "label=path") {
+			_, value, _ := strings.Cut(line, "value=")
+			values = append(values, value)
+		}
+	}
+	if want := []string{"/b", "/c"}; !slices.Equal(values, want) {
+		t.Errorf("logged overflows = %q, want %q", values, want)
+	}
+	if got := testutil.ToFloat64(metrics.HttpRequestsTotal.WithLabelValues("GET", telemetry.OtherLabelValue, "200")); got != 4 {
+		t.Errorf("requests collapsed into other = %v, want 4", got)
+	}
+}
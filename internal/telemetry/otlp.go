@@ -0,0 +1,238 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpScope is the instrumentation scope reported with exported metrics
+const otlpScope = "employee-management/internal/telemetry"
+
+// OTLPPusher sends metrics to an OTLP/HTTP receiver as protobuf
+type OTLPPusher struct {
+	gatherer prometheus.Gatherer
+	endpoint string
+	headers  map[string]string
+	resource *resourcepb.Resource
+	start    time.Time
+	client   *http.Client
+}
+
+// NewOTLPPusher creates a pusher for the OTLP/HTTP metrics endpoint, e.g.
+// http://localhost:4318/v1/metrics. Job becomes service.name and the grouping
+// labels become resource attributes.
+func NewOTLPPusher(gatherer prometheus.Gatherer, endpoint string, headers map[string]string, opts PushOptions) *OTLPPusher {
+	attrs := map[string]string{"service.name": opts.Job}
+	for name, value := range opts.Grouping {
+		attrs[name] = value
+	}
+	return &OTLPPusher{
+		gatherer: gatherer,
+		endpoint: endpoint,
+		headers:  headers,
+		resource: &resourcepb.Resource{Attributes: otlpAttributes(attrs)},
+		start:    time.Now(),
+		client:   http.DefaultClient,
+	}
+}
+
+// Name returns the exporter name used in logs
+func (p *OTLPPusher) Name() string {
+	return "otlp"
+}
+
+// Push gathers the metrics and posts them as an ExportMetricsServiceRequest.
+// Counters and histograms are cumulative since the pusher was created.
+func (p *OTLPPusher) Push(ctx context.Context) error {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("сбор метрик: %w", err)
+	}
+
+	body, err := proto.Marshal(p.request(families, time.Now()))
+	if err != nil {
+		return fmt.Errorf("кодирование OTLP: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for name, value := range p.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("OTLP приемник ответил %s: %s", resp.Status, bytes.TrimSpace(msg))
+		// As in the OTLP/HTTP spec, only throttling and unavailability are retried
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return err
+		default:
+			return &permanentError{err: err}
+		}
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (p *OTLPPusher) request(families []*dto.MetricFamily, now time.Time) *colmetricspb.ExportMetricsServiceRequest {
+	start, ts := uint64(p.start.UnixNano()), uint64(now.UnixNano())
+
+	metrics := make([]*metricspb.Metric, 0, len(families))
+	for _, mf := range families {
+		if m := otlpMetric(mf, start, ts); m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: p.resource,
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope:   &commonpb.InstrumentationScope{Name: otlpScope},
+				Metrics: metrics,
+			}},
+		}},
+	}
+}
+
+// otlpMetric converts a metric family. Counters become monotonic sums,
+// gauges and untyped metrics become gauges.
+func otlpMetric(mf *dto.MetricFamily, start, ts uint64) *metricspb.Metric {
+	m := &metricspb.Metric{Name: mf.GetName(), Description: mf.GetHelp()}
+
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		points := make([]*metricspb.NumberDataPoint, 0, len(mf.GetMetric()))
+		for _, metric := range mf.GetMetric() {
+			points = append(points, numberPoint(metric, metric.GetCounter().GetValue(), start, ts))
+		}
+		m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints:             points,
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}
+	case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		points := make([]*metricspb.NumberDataPoint, 0, len(mf.GetMetric()))
+		for _, metric := range mf.GetMetric() {
+			v := metric.GetGauge().GetValue()
+			if mf.GetType() == dto.MetricType_UNTYPED {
+				v = metric.GetUntyped().GetValue()
+			}
+			points = append(points, numberPoint(metric, v, 0, ts))
+		}
+		m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}}
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		points := make([]*metricspb.HistogramDataPoint, 0, len(mf.GetMetric()))
+		for _, metric := range mf.GetMetric() {
+			points = append(points, histogramPoint(metric, start, ts))
+		}
+		m.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			DataPoints:             points,
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}}
+	case dto.MetricType_SUMMARY:
+		points := make([]*metricspb.SummaryDataPoint, 0, len(mf.GetMetric()))
+		for _, metric := range mf.GetMetric() {
+			points = append(points, summaryPoint(metric, start, ts))
+		}
+		m.Data = &metricspb.Metric_Summary{Summary: &metricspb.Summary{DataPoints: points}}
+	default:
+		return nil
+	}
+	return m
+}
+
+func numberPoint(metric *dto.Metric, v float64, start, ts uint64) *metricspb.NumberDataPoint {
+	return &metricspb.NumberDataPoint{
+		Attributes:        otlpAttributes(labelMap(metric)),
+		StartTimeUnixNano: start,
+		TimeUnixNano:      ts,
+		Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: v},
+	}
+}
+
+// histogramPoint turns cumulative Prometheus buckets into per-bucket counts
+// with an implicit +Inf bucket at the end
+func histogramPoint(metric *dto.Metric, start, ts uint64) *metricspb.HistogramDataPoint {
+	h := metric.GetHistogram()
+	sum := h.GetSampleSum()
+	point := &metricspb.HistogramDataPoint{
+		Attributes:        otlpAttributes(labelMap(metric)),
+		StartTimeUnixNano: start,
+		TimeUnixNano:      ts,
+		Count:             h.GetSampleCount(),
+		Sum:               &sum,
+	}
+
+	var prev uint64
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), 1) {
+			continue
+		}
+		point.ExplicitBounds = append(point.ExplicitBounds, b.GetUpperBound())
+		point.BucketCounts = append(point.BucketCounts, b.GetCumulativeCount()-prev)
+		prev = b.GetCumulativeCount()
+	}
+	point.BucketCounts = append(point.BucketCounts, h.GetSampleCount()-prev)
+	return point
+}
+
+func summaryPoint(metric *dto.Metric, start, ts uint64) *metricspb.SummaryDataPoint {
+	s := metric.GetSummary()
+	point := &metricspb.SummaryDataPoint{
+		Attributes:        otlpAttributes(labelMap(metric)),
+		StartTimeUnixNano: start,
+		TimeUnixNano:      ts,
+		Count:             s.GetSampleCount(),
+		Sum:               s.GetSampleSum(),
+	}
+	for _, q := range s.GetQuantile() {
+		point.QuantileValues = append(point.QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{
+			Quantile: q.GetQuantile(),
+			Value:    q.GetValue(),
+		})
+	}
+	return point
+}
+
+// otlpAttributes converts labels to string attributes sorted by name
+func otlpAttributes(labels map[string]string) []*commonpb.KeyValue {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make([]*commonpb.KeyValue, 0, len(names))
+	for _, name := range names {
+		attrs = append(attrs, &commonpb.KeyValue{
+			Key:   name,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: labels[name]}},
+		})
+	}
+	return attrs
+}
@@ -0,0 +1,140 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// MetricsPusher sends the current metrics to a remote receiver
+type MetricsPusher interface {
+	Name() string
+	Push(ctx context.Context) error
+}
+
+// PushOptions are shared by all push exporters. Job identifies the service
+// and Grouping adds labels to every pushed series.
+type PushOptions struct {
+	Job      string
+	Grouping map[string]string
+}
+
+// PushRetry controls how a failed push is repeated: up to Attempts more
+// times, waiting Backoff before the first retry and twice as long before
+// each next one. Retries stop once the push timeout runs out.
+type PushRetry struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// permanentError marks a push failure that a retry cannot fix, such as a
+// rejected payload
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// PushExporter periodically pushes metrics through its pushers
+type PushExporter struct {
+	mu      sync.Mutex // serializes periodic pushes with the final flush
+	pushers []MetricsPusher
+	timeout time.Duration
+	retry   PushRetry
+}
+
+// NewPushExporter creates an exporter whose pushes, retries included, are
+// cut off after timeout
+func NewPushExporter(timeout time.Duration, retry PushRetry, pushers ...MetricsPusher) *PushExporter {
+	return &PushExporter{pushers: pushers, timeout: timeout, retry: retry}
+}
+
+// Add registers a pusher
+func (e *PushExporter) Add(p MetricsPusher) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pushers = append(e.pushers, p)
+}
+
+// Run pushes every interval until ctx is done
+func (e *PushExporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.Flush()
+		}
+	}
+}
+
+// Flush pushes the current metrics through every pusher once
+func (e *PushExporter) Flush() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, p := range e.pushers {
+		ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+		if err := e.push(ctx, p); err != nil {
+			slog.Error("Ошибка отправки метрик", "exporter", p.Name(), "error", err)
+		}
+		cancel()
+	}
+}
+
+// push calls p, retrying failures with exponential backoff
+func (e *PushExporter) push(ctx context.Context, p MetricsPusher) error {
+	backoff := e.retry.Backoff
+	for attempt := 0; ; attempt++ {
+		err := p.Push(ctx)
+		var permanent *permanentError
+		if err == nil || errors.As(err, &permanent) || attempt >= e.retry.Attempts {
+			return err
+		}
+		slog.Warn("Повтор отправки метрик", "exporter", p.Name(), "attempt", attempt+1, "backoff", backoff.String(), "error", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// PushgatewayPusher replaces the metrics of its group on a Prometheus Pushgateway
+type PushgatewayPusher struct {
+	pusher *push.Pusher
+}
+
+// NewPushgatewayPusher creates a pusher for the Pushgateway at url
+func NewPushgatewayPusher(gatherer prometheus.Gatherer, url string, opts PushOptions) *PushgatewayPusher {
+	pusher := push.New(url, opts.Job).Gatherer(gatherer).Client(http.DefaultClient)
+	for name, value := range opts.Grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+	return &PushgatewayPusher{pusher: pusher}
+}
+
+// Name returns the exporter name used in logs
+func (p *PushgatewayPusher) Name() string {
+	return "pushgateway"
+}
+
+// Push sends the metrics with PUT, so series gone from the registry are removed
+func (p *PushgatewayPusher) Push(ctx context.Context) error {
+	return p.pusher.PushContext(ctx)
+}
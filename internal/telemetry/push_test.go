@@ -0,0 +1,298 @@
+package telemetry_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"employee-management/internal/telemetry"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var testPushOptions = telemetry.PushOptions{
+	Job:      "employee-management",
+	Grouping: map[string]string{"instance": "test"},
+}
+
+// receiver is a local HTTP endpoint that records pushed requests and
+// answers with the queued statuses, then 200
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	times    []time.Time
+	statuses []int
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	rcv := &receiver{statuses: statuses}
+	rcv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rcv.mu.Lock()
+		rcv.requests = append(rcv.requests, r)
+		rcv.bodies = append(rcv.bodies, body)
+		rcv.times = append(rcv.times, time.Now())
+		status := http.StatusOK
+		if len(rcv.statuses) > 0 {
+			status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+		}
+		rcv.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rcv.Close)
+	return rcv
+}
+
+func (rcv *receiver) count() int {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return len(rcv.requests)
+}
+
+func (rcv *receiver) last() (*http.Request, []byte) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return rcv.requests[len(rcv.requests)-1], rcv.bodies[len(rcv.bodies)-1]
+}
+
+// newPushRegistry returns a registry with a counter at 3 and a histogram
+// observed once in each of its two buckets and once above them
+func newPushRegistry() (*prometheus.Registry, prometheus.Counter) {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "pushed_total", Help: "Pushed counter"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "pushed_seconds", Help: "Pushed histogram", Buckets: []float64{1, 2},
+	})
+	reg.MustRegister(counter, histogram)
+	counter.Add(3)
+	for _, v := range []float64{0.5, 1.5, 5} {
+		histogram.Observe(v)
+	}
+	return reg, counter
+}
+
+func TestPushgatewayPayload(t *testing.T) {
+	rcv := newReceiver(t)
+	reg, _ := newPushRegistry()
+
+	exporter := telemetry.NewPushExporter(time.Second, telemetry.PushRetry{},
+		telemetry.NewPushgatewayPusher(reg, rcv.URL, testPushOptions))
+	exporter.Flush()
+
+	if rcv.count() != 1 {
+		t.Fatalf("requests = %d, want 1", rcv.count())
+	}
+	req, body := rcv.last()
+	if req.Method != http.MethodPut {
+		t.Errorf("method = %s, want PUT", req.Method)
+	}
+	if want := "/metrics/job/employee-management/instance/test"; req.URL.Path != want {
+		t.Errorf("path = %s, want %s", req.URL.Path, want)
+	}
+
+	families := map[string]*dto.MetricFamily{}
+	dec := expfmt.NewDecoder(bytes.NewReader(body), expfmt.ResponseFormat(req.Header))
+	for {
+		var mf dto.MetricFamily
+		if err := dec.Decode(&mf); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("decode pushed metrics: %v", err)
+		}
+		families[mf.GetName()] = &mf
+	}
+	if got := families["pushed_total"].GetMetric()[0].GetCounter().GetValue(); got != 3 {
+		t.Errorf("pushed_total = %v, want 3", got)
+	}
+	if got := families["pushed_seconds"].GetMetric()[0].GetHistogram().GetSampleCount(); got != 3 {
+		t.Errorf("pushed_seconds count = %v, want 3", got)
+	}
+}
+
+func TestOTLPPayload(t *testing.T) {
+	rcv := newReceiver(t)
+	reg, _ := newPushRegistry()
+
+	pusher := telemetry.NewOTLPPusher(reg, rcv.URL+"/v1/metrics", map[string]string{"Authorization": "Bearer token"}, testPushOptions)
+	telemetry.NewPushExporter(time.Second, telemetry.PushRetry{}, pusher).Flush()
+
+	if rcv.count() != 1 {
+		t.Fatalf("requests = %d, want 1", rcv.count())
+	}
+	req, body := rcv.last()
+	if req.Method != http.MethodPost || req.URL.Path != "/v1/metrics" {
+		t.Errorf("request = %s %s, want POST /v1/metrics", req.Method, req.URL.Path)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/x-protobuf" {
+		t.Errorf("Content-Type = %q, want application/x-protobuf", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q, want the configured header", got)
+	}
+
+	var export colmetricspb.ExportMetricsServiceRequest
+	if err := proto.Unmarshal(body, &export); err != nil {
+		t.Fatalf("decode OTLP request: %v", err)
+	}
+	rm := export.GetResourceMetrics()[0]
+	attrs := map[string]string{}
+	for _, kv := range rm.GetResource().GetAttributes() {
+		attrs[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	if attrs["service.name"] != "employee-management" || attrs["instance"] != "test" {
+		t.Errorf("resource attributes = %v, want service.name and instance", attrs)
+	}
+
+	metrics := map[string]*metricspb.Metric{}
+	for _, m := range rm.GetScopeMetrics()[0].GetMetrics() {
+		metrics[m.GetName()] = m
+	}
+
+	sum := metrics["pushed_total"].GetSum()
+	if sum == nil || !sum.GetIsMonotonic() ||
+		sum.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Fatalf("pushed_total = %v, want a cumulative monotonic sum", metrics["pushed_total"])
+	}
+	if got := sum.GetDataPoints()[0].GetAsDouble(); got != 3 {
+		t.Errorf("pushed_total = %v, want 3", got)
+	}
+
+	point := metrics["pushed_seconds"].GetHistogram().GetDataPoints()[0]
+	if got, want := point.GetExplicitBounds(), []float64{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("bounds = %v, want %v", got, want)
+	}
+	// Per-bucket counts with the implicit +Inf bucket last
+	if got, want := point.GetBucketCounts(), []uint64{1, 1, 1}; !slices.Equal(got, want) {
+		t.Errorf("bucket counts = %v, want %v", got, want)
+	}
+	if point.GetCount() != 3 || point.GetSum() != 7 {
+		t.Errorf("count, sum = %d, %v, want 3, 7", point.GetCount(), point.GetSum())
+	}
+}
+
+func TestPushRetriesWithBackoff(t *testing.T) {
+	rcv := newReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	reg, _ := newPushRegistry()
+
+	backoff := 30 * time.Millisecond
+	pusher := telemetry.NewOTLPPusher(reg, rcv.URL, nil, testPushOptions)
+	telemetry.NewPushExporter(5*time.Second, telemetry.PushRetry{Attempts: 3, Backoff: backoff}, pusher).Flush()
+
+	if rcv.count() != 3 {
+		t.Fatalf("requests = %d, want 2 failures and a success", rcv.count())
+	}
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	for i, want := range []time.Duration{backoff, 2 * backoff} {
+		if gap := rcv.times[i+1].Sub(rcv.times[i]); gap < want {
+			t.Errorf("retry %d came after %v, want at least %v", i+1, gap, want)
+		}
+	}
+}
+
+func TestPushDoesNotRetryRejectedPayload(t *testing.T) {
+	rcv := newReceiver(t, http.StatusBadRequest)
+	reg, _ := newPushRegistry()
+
+	pusher := telemetry.NewOTLPPusher(reg, rcv.URL, nil, testPushOptions)
+	telemetry.NewPushExporter(5*time.Second, telemetry.PushRetry{Attempts: 3, Backoff: time.Millisecond}, pusher).Flush()
+
+	if rcv.count() != 1 {
+		t.Errorf("requests = %d, want 1", rcv.count())
+	}
+}
+
+func TestPushRetriesStopAtTimeout(t *testing.T) {
+	rcv := newReceiver(t, 503, 503, 503, 503, 503, 503)
+	reg, _ := newPushRegistry()
+
+	pusher := telemetry.NewOTLPPusher(reg, rcv.URL, nil, testPushOptions)
+	start := time.Now()
+	telemetry.NewPushExporter(100*time.Millisecond, telemetry.PushRetry{Attempts: 5, Backoff: 80 * time.Millisecond}, pusher).Flush()
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("flush took %v, want it cut off by the timeout", elapsed)
+	}
+	if rcv.count() != 2 {
+		t.Errorf("requests = %d, want 2 within the timeout", rcv.count())
+	}
+}
+
+// stubPusher records the counter value it sees on every push
+type stubPusher struct {
+	counter prometheus.Counter
+	mu      sync.Mutex
+	values  []float64
+	err     error
+}
+
+func (p *stubPusher) Name() string { return "stub" }
+
+func (p *stubPusher) Push(ctx context.Context) error {
+	var m dto.Metric
+	p.counter.Write(&m)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values = append(p.values, m.GetCounter().GetValue())
+	return p.err
+}
+
+func TestFlushOnShutdownPushesFinalValues(t *testing.T) {
+	_, counter := newPushRegistry()
+	stub := &stubPusher{counter: counter}
+	exporter := telemetry.NewPushExporter(time.Second, telemetry.PushRetry{}, stub)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		exporter.Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	counter.Inc()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was canceled")
+	}
+	exporter.Flush()
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.values) != 1 || stub.values[0] != 4 {
+		t.Errorf("pushed values = %v, want the final value 4 once", stub.values)
+	}
+}
+
+func TestFlushContinuesAfterFailedPusher(t *testing.T) {
+	rcv := newReceiver(t)
+	reg, counter := newPushRegistry()
+	failing := &stubPusher{counter: counter, err: errors.New("недоступен")}
+
+	exporter := telemetry.NewPushExporter(time.Second, telemetry.PushRetry{Attempts: 1, Backoff: time.Millisecond},
+		failing, telemetry.NewOTLPPusher(reg, rcv.URL, nil, testPushOptions))
+	exporter.Flush()
+
+	if len(failing.values) != 2 {
+		t.Errorf("failing pusher called %d times, want 2", len(failing.values))
+	}
+	if rcv.count() != 1 {
+		t.Errorf("requests = %d, want 1", rcv.count())
+	}
+}
@@ -0,0 +1,47 @@
+package telemetry
+
+import (
+	"math"
+	"sort"
+)
+
+// Bucket is a cumulative histogram bucket
+type Bucket struct {
+	UpperBound float64
+	Count      float64
+}
+
+// HistogramQuantile estimates a quantile from cumulative histogram buckets
+// with linear interpolation inside the bucket, like PromQL histogram_quantile.
+// The buckets must include +Inf. It returns NaN when there is nothing to estimate.
+func HistogramQuantile(q float64, buckets []Bucket) float64 {
+	if len(buckets) < 2 {
+		return math.NaN()
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].UpperBound < buckets[j].UpperBound })
+	if !math.IsInf(buckets[len(buckets)-1].UpperBound, 1) {
+		return math.NaN()
+	}
+
+	observations := buckets[len(buckets)-1].Count
+	if observations == 0 {
+		return math.NaN()
+	}
+
+	rank := q * observations
+	i := sort.Search(len(buckets)-1, func(i int) bool { return buckets[i].Count >= rank })
+	if i == len(buckets)-1 {
+		// The quantile falls into the +Inf bucket
+		return buckets[len(buckets)-2].UpperBound
+	}
+
+	lower, lowerCount := 0.0, 0.0
+	if i > 0 {
+		lower, lowerCount = buckets[i-1].UpperBound, buckets[i-1].Count
+	}
+	inBucket := buckets[i].Count - lowerCount
+	if inBucket <= 0 {
+		return buckets[i].UpperBound
+	}
+	return lower + (buckets[i].UpperBound-lower)*(rank-lowerCount)/inBucket
+}
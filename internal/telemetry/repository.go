@@ -0,0 +1,46 @@
+package telemetry
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// initRepositoryMetrics creates storage layer metrics
+func (m *Metrics) initRepositoryMetrics() {
+	m.RepositoryCallsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "repository_calls_total",
+		Help: "Total number of repository calls",
+	}, []string{"method"})
+
+	m.RepositoryErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "repository_errors_total",
+		Help: "Total number of failed repository calls",
+	}, []string{"method"})
+
+	m.RepositoryCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "repository_call_duration_seconds",
+		Help:    "Repository call duration in seconds",
+		Buckets: []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+	}, []string{"method"})
+
+	m.RepositoryResultSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "repository_result_size",
+		Help:    "Number of records returned by repository list calls",
+		Buckets: []float64{0, 1, 5, 10, 25, 50, 100, 250, 500, 1000},
+	}, []string{"method"})
+}
+
+// RecordRepositoryCall counts a repository call, its failure and observes its duration
+func (m *Metrics) RecordRepositoryCall(method string, duration time.Duration, err error) {
+	m.RepositoryCallsTotal.WithLabelValues(method).Inc()
+	m.RepositoryCallDuration.WithLabelValues(method).Observe(duration.Seconds())
+	if err != nil {
+		m.RepositoryErrorsTotal.WithLabelValues(method).Inc()
+	}
+}
+
+// RecordRepositoryResultSize observes the number of records a list call returned
+func (m *Metrics) RecordRepositoryResultSize(method string, size int) {
+	m.RepositoryResultSize.WithLabelValues(method).Observe(float64(size))
+}
@@ -0,0 +1,355 @@
+package telemetry
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Head samplers
+const (
+	HeadSamplerAlways      = "always"
+	HeadSamplerNever       = "never"
+	HeadSamplerRatio       = "ratio"
+	HeadSamplerParentRatio = "parent_ratio"
+)
+
+// Tail sampling decision reasons
+const (
+	tailReasonError      = "error"
+	tailReasonStatus5xx  = "status_5xx"
+	tailReasonSlow       = "slow"
+	tailReasonBaseline   = "baseline"
+	tailReasonSampledOut = "sampled_out"
+)
+
+// NewHeadSampler creates the sampler deciding at span start whether a trace
+// is recorded. parent_ratio follows the decision of a sampled or unsampled
+// parent and applies ratio to new traces.
+func NewHeadSampler(kind string, ratio float64) (sdktrace.Sampler, error) {
+	switch kind {
+	case HeadSamplerAlways, "":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case HeadSamplerNever:
+		return sdktrace.NeverSample(), nil
+	case HeadSamplerRatio:
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	case HeadSamplerParentRatio:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	default:
+		return nil, fmt.Errorf("неизвестный сэмплер: %s", kind)
+	}
+}
+
+// TailSamplingOptions configures the tail sampler
+type TailSamplingOptions struct {
+	LatencyThreshold time.Duration // traces slower than this are kept
+	BaselineRatio    float64       // share of the other traces kept
+	MaxTraces        int           // buffered traces; the oldest is decided early beyond this
+	MaxSpansPerTrace int           // further spans of a trace are dropped
+	DecisionWait     time.Duration // a trace whose root span has not ended by then is decided early
+	DecidedTTL       time.Duration // how long a decision is remembered for late spans of the trace
+}
+
+// tailExpiryInterval is the longest time between expiry checks of idle
+// buffered traces and remembered decisions
+const tailExpiryInterval = time.Second
+
+// initSamplingMetrics creates tail sampling metrics
+func (m *Metrics) initSamplingMetrics() {
+	m.TailSampledTraces = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tail_sampling_traces_total",
+		Help: "Traces decided by the tail sampler",
+	}, []string{"decision", "reason"})
+
+	m.TailDroppedSpans = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tail_sampling_spans_dropped_total",
+		Help: "Spans dropped because their trace reached the span limit",
+	})
+
+	m.TailBufferedTraces = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tail_sampling_buffered_traces",
+		Help: "Traces waiting for a tail sampling decision",
+	})
+
+	m.TailBufferedSpans = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tail_sampling_buffered_spans",
+		Help: "Spans waiting for a tail sampling decision",
+	})
+}
+
+type tailTrace struct {
+	id        trace.TraceID
+	spans     []sdktrace.ReadOnlySpan
+	firstSeen time.Time
+	elem      *list.Element
+}
+
+// tailDecision is a remembered decision of a trace that was already passed on
+type tailDecision struct {
+	id        trace.TraceID
+	keep      bool
+	decidedAt time.Time
+}
+
+// TailSampler is a span processor that buffers finished spans per trace
+// and passes whole traces to next once the local root span ends. Traces
+// with errors, 5xx responses or latency above the threshold are kept,
+// the rest only at the baseline ratio. Spans arriving after their trace
+// was decided follow that decision for DecidedTTL.
+type TailSampler struct {
+	next    sdktrace.SpanProcessor
+	opts    TailSamplingOptions
+	metrics *Metrics
+
+	mu           sync.Mutex
+	rand         *rand.Rand
+	traces       map[trace.TraceID]*tailTrace
+	order        *list.List // traces by arrival of their first span
+	spans        int
+	decided      map[trace.TraceID]*list.Element
+	decidedOrder *list.List // *tailDecision by decision time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+// NewTailSampler creates a tail sampler in front of next. It checks for
+// expired traces in the background until Shutdown, so traces are decided
+// even when no new spans arrive.
+func NewTailSampler(next sdktrace.SpanProcessor, opts TailSamplingOptions, metrics *Metrics) *TailSampler {
+	t := &TailSampler{
+		next:         next,
+		opts:         opts,
+		metrics:      metrics,
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		traces:       make(map[trace.TraceID]*tailTrace),
+		order:        list.New(),
+		decided:      make(map[trace.TraceID]*list.Element),
+		decidedOrder: list.New(),
+		stop:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+
+	interval := tailExpiryInterval
+	if opts.DecisionWait > 0 && opts.DecisionWait/2 < interval {
+		interval = opts.DecisionWait / 2
+	}
+	go t.runExpiry(interval)
+	return t
+}
+
+func (t *TailSampler) runExpiry(interval time.Duration) {
+	defer close(t.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case now := <-ticker.C:
+			t.mu.Lock()
+			kept := t.decideLocked(t.expireLocked(now), now)
+			t.updateGaugesLocked()
+			t.mu.Unlock()
+
+			t.forward(kept)
+		}
+	}
+}
+
+// OnStart does nothing, spans are only looked at when they end
+func (t *TailSampler) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {}
+
+// OnEnd buffers s and forwards the traces decided because of it
+func (t *TailSampler) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+	now := time.Now()
+	id := s.SpanContext().TraceID()
+
+	t.mu.Lock()
+	if keep, ok := t.decisionLocked(id, now); ok {
+		t.mu.Unlock()
+		if keep {
+			t.next.OnEnd(s)
+		}
+		return
+	}
+	tr, ok := t.traces[id]
+	if !ok {
+		tr = &tailTrace{id: id, firstSeen: now}
+		tr.elem = t.order.PushBack(tr)
+		t.traces[id] = tr
+	}
+	if t.opts.MaxSpansPerTrace <= 0 || len(tr.spans) < t.opts.MaxSpansPerTrace {
+		tr.spans = append(tr.spans, s)
+		t.spans++
+	} else {
+		t.metrics.TailDroppedSpans.Inc()
+	}
+
+	var ready []*tailTrace
+	if parent := s.Parent(); !parent.IsValid() || parent.IsRemote() {
+		ready = append(ready, t.removeLocked(tr))
+	}
+	ready = append(ready, t.expireLocked(now)...)
+	kept := t.decideLocked(ready, now)
+	t.updateGaugesLocked()
+	t.mu.Unlock()
+
+	t.forward(kept)
+}
+
+// expireLocked removes traces past the decision wait or beyond the trace
+// limit and forgets decisions older than DecidedTTL
+func (t *TailSampler) expireLocked(now time.Time) []*tailTrace {
+	for e := t.decidedOrder.Front(); e != nil; e = t.decidedOrder.Front() {
+		d := e.Value.(*tailDecision)
+		if now.Sub(d.decidedAt) <= t.opts.DecidedTTL {
+			break
+		}
+		t.forgetLocked(e)
+	}
+
+	var expired []*tailTrace
+	for e := t.order.Front(); e != nil; e = t.order.Front() {
+		tr := e.Value.(*tailTrace)
+		overLimit := t.opts.MaxTraces > 0 && len(t.traces) > t.opts.MaxTraces
+		tooOld := t.opts.DecisionWait > 0 && now.Sub(tr.firstSeen) > t.opts.DecisionWait
+		if !overLimit && !tooOld {
+			break
+		}
+		expired = append(expired, t.removeLocked(tr))
+	}
+	return expired
+}
+
+func (t *TailSampler) removeLocked(tr *tailTrace) *tailTrace {
+	delete(t.traces, tr.id)
+	t.order.Remove(tr.elem)
+	t.spans -= len(tr.spans)
+	return tr
+}
+
+// decideLocked returns the spans of the kept traces and remembers the
+// decisions for late spans
+func (t *TailSampler) decideLocked(traces []*tailTrace, now time.Time) []sdktrace.ReadOnlySpan {
+	var kept []sdktrace.ReadOnlySpan
+	for _, tr := range traces {
+		keep, reason := t.decide(tr.spans)
+		decision := "dropped"
+		if keep {
+			decision = "kept"
+			kept = append(kept, tr.spans...)
+		}
+		t.metrics.TailSampledTraces.WithLabelValues(decision, reason).Inc()
+		t.rememberLocked(tr.id, keep, now)
+	}
+	return kept
+}
+
+// rememberLocked records a decision, keeping at most MaxTraces of them
+func (t *TailSampler) rememberLocked(id trace.TraceID, keep bool, now time.Time) {
+	if t.opts.DecidedTTL <= 0 {
+		return
+	}
+	if e, ok := t.decided[id]; ok {
+		t.forgetLocked(e)
+	}
+	t.decided[id] = t.decidedOrder.PushBack(&tailDecision{id: id, keep: keep, decidedAt: now})
+	if t.opts.MaxTraces > 0 && len(t.decided) > t.opts.MaxTraces {
+		t.forgetLocked(t.decidedOrder.Front())
+	}
+}
+
+// decisionLocked returns the remembered decision of a trace
+func (t *TailSampler) decisionLocked(id trace.TraceID, now time.Time) (keep, ok bool) {
+	e, ok := t.decided[id]
+	if !ok {
+		return false, false
+	}
+	d := e.Value.(*tailDecision)
+	if now.Sub(d.decidedAt) > t.opts.DecidedTTL {
+		t.forgetLocked(e)
+		return false, false
+	}
+	return d.keep, true
+}
+
+func (t *TailSampler) forgetLocked(e *list.Element) {
+	delete(t.decided, e.Value.(*tailDecision).id)
+	t.decidedOrder.Remove(e)
+}
+
+func (t *TailSampler) decide(spans []sdktrace.ReadOnlySpan) (bool, string) {
+	slow := false
+	for _, s := range spans {
+		if s.Status().Code == codes.Error {
+			return true, tailReasonError
+		}
+		for _, kv := range s.Attributes() {
+			if kv.Key == semconv.HTTPResponseStatusCodeKey && kv.Value.AsInt64() >= 500 {
+				return true, tailReasonStatus5xx
+			}
+		}
+		if t.opts.LatencyThreshold > 0 && s.EndTime().Sub(s.StartTime()) > t.opts.LatencyThreshold {
+			slow = true
+		}
+	}
+	if slow {
+		return true, tailReasonSlow
+	}
+	if t.rand.Float64() < t.opts.BaselineRatio {
+		return true, tailReasonBaseline
+	}
+	return false, tailReasonSampledOut
+}
+
+func (t *TailSampler) updateGaugesLocked() {
+	t.metrics.TailBufferedTraces.Set(float64(len(t.traces)))
+	t.metrics.TailBufferedSpans.Set(float64(t.spans))
+}
+
+func (t *TailSampler) forward(spans []sdktrace.ReadOnlySpan) {
+	for _, s := range spans {
+		t.next.OnEnd(s)
+	}
+}
+
+// ForceFlush decides every buffered trace and flushes next
+func (t *TailSampler) ForceFlush(ctx context.Context) error {
+	t.mu.Lock()
+	var all []*tailTrace
+	for e := t.order.Front(); e != nil; e = t.order.Front() {
+		all = append(all, t.removeLocked(e.Value.(*tailTrace)))
+	}
+	kept := t.decideLocked(all, time.Now())
+	t.updateGaugesLocked()
+	t.mu.Unlock()
+
+	t.forward(kept)
+	return t.next.ForceFlush(ctx)
+}
+
+// Shutdown stops the expiry checks, decides buffered traces and shuts next down
+func (t *TailSampler) Shutdown(ctx context.Context) error {
+	t.stopOnce.Do(func() { close(t.stop) })
+	<-t.stopped
+	if err := t.ForceFlush(ctx); err != nil {
+		return err
+	}
+	return t.next.Shutdown(ctx)
+}
@@ -0,0 +1,111 @@
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"employee-management/internal/telemetry"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTailTracer returns a tracer whose spans pass through a tail sampler
+// into a recorder
+func newTailTracer(t *testing.T, opts telemetry.TailSamplingOptions) (trace.Tracer, *tracetest.SpanRecorder, *telemetry.Metrics) {
+	t.Helper()
+	metrics := telemetry.NewMetrics(telemetry.BuildInfo{})
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(telemetry.NewTailSampler(recorder, opts, metrics)))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+	return tp.Tracer("test"), recorder, metrics
+}
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTailSamplerDecidesIdleTraces(t *testing.T) {
+	tracer, recorder, metrics := newTailTracer(t, telemetry.TailSamplingOptions{
+		BaselineRatio: 1,
+		DecisionWait:  50 * time.Millisecond,
+		DecidedTTL:    time.Minute,
+	})
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+
+	// No further spans arrive, the background check must decide the trace
+	waitFor(t, "the idle trace to be decided", func() bool { return len(recorder.Ended()) == 1 })
+	if got := testutil.ToFloat64(metrics.TailBufferedTraces); got != 0 {
+		t.Errorf("buffered traces = %v, want 0", got)
+	}
+	root.End()
+}
+
+func TestTailSamplerLateSpansFollowDecision(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		baseline float64
+		want     int
+	}{
+		{"kept", 1, 2},
+		{"dropped", 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer, recorder, metrics := newTailTracer(t, telemetry.TailSamplingOptions{
+				BaselineRatio: tc.baseline,
+				DecisionWait:  50 * time.Millisecond,
+				DecidedTTL:    time.Minute,
+			})
+
+			ctx, root := tracer.Start(context.Background(), "root")
+			_, child := tracer.Start(ctx, "child")
+			child.End()
+			waitFor(t, "the early decision", func() bool { return testutil.CollectAndCount(metrics.TailSampledTraces) == 1 })
+
+			root.End()
+			if got := len(recorder.Ended()); got != tc.want {
+				t.Errorf("forwarded spans = %d, want %d", got, tc.want)
+			}
+			if got := testutil.CollectAndCount(metrics.TailSampledTraces); got != 1 {
+				t.Errorf("decision series = %d, want the trace decided once", got)
+			}
+			if got := testutil.ToFloat64(metrics.TailBufferedTraces); got != 0 {
+				t.Errorf("buffered traces = %v, want the late span not buffered", got)
+			}
+		})
+	}
+}
+
+func TestTailSamplerForgetsDecisionsAfterTTL(t *testing.T) {
+	tracer, recorder, _ := newTailTracer(t, telemetry.TailSamplingOptions{
+		BaselineRatio: 1,
+		DecisionWait:  20 * time.Millisecond,
+		DecidedTTL:    20 * time.Millisecond,
+	})
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	waitFor(t, "the early decision", func() bool { return len(recorder.Ended()) == 1 })
+	time.Sleep(60 * time.Millisecond)
+
+	// The decision is gone, so the root span is decided as a trace of its own
+	root.End()
+	if got := len(recorder.Ended()); got != 2 {
+		t.Errorf("forwarded spans = %d, want 2", got)
+	}
+}
@@ -0,0 +1,362 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// SLO types
+const (
+	SLOLatency      = "latency"
+	SLOAvailability = "availability"
+)
+
+// SLO is a service level objective over the HTTP metrics. Method and Path
+// narrow it to one route, empty values match every request.
+type SLO struct {
+	Name      string
+	Type      string
+	Method    string
+	Path      string
+	Objective float64       // target ratio of good requests, e.g. 0.995
+	Threshold time.Duration // latency SLOs: a request is good when faster than this
+	Window    time.Duration // compliance and error budget window
+}
+
+// burnRateWindows are the rolling windows exported as slo_burn_rate
+var burnRateWindows = []time.Duration{
+	5 * time.Minute, 30 * time.Minute, time.Hour, 2 * time.Hour, 6 * time.Hour, 24 * time.Hour, 72 * time.Hour,
+}
+
+// burnRateAlerts are multi-window burn-rate alerts: both windows must burn
+// faster than the factor
+var burnRateAlerts = []struct {
+	name        string
+	long, short time.Duration
+	factor      float64
+}{
+	{"page", time.Hour, 5 * time.Minute, 14.4},
+	{"page", 6 * time.Hour, 30 * time.Minute, 6},
+	{"ticket", 24 * time.Hour, 2 * time.Hour, 3},
+	{"ticket", 72 * time.Hour, 6 * time.Hour, 1},
+}
+
+// initSLOMetrics creates SLO metrics
+func (m *Metrics) initSLOMetrics() {
+	m.SLOObjectiveRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "slo_objective_ratio",
+		Help: "Target ratio of good requests",
+	}, []string{"slo"})
+
+	m.SLOComplianceRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "slo_compliance_ratio",
+		Help: "Ratio of good requests over the SLO window",
+	}, []string{"slo"})
+
+	m.SLOErrorBudgetRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "slo_error_budget_remaining_ratio",
+		Help: "Remaining share of the error budget over the SLO window",
+	}, []string{"slo"})
+
+	m.SLOBurnRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "slo_burn_rate",
+		Help: "Error budget burn rate over a rolling window",
+	}, []string{"slo", "window"})
+
+	m.SLOBurnRateAlerting = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "slo_burn_rate_alerting",
+		Help: "1 when a multi-window burn-rate alert fires",
+	}, []string{"slo", "alert"})
+}
+
+// SLOStatus is the current state of one SLO
+type SLOStatus struct {
+	Name                 string             `json:"name"`
+	Type                 string             `json:"type"`
+	Objective            float64            `json:"objective"`
+	Window               string             `json:"window"`
+	Total                float64            `json:"total"`
+	Good                 float64            `json:"good"`
+	Compliance           float64            `json:"compliance"`
+	ErrorBudgetRemaining float64            `json:"error_budget_remaining"`
+	BurnRates            map[string]float64 `json:"burn_rates"`
+	Alerts               []string           `json:"alerts,omitempty"`
+	Met                  bool               `json:"met"`
+}
+
+type sloSample struct {
+	at          time.Time
+	total, good float64
+}
+
+type sloState struct {
+	slo     SLO
+	samples []sloSample // cumulative counts, oldest first
+}
+
+// SLOTracker evaluates SLOs from the HTTP metrics of a registry
+type SLOTracker struct {
+	mu       sync.RWMutex
+	metrics  *Metrics
+	states   []*sloState
+	statuses []SLOStatus
+}
+
+// NewSLOTracker creates a tracker for slos. Counters start at zero with the
+// process, so the history starts with an empty sample.
+func NewSLOTracker(metrics *Metrics, slos []SLO) (*SLOTracker, error) {
+	t := &SLOTracker{metrics: metrics}
+	now := time.Now()
+	for _, slo := range slos {
+		if err := slo.validate(); err != nil {
+			return nil, err
+		}
+		if slo.Type == SLOLatency && !isBucketBound(slo.Threshold.Seconds()) {
+			slog.Warn("Порог SLO не совпадает с границей корзины гистограммы, значение будет интерполировано",
+				"slo", slo.Name, "threshold", slo.Threshold.String())
+		}
+		metrics.SLOObjectiveRatio.WithLabelValues(slo.Name).Set(slo.Objective)
+		t.states = append(t.states, &sloState{slo: slo, samples: []sloSample{{at: now}}})
+	}
+	return t, nil
+}
+
+func (s SLO) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("у SLO должно быть имя")
+	}
+	if s.Objective <= 0 || s.Objective >= 1 {
+		return fmt.Errorf("SLO %s: цель должна быть между 0 и 1", s.Name)
+	}
+	if s.Window <= 0 {
+		return fmt.Errorf("SLO %s: окно должно быть положительным", s.Name)
+	}
+	switch s.Type {
+	case SLOAvailability:
+	case SLOLatency:
+		if s.Threshold <= 0 {
+			return fmt.Errorf("SLO %s: порог задержки должен быть положительным", s.Name)
+		}
+	default:
+		return fmt.Errorf("SLO %s: неизвестный тип %s", s.Name, s.Type)
+	}
+	return nil
+}
+
+// Run evaluates the SLOs now and then every interval until ctx is done
+func (t *SLOTracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	if err := t.Evaluate(time.Now()); err != nil {
+		slog.Error("Ошибка расчета SLO", "error", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := t.Evaluate(now); err != nil {
+				slog.Error("Ошибка расчета SLO", "error", err)
+			}
+		}
+	}
+}
+
+// Evaluate takes a sample of the HTTP metrics at now and updates SLO metrics
+func (t *SLOTracker) Evaluate(now time.Time) error {
+	families, err := t.metrics.Registry.Gather()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, mf := range families {
+		byName[mf.GetName()] = mf
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := make([]SLOStatus, 0, len(t.states))
+	for _, st := range t.states {
+		total, good := st.slo.count(byName)
+		st.samples = append(st.samples, sloSample{at: now, total: total, good: good})
+		st.prune(now)
+		statuses = append(statuses, st.status(now, t.metrics))
+	}
+	t.statuses = statuses
+	return nil
+}
+
+// Statuses returns the result of the last evaluation
+func (t *SLOTracker) Statuses() []SLOStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	statuses := make([]SLOStatus, len(t.statuses))
+	copy(statuses, t.statuses)
+	return statuses
+}
+
+// count returns cumulative total and good requests for the SLO
+func (s SLO) count(families map[string]*dto.MetricFamily) (total, good float64) {
+	switch s.Type {
+	case SLOAvailability:
+		for _, m := range families["http_requests_total"].GetMetric() {
+			labels := labelMap(m)
+			if !s.matches(labels) {
+				continue
+			}
+			v := m.GetCounter().GetValue()
+			total += v
+			if code, err := strconv.Atoi(labels["status"]); err == nil && code < 500 {
+				good += v
+			}
+		}
+	case SLOLatency:
+		threshold := s.Threshold.Seconds()
+		for _, m := range families["http_request_duration_seconds"].GetMetric() {
+			if !s.matches(labelMap(m)) {
+				continue
+			}
+			h := m.GetHistogram()
+			total += float64(h.GetSampleCount())
+			good += countBelow(h, threshold)
+		}
+	}
+	return total, good
+}
+
+func (s SLO) matches(labels map[string]string) bool {
+	return (s.Method == "" || labels["method"] == s.Method) && (s.Path == "" || labels["path"] == s.Path)
+}
+
+// countBelow returns the number of observations not above threshold,
+// interpolating linearly when threshold falls inside a bucket
+func countBelow(h *dto.Histogram, threshold float64) float64 {
+	lower, lowerCount := 0.0, 0.0
+	for _, b := range h.GetBucket() {
+		upper, count := b.GetUpperBound(), float64(b.GetCumulativeCount())
+		if threshold == upper {
+			return count
+		}
+		if threshold < upper {
+			return lowerCount + (count-lowerCount)*(threshold-lower)/(upper-lower)
+		}
+		lower, lowerCount = upper, count
+	}
+	return float64(h.GetSampleCount())
+}
+
+func isBucketBound(v float64) bool {
+	for _, b := range httpDurationBuckets {
+		if b == v {
+			return true
+		}
+	}
+	return false
+}
+
+// prune drops samples that no window needs anymore, keeping one sample at or
+// before the start of the longest window
+func (st *sloState) prune(now time.Time) {
+	longest := st.slo.Window
+	for _, w := range burnRateWindows {
+		if w > longest {
+			longest = w
+		}
+	}
+	cutoff := now.Add(-longest)
+	i := 0
+	for i+1 < len(st.samples) && !st.samples[i+1].at.After(cutoff) {
+		i++
+	}
+	st.samples = st.samples[i:]
+}
+
+// since returns total and good requests during the window ending at now.
+// When the history is shorter than the window, the oldest sample is used.
+func (st *sloState) since(now time.Time, window time.Duration) (total, good float64) {
+	last := st.samples[len(st.samples)-1]
+	start := now.Add(-window)
+	i := sort.Search(len(st.samples), func(i int) bool { return st.samples[i].at.After(start) })
+	if i > 0 {
+		i--
+	}
+	first := st.samples[i]
+	return last.total - first.total, last.good - first.good
+}
+
+func (st *sloState) burnRate(now time.Time, window time.Duration) float64 {
+	total, good := st.since(now, window)
+	if total <= 0 {
+		return 0
+	}
+	return ((total - good) / total) / (1 - st.slo.Objective)
+}
+
+func (st *sloState) status(now time.Time, m *Metrics) SLOStatus {
+	slo := st.slo
+	total, good := st.since(now, slo.Window)
+	status := SLOStatus{
+		Name:       slo.Name,
+		Type:       slo.Type,
+		Objective:  slo.Objective,
+		Window:     windowName(slo.Window),
+		Total:      total,
+		Good:       good,
+		Compliance: 1,
+		BurnRates:  make(map[string]float64),
+	}
+	if total > 0 {
+		status.Compliance = good / total
+	}
+	status.ErrorBudgetRemaining = 1 - (1-status.Compliance)/(1-slo.Objective)
+	status.Met = status.Compliance >= slo.Objective
+
+	m.SLOComplianceRatio.WithLabelValues(slo.Name).Set(status.Compliance)
+	m.SLOErrorBudgetRemaining.WithLabelValues(slo.Name).Set(status.ErrorBudgetRemaining)
+
+	for _, w := range burnRateWindows {
+		rate := st.burnRate(now, w)
+		name := windowName(w)
+		status.BurnRates[name] = rate
+		m.SLOBurnRate.WithLabelValues(slo.Name, name).Set(rate)
+	}
+
+	firing := make(map[string]bool)
+	for _, a := range burnRateAlerts {
+		if st.burnRate(now, a.long) > a.factor && st.burnRate(now, a.short) > a.factor {
+			firing[a.name] = true
+		}
+	}
+	for _, name := range []string{"page", "ticket"} {
+		v := 0.0
+		if firing[name] {
+			v = 1
+			status.Alerts = append(status.Alerts, name)
+		}
+		m.SLOBurnRateAlerting.WithLabelValues(slo.Name, name).Set(v)
+	}
+	return status
+}
+
+// windowName formats a window the way alerting rules usually name them: 5m, 1h, 3d
+func windowName(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
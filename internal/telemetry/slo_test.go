@@ -0,0 +1,146 @@
+package telemetry
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSLOSinceAndPrune(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	st := &sloState{
+		slo: SLO{Objective: 0.99, Window: 24 * time.Hour},
+		samples: []sloSample{
+			{at: at(0)},
+			{at: at(time.Hour), total: 100, good: 90},
+			{at: at(time.Hour + 10*time.Minute), total: 200, good: 190},
+		},
+	}
+	now := at(time.Hour + 10*time.Minute)
+
+	tests := []struct {
+		window      time.Duration
+		total, good float64
+		burnRate    float64
+	}{
+		{5 * time.Minute, 100, 100, 0},  // from the sample before the window
+		{10 * time.Minute, 100, 100, 0}, // a sample on the window start counts
+		{30 * time.Minute, 200, 190, 5},
+		{72 * time.Hour, 200, 190, 5}, // longer than the history
+	}
+	for _, tt := range tests {
+		total, good := st.since(now, tt.window)
+		if total != tt.total || good != tt.good {
+			t.Errorf("since(%v) = %v, %v, want %v, %v", tt.window, total, good, tt.total, tt.good)
+		}
+		if got := st.burnRate(now, tt.window); math.Abs(got-tt.burnRate) > 1e-9 {
+			t.Errorf("burnRate(%v) = %v, want %v", tt.window, got, tt.burnRate)
+		}
+	}
+
+	// The longest window is 72h: one sample at or before its start stays
+	st.prune(at(73*time.Hour + 5*time.Minute))
+	if len(st.samples) != 2 || !st.samples[0].at.Equal(at(time.Hour)) {
+		t.Errorf("samples after prune start at %v, %d left, want the 1h sample and 2 left", st.samples[0].at, len(st.samples))
+	}
+	st.prune(at(200 * time.Hour))
+	if len(st.samples) != 1 {
+		t.Errorf("%d samples after pruning everything, want the last one kept", len(st.samples))
+	}
+}
+
+func TestCountBelowInterpolates(t *testing.T) {
+	h := &dto.Histogram{
+		SampleCount: proto.Uint64(60),
+		Bucket: []*dto.Bucket{
+			{UpperBound: proto.Float64(0.1), CumulativeCount: proto.Uint64(10)},
+			{UpperBound: proto.Float64(0.25), CumulativeCount: proto.Uint64(40)},
+			{UpperBound: proto.Float64(0.5), CumulativeCount: proto.Uint64(50)},
+		},
+	}
+	tests := []struct {
+		threshold, want float64
+	}{
+		{0.05, 5},
+		{0.1, 10},
+		{0.2, 30},
+		{0.5, 50},
+		{1, 60}, // above every bound: all observations
+	}
+	for _, tt := range tests {
+		if got := countBelow(h, tt.threshold); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("countBelow(%v) = %v, want %v", tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestSLOTrackerEvaluate(t *testing.T) {
+	ctx := context.Background()
+	metrics := NewMetrics(BuildInfo{})
+	tracker, err := NewSLOTracker(metrics, []SLO{
+		{Name: "availability", Type: SLOAvailability, Objective: 0.99, Window: 24 * time.Hour},
+		{Name: "latency", Type: SLOLatency, Path: "/api/employees", Objective: 0.9, Threshold: 100 * time.Millisecond, Window: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("NewSLOTracker: %v", err)
+	}
+	record := func(n, status int, path string, duration time.Duration) {
+		for i := 0; i < n; i++ {
+			metrics.RecordHTTPRequest(ctx, http.MethodGet, path, status, duration)
+		}
+	}
+	start := time.Now()
+
+	record(90, http.StatusOK, "/api/employees", 50*time.Millisecond)
+	record(10, http.StatusInternalServerError, "/api/employees", 50*time.Millisecond)
+	record(50, http.StatusNotFound, "/api/departments", 2*time.Second) // 4xx is available
+	if err := tracker.Evaluate(start.Add(time.Hour)); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+
+	statuses := tracker.Statuses()
+	avail, latency := statuses[0], statuses[1]
+	if avail.Total != 150 || avail.Good != 140 || avail.Met {
+		t.Errorf("availability = %v of %v good, met %v, want 140 of 150 and not met", avail.Good, avail.Total, avail.Met)
+	}
+	wantBurn := (10.0 / 150) / 0.01
+	if got := avail.BurnRates["5m"]; math.Abs(got-wantBurn) > 1e-9 {
+		t.Errorf("5m burn rate = %v, want %v", got, wantBurn)
+	}
+	// 6.7 burns faster than the 6h/30m page factor of 6
+	if !slices.Equal(avail.Alerts, []string{"page", "ticket"}) {
+		t.Errorf("alerts = %v, want page and ticket", avail.Alerts)
+	}
+	if got, want := avail.ErrorBudgetRemaining, 1-wantBurn; math.Abs(got-want) > 1e-9 {
+		t.Errorf("error budget remaining = %v, want %v", got, want)
+	}
+	// Only the route of the SLO counts, and its requests were all fast
+	if latency.Total != 100 || latency.Good != 100 || !latency.Met {
+		t.Errorf("latency = %v of %v good, want 100 of 100 and met", latency.Good, latency.Total)
+	}
+
+	// Ten minutes of clean traffic: short windows recover, long ones not yet
+	record(100, http.StatusOK, "/api/employees", 50*time.Millisecond)
+	if err := tracker.Evaluate(start.Add(time.Hour + 10*time.Minute)); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	avail = tracker.Statuses()[0]
+	if got := avail.BurnRates["5m"]; got != 0 {
+		t.Errorf("5m burn rate after clean traffic = %v, want 0", got)
+	}
+	if got, want := avail.BurnRates["30m"], (10.0/250)/0.01; math.Abs(got-want) > 1e-9 {
+		t.Errorf("30m burn rate = %v, want %v", got, want)
+	}
+	// The page needs its short window burning too; the 24h/2h ticket
+	// still fires at 4
+	if !slices.Equal(avail.Alerts, []string{"ticket"}) {
+		t.Errorf("alerts = %v, want ticket only", avail.Alerts)
+	}
+}
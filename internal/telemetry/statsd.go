@@ -0,0 +1,376 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// StatsD flavors
+const (
+	StatsDPlain = "statsd"
+	DogStatsD   = "dogstatsd"
+)
+
+// StatsD metric types
+const (
+	statsdCount     = "c"
+	statsdGauge     = "g"
+	statsdTiming    = "ms"
+	statsdHistogram = "h"
+)
+
+// DefaultStatsDPacketSize keeps a packet within the usual Ethernet MTU
+const DefaultStatsDPacketSize = 1432
+
+// StatsDOptions configures a StatsD emitter
+type StatsDOptions struct {
+	Flavor        string            // statsd or dogstatsd
+	Prefix        string            // prepended to every metric name
+	Tags          map[string]string // added to every metric
+	SampleRate    float64           // share of counter and timing events sent, 0 < rate <= 1
+	MaxPacketSize int
+}
+
+// StatsD aggregates metrics in memory and sends them as StatsD or DogStatsD
+// UDP packets on Flush. Counters are summed and gauges keep the last value;
+// timings and histograms keep every sampled value. Plain StatsD has no tags,
+// so tag values, global ones first in key order, become name segments. A nil
+// *StatsD discards everything.
+type StatsD struct {
+	conn           net.Conn
+	opts           StatsDOptions
+	globalTags     string // DogStatsD tag list of opts.Tags
+	globalSegments string // plain StatsD name segments of opts.Tags
+
+	mu      sync.Mutex
+	rand    *rand.Rand
+	order   []string // aggregate keys in first-seen order
+	metrics map[string]*statsdAggregate
+	failing bool
+	dropped int
+}
+
+type statsdAggregate struct {
+	name   string
+	tags   string
+	typ    string
+	value  float64   // counters and gauges
+	values []float64 // timings and histograms
+}
+
+// NewStatsD creates an emitter sending to the UDP address addr
+func NewStatsD(addr string, opts StatsDOptions) (*StatsD, error) {
+	if opts.Flavor == "" {
+		opts.Flavor = StatsDPlain
+	}
+	if opts.Flavor != StatsDPlain && opts.Flavor != DogStatsD {
+		return nil, fmt.Errorf("неизвестный вариант StatsD: %s", opts.Flavor)
+	}
+	if opts.SampleRate <= 0 || opts.SampleRate > 1 {
+		opts.SampleRate = 1
+	}
+	if opts.MaxPacketSize <= 0 {
+		opts.MaxPacketSize = DefaultStatsDPacketSize
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть UDP сокет StatsD: %w", err)
+	}
+
+	s := &StatsD{
+		conn:    conn,
+		opts:    opts,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		metrics: make(map[string]*statsdAggregate),
+	}
+	if len(opts.Tags) > 0 {
+		keys := make([]string, 0, len(opts.Tags))
+		for k := range opts.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, 2*len(keys))
+		for _, k := range keys {
+			pairs = append(pairs, k, opts.Tags[k])
+		}
+		if opts.Flavor == DogStatsD {
+			s.globalTags = s.dogTags(pairs)
+		} else {
+			s.globalSegments = plainSegments(pairs)
+		}
+	}
+	return s, nil
+}
+
+// Count adds v to a counter. tags are key, value pairs.
+func (s *StatsD) Count(name string, v float64, tags ...string) {
+	if s == nil || !s.sampled() {
+		return
+	}
+	s.record(name, statsdCount, v, tags)
+}
+
+// Gauge sets a gauge. Gauges are never sampled.
+func (s *StatsD) Gauge(name string, v float64, tags ...string) {
+	if s == nil {
+		return
+	}
+	s.record(name, statsdGauge, v, tags)
+}
+
+// Timing records a duration in milliseconds
+func (s *StatsD) Timing(name string, d time.Duration, tags ...string) {
+	if s == nil || !s.sampled() {
+		return
+	}
+	s.record(name, statsdTiming, float64(d)/float64(time.Millisecond), tags)
+}
+
+// Histogram records a value distribution. Plain StatsD has no histogram type,
+// so values are sent as timings.
+func (s *StatsD) Histogram(name string, v float64, tags ...string) {
+	if s == nil || !s.sampled() {
+		return
+	}
+	typ := statsdHistogram
+	if s.opts.Flavor == StatsDPlain {
+		typ = statsdTiming
+	}
+	s.record(name, typ, v, tags)
+}
+
+func (s *StatsD) sampled() bool {
+	if s.opts.SampleRate >= 1 {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rand.Float64() < s.opts.SampleRate
+}
+
+func (s *StatsD) record(name, typ string, v float64, tags []string) {
+	name = s.opts.Prefix + name
+	var tagStr string
+	if s.opts.Flavor == DogStatsD {
+		tagStr = s.dogTags(tags)
+	} else {
+		name += s.globalSegments + plainSegments(tags)
+	}
+	key := name + "|" + typ + "|" + tagStr
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	agg, ok := s.metrics[key]
+	if !ok {
+		agg = &statsdAggregate{name: name, tags: tagStr, typ: typ}
+		s.metrics[key] = agg
+		s.order = append(s.order, key)
+	}
+	switch typ {
+	case statsdCount:
+		agg.value += v
+	case statsdGauge:
+		agg.value = v
+	default:
+		agg.values = append(agg.values, v)
+	}
+}
+
+// plainSegments turns the values of key, value pairs into name segments
+func plainSegments(pairs []string) string {
+	var sb strings.Builder
+	for i := 1; i < len(pairs); i += 2 {
+		sb.WriteByte('.')
+		sb.WriteString(sanitizeStatsDSegment(pairs[i]))
+	}
+	return sb.String()
+}
+
+// dogTags formats key, value pairs as a DogStatsD tag list with the global tags
+func (s *StatsD) dogTags(pairs []string) string {
+	tags := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		tags = append(tags, sanitizeStatsDTag(pairs[i])+":"+sanitizeStatsDTag(pairs[i+1]))
+	}
+	sort.Strings(tags)
+	joined := strings.Join(tags, ",")
+	switch {
+	case s.globalTags == "":
+		return joined
+	case joined == "":
+		return s.globalTags
+	default:
+		return s.globalTags + "," + joined
+	}
+}
+
+// Run flushes every interval until ctx is done. Close sends what is left.
+func (s *StatsD) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Flush()
+		}
+	}
+}
+
+// Flush sends the aggregated metrics in packets of at most MaxPacketSize
+// bytes and resets the aggregation
+func (s *StatsD) Flush() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	order, metrics := s.order, s.metrics
+	s.order, s.metrics = nil, make(map[string]*statsdAggregate, len(metrics))
+	s.mu.Unlock()
+
+	b := statsdBatch{s: s, max: s.opts.MaxPacketSize}
+	for _, key := range order {
+		agg := metrics[key]
+		switch agg.typ {
+		case statsdCount, statsdGauge:
+			b.add(s.line(agg, []float64{agg.value}))
+		default:
+			s.addValues(&b, agg)
+		}
+	}
+	b.send()
+}
+
+// addValues adds timing or histogram values. DogStatsD packs several values
+// into one line; plain StatsD needs a line per value.
+func (s *StatsD) addValues(b *statsdBatch, agg *statsdAggregate) {
+	if s.opts.Flavor == StatsDPlain {
+		for _, v := range agg.values {
+			b.add(s.line(agg, []float64{v}))
+		}
+		return
+	}
+
+	start := 0
+	for start < len(agg.values) {
+		end := start + 1
+		for end < len(agg.values) && len(s.line(agg, agg.values[start:end+1])) <= b.max {
+			end++
+		}
+		b.add(s.line(agg, agg.values[start:end]))
+		start = end
+	}
+}
+
+func (s *StatsD) line(agg *statsdAggregate, values []float64) string {
+	var sb strings.Builder
+	sb.WriteString(agg.name)
+	for _, v := range values {
+		sb.WriteByte(':')
+		sb.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	sb.WriteByte('|')
+	sb.WriteString(agg.typ)
+	if agg.typ != statsdGauge && s.opts.SampleRate < 1 {
+		sb.WriteString("|@")
+		sb.WriteString(strconv.FormatFloat(s.opts.SampleRate, 'f', -1, 64))
+	}
+	if agg.tags != "" {
+		sb.WriteString("|#")
+		sb.WriteString(agg.tags)
+	}
+	return sb.String()
+}
+
+// Close flushes pending metrics and closes the socket
+func (s *StatsD) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.Flush()
+	return s.conn.Close()
+}
+
+// statsdBatch joins lines with newlines into packets no larger than max
+type statsdBatch struct {
+	s   *StatsD
+	max int
+	buf bytes.Buffer
+}
+
+func (b *statsdBatch) add(line string) {
+	if b.buf.Len() > 0 && b.buf.Len()+1+len(line) > b.max {
+		b.send()
+	}
+	if b.buf.Len() > 0 {
+		b.buf.WriteByte('\n')
+	}
+	b.buf.WriteString(line)
+}
+
+func (b *statsdBatch) send() {
+	if b.buf.Len() == 0 {
+		return
+	}
+	_, err := b.s.conn.Write(b.buf.Bytes())
+	b.buf.Reset()
+
+	s := b.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A missing listener fails every flush, so only state changes are logged
+	if err != nil {
+		s.dropped++
+		if !s.failing {
+			slog.Warn("Ошибка отправки метрик StatsD", "error", err)
+		}
+		s.failing = true
+		return
+	}
+	if s.failing {
+		slog.Info("Отправка метрик StatsD восстановлена", "dropped_packets", s.dropped)
+		s.failing = false
+	}
+}
+
+// sanitizeStatsDSegment makes a tag value usable as a plain StatsD name
+// segment. Letters of any script are kept, so Cyrillic positions and
+// departments stay apart.
+func sanitizeStatsDSegment(v string) string {
+	v = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, v)
+	v = strings.Trim(v, "_")
+	if v == "" {
+		return "none"
+	}
+	return v
+}
+
+// sanitizeStatsDTag replaces characters reserved by the DogStatsD protocol
+func sanitizeStatsDTag(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '\n':
+			return '_'
+		}
+		return r
+	}, v)
+}
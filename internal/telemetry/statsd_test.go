@@ -0,0 +1,203 @@
+package telemetry_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"employee-management/internal/telemetry"
+)
+
+// listenStatsD returns an emitter sending to a local UDP listener and a
+// function that closes the emitter and returns the packets it sent
+func listenStatsD(t *testing.T, opts telemetry.StatsDOptions) (*telemetry.StatsD, func() []string) {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	s, err := telemetry.NewStatsD(pc.LocalAddr().String(), opts)
+	if err != nil {
+		t.Fatalf("NewStatsD: %v", err)
+	}
+
+	return s, func() []string {
+		if err := s.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		var packets []string
+		buf := make([]byte, 65536)
+		for {
+			pc.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			n, _, err := pc.ReadFrom(buf)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return packets
+			}
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			packets = append(packets, string(buf[:n]))
+		}
+	}
+}
+
+func lines(packets []string) []string {
+	var out []string
+	for _, p := range packets {
+		out = append(out, strings.Split(p, "\n")...)
+	}
+	return out
+}
+
+func TestStatsDAggregatesBetweenFlushes(t *testing.T) {
+	s, collect := listenStatsD(t, telemetry.StatsDOptions{Prefix: "app."})
+
+	s.Count("requests", 1, "method", "GET")
+	s.Count("requests", 2, "method", "GET")
+	s.Count("requests", 1, "method", "POST")
+	s.Gauge("employees", 4)
+	s.Gauge("employees", 5)
+	s.Timing("latency", 1500*time.Microsecond)
+	s.Timing("latency", 20*time.Millisecond)
+	s.Histogram("tenure", 30)
+
+	got := lines(collect())
+	want := []string{
+		"app.requests.GET:3|c",
+		"app.requests.POST:1|c",
+		"app.employees:5|g",
+		"app.latency:1.5|ms",
+		"app.latency:20|ms",
+		"app.tenure:30|ms", // plain StatsD has no histogram type
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestStatsDSampleRateSuffix(t *testing.T) {
+	s, collect := listenStatsD(t, telemetry.StatsDOptions{SampleRate: 0.5})
+
+	// With 200 events the chance that none is sampled is negligible
+	for i := 0; i < 200; i++ {
+		s.Count("requests", 1)
+	}
+	s.Gauge("employees", 5)
+
+	got := lines(collect())
+	if len(got) != 2 {
+		t.Fatalf("lines = %q, want a counter and a gauge", got)
+	}
+	if !strings.HasPrefix(got[0], "requests:") || !strings.HasSuffix(got[0], "|c|@0.5") {
+		t.Errorf("counter line = %q, want the |@0.5 suffix", got[0])
+	}
+	if got[1] != "employees:5|g" {
+		t.Errorf("gauge line = %q, want it unsampled", got[1])
+	}
+}
+
+func TestDogStatsDTags(t *testing.T) {
+	s, collect := listenStatsD(t, telemetry.StatsDOptions{
+		Flavor: telemetry.DogStatsD,
+		Tags:   map[string]string{"env": "prod", "app": "hr"},
+	})
+
+	s.Count("requests", 1, "path", "/api/employees", "method", "GET")
+	s.Histogram("tenure", 30, "department", "a|b:c")
+	s.Histogram("tenure", 60, "department", "a|b:c")
+	s.Gauge("employees", 5)
+
+	got := lines(collect())
+	want := []string{
+		"requests:1|c|#app:hr,env:prod,method:GET,path:/api/employees",
+		"tenure:30:60|h|#app:hr,env:prod,department:a_b_c",
+		"employees:5|g|#app:hr,env:prod",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestPlainStatsDGlobalTagsBecomeSegments(t *testing.T) {
+	s, collect := listenStatsD(t, telemetry.StatsDOptions{
+		Prefix: "app.",
+		Tags:   map[string]string{"env": "prod", "dc": "msk-1"},
+	})
+
+	s.Count("requests", 1, "method", "GET")
+	s.Gauge("employees", 5)
+
+	got := lines(collect())
+	want := []string{
+		"app.requests.msk-1.prod.GET:1|c",
+		"app.employees.msk-1.prod:5|g",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestStatsDSplitsPacketsAtMaxSize(t *testing.T) {
+	const max = 64
+	s, collect := listenStatsD(t, telemetry.StatsDOptions{Flavor: telemetry.DogStatsD, MaxPacketSize: max})
+
+	var want []string
+	for _, name := range []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf"} {
+		s.Count("counter."+name, 1)
+		want = append(want, "counter."+name+":1|c")
+	}
+	for i := 0; i < 30; i++ {
+		s.Histogram("values", 1000)
+	}
+
+	packets := collect()
+	if len(packets) < 2 {
+		t.Fatalf("packets = %d, want the batch split", len(packets))
+	}
+	for _, p := range packets {
+		if len(p) > max {
+			t.Errorf("packet of %d bytes exceeds %d: %q", len(p), max, p)
+		}
+	}
+
+	got := lines(packets)
+	if !slices.Equal(got[:len(want)], want) {
+		t.Errorf("counter lines = %q, want %q", got[:len(want)], want)
+	}
+	values := 0
+	for _, line := range got[len(want):] {
+		if !strings.HasPrefix(line, "values:") || !strings.HasSuffix(line, "|h") {
+			t.Fatalf("unexpected line %q", line)
+		}
+		values += strings.Count(line, ":1000")
+	}
+	if values != 30 {
+		t.Errorf("histogram values sent = %d, want 30", values)
+	}
+}
+
+func TestPlainStatsDKeepsCyrillicSegments(t *testing.T) {
+	s, collect := listenStatsD(t, telemetry.StatsDOptions{})
+
+	s.Count("employees.hired", 1, "department", "dept1", "position", "Программист")
+	s.Count("employees.hired", 1, "department", "dept1", "position", "Аналитик")
+	s.Count("employees.hired", 1, "department", "dept2", "position", "Менеджер по продажам")
+	s.Count("employees.hired", 1, "department", "dept3", "position", "HR-менеджер")
+
+	got := lines(collect())
+	want := []string{
+		"employees.hired.dept1.Программист:1|c",
+		"employees.hired.dept1.Аналитик:1|c",
+		"employees.hired.dept2.Менеджер_по_продажам:1|c",
+		"employees.hired.dept3.HR-менеджер:1|c",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// SpanRecord is the JSON form of a finished span
+type SpanRecord struct {
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	Name         string         `json:"name"`
+	Kind         string         `json:"kind"`
+	Start        time.Time      `json:"start"`
+	End          time.Time      `json:"end"`
+	DurationMs   float64        `json:"duration_ms"`
+	StatusCode   string         `json:"status_code"`
+	StatusText   string         `json:"status_message,omitempty"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Events       []SpanEvent    `json:"events,omitempty"`
+	Service      string         `json:"service,omitempty"`
+	Scope        string         `json:"scope,omitempty"`
+}
+
+// SpanEvent is the JSON form of a span event
+type SpanEvent struct {
+	Name       string         `json:"name"`
+	Time       time.Time      `json:"time"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// NewSpanRecord converts a finished span
+func NewSpanRecord(span sdktrace.ReadOnlySpan) SpanRecord {
+	sc := span.SpanContext()
+	rec := SpanRecord{
+		TraceID:    sc.TraceID().String(),
+		SpanID:     sc.SpanID().String(),
+		Name:       span.Name(),
+		Kind:       span.SpanKind().String(),
+		Start:      span.StartTime(),
+		End:        span.EndTime(),
+		DurationMs: float64(span.EndTime().Sub(span.StartTime())) / float64(time.Millisecond),
+		StatusCode: span.Status().Code.String(),
+		StatusText: span.Status().Description,
+		Attributes: attributeMap(span.Attributes()),
+		Scope:      span.InstrumentationScope().Name,
+	}
+	if parent := span.Parent(); parent.SpanID().IsValid() {
+		rec.ParentSpanID = parent.SpanID().String()
+	}
+	if res := span.Resource(); res != nil {
+		if v, ok := res.Set().Value("service.name"); ok {
+			rec.Service = v.AsString()
+		}
+	}
+	for _, ev := range span.Events() {
+		rec.Events = append(rec.Events, SpanEvent{
+			Name:       ev.Name,
+			Time:       ev.Time,
+			Attributes: attributeMap(ev.Attributes),
+		})
+	}
+	return rec
+}
+
+func attributeMap(attrs []attribute.KeyValue) map[string]any {
+	if len(attrs) == 0 {
+		return nil
+	}
+	m := make(map[string]any, len(attrs))
+	for _, kv := range attrs {
+		m[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return m
+}
+
+// FileSpanExporter writes spans as JSON lines for offline analysis
+type FileSpanExporter struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+// NewFileSpanExporter creates an exporter writing to w, closing it on shutdown
+func NewFileSpanExporter(w io.WriteCloser) *FileSpanExporter {
+	return &FileSpanExporter{w: w}
+}
+
+// ExportSpans writes one line per span
+func (e *FileSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	buf := bufio.NewWriter(e.w)
+	enc := json.NewEncoder(buf)
+	for _, span := range spans {
+		if err := enc.Encode(NewSpanRecord(span)); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// Shutdown closes the file
+func (e *FileSpanExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.w.Close()
+}
@@ -0,0 +1,280 @@
+package telemetry
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceStore is a span processor keeping the spans of the last traces in
+// memory for the built-in trace viewer
+type TraceStore struct {
+	maxTraces int
+	maxSpans  int
+
+	mu     sync.RWMutex
+	traces map[trace.TraceID]*storedTrace
+	order  *list.List // oldest trace first
+}
+
+type storedTrace struct {
+	id    trace.TraceID
+	spans []SpanRecord
+	root  int // index of the local root span, -1 until it ends
+	elem  *list.Element
+}
+
+// TraceSummary describes one stored trace in listings
+type TraceSummary struct {
+	TraceID    string    `json:"trace_id"`
+	Name       string    `json:"name"`
+	Method     string    `json:"method,omitempty"`
+	Route      string    `json:"route,omitempty"`
+	HTTPStatus int       `json:"http_status,omitempty"`
+	Error      bool      `json:"error"`
+	Start      time.Time `json:"start"`
+	DurationMs float64   `json:"duration_ms"`
+	SpanCount  int       `json:"span_count"`
+	Complete   bool      `json:"complete"` // the root span has ended
+}
+
+// TraceQuery filters stored traces. Status is an exact code ("404"), a
+// class ("5xx") or "error". Zero fields match everything.
+type TraceQuery struct {
+	TraceID     string
+	Route       string // substring of the route or root span name
+	Status      string
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	Limit       int
+}
+
+// SpanNode is a span in a trace tree. OffsetMs is the start relative to
+// the beginning of the trace.
+type SpanNode struct {
+	SpanRecord
+	OffsetMs float64     `json:"offset_ms"`
+	Children []*SpanNode `json:"children,omitempty"`
+}
+
+// TraceTree is a whole trace as a span tree, ready for a waterfall view.
+// Spans whose parent is not stored become additional roots.
+type TraceTree struct {
+	TraceID    string      `json:"trace_id"`
+	Start      time.Time   `json:"start"`
+	DurationMs float64     `json:"duration_ms"`
+	SpanCount  int         `json:"span_count"`
+	Roots      []*SpanNode `json:"roots"`
+}
+
+// NewTraceStore creates a store for up to maxTraces traces of up to
+// maxSpans spans each
+func NewTraceStore(maxTraces, maxSpans int) *TraceStore {
+	return &TraceStore{
+		maxTraces: maxTraces,
+		maxSpans:  maxSpans,
+		traces:    make(map[trace.TraceID]*storedTrace),
+		order:     list.New(),
+	}
+}
+
+// OnStart does nothing, spans are stored when they end
+func (s *TraceStore) OnStart(parent context.Context, span sdktrace.ReadWriteSpan) {}
+
+// OnEnd stores span, evicting the oldest trace when the store is full
+func (s *TraceStore) OnEnd(span sdktrace.ReadOnlySpan) {
+	if !span.SpanContext().IsSampled() {
+		return
+	}
+	rec := NewSpanRecord(span)
+	id := span.SpanContext().TraceID()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.traces[id]
+	if !ok {
+		t = &storedTrace{id: id, root: -1}
+		t.elem = s.order.PushBack(t)
+		s.traces[id] = t
+		for s.maxTraces > 0 && len(s.traces) > s.maxTraces {
+			oldest := s.order.Remove(s.order.Front()).(*storedTrace)
+			delete(s.traces, oldest.id)
+		}
+	}
+	parent := span.Parent()
+	isRoot := !parent.IsValid() || parent.IsRemote()
+	if s.maxSpans > 0 && len(t.spans) >= s.maxSpans {
+		if !isRoot || t.root >= 0 {
+			return
+		}
+		// The root ends last and carries the route and status, so the
+		// span that ended first makes room for it. Children end before
+		// their parents, so no stored span is its child.
+		copy(t.spans, t.spans[1:])
+		t.spans = t.spans[:len(t.spans)-1]
+	}
+	t.spans = append(t.spans, rec)
+	if isRoot {
+		t.root = len(t.spans) - 1
+	}
+}
+
+// ForceFlush does nothing, spans are stored synchronously
+func (s *TraceStore) ForceFlush(ctx context.Context) error { return nil }
+
+// Shutdown does nothing, the store stays readable
+func (s *TraceStore) Shutdown(ctx context.Context) error { return nil }
+
+// List returns summaries of the stored traces matching q, newest first
+func (s *TraceStore) List(q TraceQuery) []TraceSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []TraceSummary
+	for e := s.order.Back(); e != nil; e = e.Prev() {
+		sum := e.Value.(*storedTrace).summary()
+		if !q.matches(sum) {
+			continue
+		}
+		result = append(result, sum)
+		if q.Limit > 0 && len(result) >= q.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// Get returns the span tree of a stored trace
+func (s *TraceStore) Get(traceID string) (*TraceTree, bool) {
+	id, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		return nil, false
+	}
+
+	s.mu.RLock()
+	t, ok := s.traces[id]
+	var spans []SpanRecord
+	if ok {
+		spans = append(spans, t.spans...)
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return buildTraceTree(traceID, spans), true
+}
+
+func (t *storedTrace) summary() TraceSummary {
+	sum := TraceSummary{
+		TraceID:   t.id.String(),
+		SpanCount: len(t.spans),
+		Complete:  t.root >= 0,
+	}
+	start, end := traceBounds(t.spans)
+	sum.Start = start
+	sum.DurationMs = float64(end.Sub(start)) / float64(time.Millisecond)
+
+	for _, span := range t.spans {
+		if span.StatusCode == "Error" {
+			sum.Error = true
+		}
+	}
+	if t.root >= 0 {
+		root := t.spans[t.root]
+		sum.Name = root.Name
+		sum.Method, _ = root.Attributes[string(semconv.HTTPRequestMethodKey)].(string)
+		sum.Route, _ = root.Attributes[string(semconv.HTTPRouteKey)].(string)
+		if code, ok := root.Attributes[string(semconv.HTTPResponseStatusCodeKey)].(int64); ok {
+			sum.HTTPStatus = int(code)
+		}
+	} else if len(t.spans) > 0 {
+		sum.Name = t.spans[0].Name
+	}
+	return sum
+}
+
+func (q TraceQuery) matches(sum TraceSummary) bool {
+	if q.TraceID != "" && !strings.HasPrefix(sum.TraceID, strings.ToLower(q.TraceID)) {
+		return false
+	}
+	if q.Route != "" && !strings.Contains(sum.Route, q.Route) && !strings.Contains(sum.Name, q.Route) {
+		return false
+	}
+	duration := time.Duration(sum.DurationMs * float64(time.Millisecond))
+	if q.MinDuration > 0 && duration < q.MinDuration {
+		return false
+	}
+	if q.MaxDuration > 0 && duration > q.MaxDuration {
+		return false
+	}
+
+	switch status := strings.ToLower(q.Status); {
+	case status == "":
+	case status == "error":
+		return sum.Error
+	case len(status) == 3 && strings.HasSuffix(status, "xx"):
+		return sum.HTTPStatus/100 == int(status[0]-'0')
+	default:
+		code, err := strconv.Atoi(status)
+		return err == nil && sum.HTTPStatus == code
+	}
+	return true
+}
+
+func traceBounds(spans []SpanRecord) (time.Time, time.Time) {
+	var start, end time.Time
+	for i, span := range spans {
+		if i == 0 || span.Start.Before(start) {
+			start = span.Start
+		}
+		if span.End.After(end) {
+			end = span.End
+		}
+	}
+	return start, end
+}
+
+func buildTraceTree(traceID string, spans []SpanRecord) *TraceTree {
+	start, end := traceBounds(spans)
+	tree := &TraceTree{
+		TraceID:    traceID,
+		Start:      start,
+		DurationMs: float64(end.Sub(start)) / float64(time.Millisecond),
+		SpanCount:  len(spans),
+	}
+
+	nodes := make(map[string]*SpanNode, len(spans))
+	for _, span := range spans {
+		nodes[span.SpanID] = &SpanNode{
+			SpanRecord: span,
+			OffsetMs:   float64(span.Start.Sub(start)) / float64(time.Millisecond),
+		}
+	}
+	for _, span := range spans {
+		node := nodes[span.SpanID]
+		if parent, ok := nodes[span.ParentSpanID]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			tree.Roots = append(tree.Roots, node)
+		}
+	}
+
+	sortSpanNodes(tree.Roots)
+	return tree
+}
+
+func sortSpanNodes(nodes []*SpanNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Start.Before(nodes[j].Start) })
+	for _, node := range nodes {
+		sortSpanNodes(node.Children)
+	}
+}
@@ -0,0 +1,51 @@
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+
+	"employee-management/internal/telemetry"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+func TestTraceStoreKeepsRootOfLargeTrace(t *testing.T) {
+	store := telemetry.NewTraceStore(10, 3)
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSpanProcessor(store),
+	)
+	defer tp.Shutdown(context.Background())
+	tracer := tp.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "POST /api/employees/search")
+	root.SetAttributes(semconv.HTTPRoute("/api/employees/search"), semconv.HTTPResponseStatusCode(200))
+	for i := 0; i < 5; i++ {
+		childCtx, child := tracer.Start(ctx, "EmployeeService.SearchEmployees")
+		_, grandchild := tracer.Start(childCtx, "Repository.SearchEmployees")
+		grandchild.End()
+		child.End()
+	}
+	root.End()
+
+	traces := store.List(telemetry.TraceQuery{})
+	if len(traces) != 1 {
+		t.Fatalf("traces = %+v, want one", traces)
+	}
+	sum := traces[0]
+	if !sum.Complete || sum.Route != "/api/employees/search" || sum.HTTPStatus != 200 {
+		t.Errorf("summary = %+v, want the complete trace with its route and status", sum)
+	}
+	if sum.SpanCount != 3 {
+		t.Errorf("span count = %d, want the limit of 3", sum.SpanCount)
+	}
+
+	tree, ok := store.Get(sum.TraceID)
+	if !ok {
+		t.Fatal("stored trace not found")
+	}
+	if tree.Roots[0].Name != "POST /api/employees/search" {
+		t.Errorf("first root = %q, want the request span", tree.Roots[0].Name)
+	}
+}
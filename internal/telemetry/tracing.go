@@ -1,29 +1,200 @@
 package telemetry
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"employee-management/internal/logger"
+
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 )
 
-// InitTracer initializes OpenTelemetry tracer with Jaeger exporter
-func InitTracer(jaegerURL, serviceName string) (*sdktrace.TracerProvider, error) {
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(jaegerURL)))
+// Trace exporters
+const (
+	TraceExporterOTLPGRPC = "otlp-grpc"
+	TraceExporterOTLPHTTP = "otlp-http"
+	TraceExporterStdout   = "stdout"
+	TraceExporterFile     = "file"
+	TraceExporterNone     = "none"
+)
+
+// fallbackCooldown is how long spans go straight to the fallback exporter
+// after the primary one failed
+const fallbackCooldown = 30 * time.Second
+
+// TracingOptions configures the tracer provider
+type TracingOptions struct {
+	ServiceName    string
+	ServiceVersion string
+	Exporter       string
+	Endpoint       string // host:port of the OTLP collector
+	Insecure       bool
+	Headers        map[string]string
+	Timeout        time.Duration
+	Fallback       string // file, stdout or none; used when an OTLP collector is unreachable
+	FileDir        string
+	FileName       string
+	Rotation       logger.RotationOptions
+	Sampler        sdktrace.Sampler     // head sampler, parent-based always sampling when nil
+	TailSampling   *TailSamplingOptions // nil disables tail sampling
+	Metrics        *Metrics             // receives tail sampling metrics
+	Store          *TraceStore          // keeps recent traces for the viewer, may be nil
+}
+
+// InitTracer creates the tracer provider for opts and installs it globally.
+// The returned provider must be shut down on exit to flush buffered spans.
+// With the none exporter spans only reach the trace store.
+func InitTracer(ctx context.Context, opts TracingOptions) (*sdktrace.TracerProvider, error) {
+	providerOpts := []sdktrace.TracerProviderOption{}
+	if opts.Store != nil {
+		providerOpts = append(providerOpts, sdktrace.WithSpanProcessor(opts.Store))
+	}
+	if opts.Exporter != TraceExporterNone {
+		processor, err := newExportProcessor(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		providerOpts = append(providerOpts, sdktrace.WithSpanProcessor(processor))
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+		semconv.ServiceVersion(opts.ServiceVersion),
+	))
 	if err != nil {
 		return nil, err
 	}
 
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exp),
-		sdktrace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceName(serviceName),
-		)),
-	)
+	sampler := opts.Sampler
+	if sampler == nil {
+		sampler = sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+
+	tp := sdktrace.NewTracerProvider(append(providerOpts,
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(res),
+	)...)
 
 	otel.SetTracerProvider(tp)
 	return tp, nil
 }
 
+// newExportProcessor creates the batching processor of the configured
+// exporter, behind the tail sampler when it is enabled
+func newExportProcessor(ctx context.Context, opts TracingOptions) (sdktrace.SpanProcessor, error) {
+	exp, err := newSpanExporter(ctx, opts.Exporter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Exporter == TraceExporterOTLPGRPC || opts.Exporter == TraceExporterOTLPHTTP {
+		if opts.Fallback != "" && opts.Fallback != TraceExporterNone {
+			fallback, err := newSpanExporter(ctx, opts.Fallback, opts)
+			if err != nil {
+				return nil, fmt.Errorf("резервный экспортер трассировки: %w", err)
+			}
+			exp = &fallbackExporter{name: opts.Exporter, primary: exp, fallback: fallback}
+		}
+	}
+
+	var processor sdktrace.SpanProcessor = sdktrace.NewBatchSpanProcessor(exp)
+	if opts.TailSampling != nil {
+		processor = NewTailSampler(processor, *opts.TailSampling, opts.Metrics)
+	}
+	return processor, nil
+}
+
+func newSpanExporter(ctx context.Context, name string, opts TracingOptions) (sdktrace.SpanExporter, error) {
+	switch name {
+	case TraceExporterOTLPGRPC:
+		grpcOpts := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(opts.Endpoint),
+			otlptracegrpc.WithHeaders(opts.Headers),
+			otlptracegrpc.WithTimeout(opts.Timeout),
+			otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{Enabled: false}),
+		}
+		if opts.Insecure {
+			grpcOpts = append(grpcOpts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, grpcOpts...)
+	case TraceExporterOTLPHTTP:
+		httpOpts := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(opts.Endpoint),
+			otlptracehttp.WithHeaders(opts.Headers),
+			otlptracehttp.WithTimeout(opts.Timeout),
+			otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}),
+		}
+		if opts.Insecure {
+			httpOpts = append(httpOpts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, httpOpts...)
+	case TraceExporterStdout:
+		return stdouttrace.New()
+	case TraceExporterFile:
+		file, err := logger.OpenRotatingFile(opts.FileDir, opts.FileName, opts.Rotation)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось открыть файл трассировки: %w", err)
+		}
+		return NewFileSpanExporter(file), nil
+	default:
+		return nil, fmt.Errorf("неизвестный экспортер трассировки: %s", name)
+	}
+}
+
+// fallbackExporter sends spans to the fallback exporter while the primary
+// one fails. Retries are disabled on the primary exporter, so a dead
+// collector costs one timeout per cooldown period.
+type fallbackExporter struct {
+	name     string
+	primary  sdktrace.SpanExporter
+	fallback sdktrace.SpanExporter
+
+	mu         sync.Mutex
+	retryAfter time.Time
+}
+
+func (e *fallbackExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	failing := !e.retryAfter.IsZero()
+	skip := failing && time.Now().Before(e.retryAfter)
+	e.mu.Unlock()
+
+	if !skip {
+		err := e.primary.ExportSpans(ctx, spans)
+		e.mu.Lock()
+		switch {
+		case err == nil && failing:
+			slog.Info("Коллектор трассировки снова доступен", "exporter", e.name)
+			e.retryAfter = time.Time{}
+		case err != nil && !failing:
+			slog.Warn("Коллектор трассировки недоступен, спаны пишутся в резервный экспортер",
+				"exporter", e.name, "error", err)
+		}
+		if err != nil {
+			e.retryAfter = time.Now().Add(fallbackCooldown)
+		}
+		e.mu.Unlock()
+		if err == nil {
+			return nil
+		}
+	}
+	return e.fallback.ExportSpans(ctx, spans)
+}
+
+func (e *fallbackExporter) Shutdown(ctx context.Context) error {
+	primaryErr := e.primary.Shutdown(ctx)
+	if err := e.fallback.Shutdown(ctx); err != nil {
+		return err
+	}
+	return primaryErr
+}
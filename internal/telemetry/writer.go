@@ -0,0 +1,77 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"employee-management/internal/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricsMu       sync.Mutex
+	metricsFile     *logger.RotatingFile
+	metricsFormat   = FormatPrometheus
+	metricsGatherer prometheus.Gatherer
+)
+
+// SetupMetricsWriter sets up the metrics file writer for metrics gathered from gatherer
+func SetupMetricsWriter(gatherer prometheus.Gatherer, metricsDir, metricsFileName string, format MetricsFormat, rotation logger.RotationOptions) (*logger.RotatingFile, error) {
+	file, err := logger.OpenRotatingFile(metricsDir, metricsFileName, rotation)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл метрик: %w", err)
+	}
+	if err := file.SetFraming(DumpFraming(format)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("не удалось открыть файл метрик: %w", err)
+	}
+
+	metricsMu.Lock()
+	metricsFile = file
+	metricsFormat = format
+	metricsGatherer = gatherer
+	metricsMu.Unlock()
+	return file, nil
+}
+
+// WriteMetricsToFile writes current metrics to file
+func WriteMetricsToFile() {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	if metricsFile == nil {
+		return
+	}
+
+	metrics, err := metricsGatherer.Gather()
+	if err != nil {
+		slog.Error("Ошибка сбора метрик", "error", err)
+		return
+	}
+
+	if err := EncodeDump(metricsFile, metricsFormat, metrics, time.Now()); err != nil {
+		slog.Error("Ошибка записи метрик в файл", "error", err)
+		return
+	}
+
+	metricsFile.Sync()
+}
+
+// StartMetricsWriter writes metrics every interval until ctx is done
+func StartMetricsWriter(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			WriteMetricsToFile()
+		}
+	}
+}
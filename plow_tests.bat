@@ -22,18 +22,7 @@ echo  Результаты сохранены в %RESULTS_DIR%/test3_departments
 echo.
 echo  Создание отчета...
 
-echo # Отчет нагрузочного тестирования > "%RESULTS_DIR%/report.md"
-echo. >> "%RESULTS_DIR%/report.md"
-echo "## Результаты тестов" >> "%RESULTS_DIR%/report.md"
-echo. >> "%RESULTS_DIR%/report.md"
-
-for %%f in ("%RESULTS_DIR%/*.log") do (
-    echo "### %%~nf" >> "%RESULTS_DIR%/report.md"
-    echo "\`\`\`" >> "%RESULTS_DIR%/report.md"
-    type "%%f" >> "%RESULTS_DIR%/report.md"
-    echo "\`\`\`" >> "%RESULTS_DIR%/report.md"
-    echo. >> "%RESULTS_DIR%/report.md"
-)
+go run ./cmd/metrics-analyzer -o "%RESULTS_DIR%/report.md" metrics/metrics.log
 
 echo.
 echo  Тестирование завершено!
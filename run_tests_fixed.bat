@@ -22,33 +22,7 @@ echo  Результаты сохранены в %RESULTS_DIR%\test3_departments
 
 echo.
 echo  Анализ результатов...
-echo # Отчет нагрузочного тестирования > "%RESULTS_DIR%\report.md"
-echo. >> "%RESULTS_DIR%\report.md"
-echo "## Результаты тестов" >> "%RESULTS_DIR%\report.md"
-echo. >> "%RESULTS_DIR%\report.md"
-
-if exist "%RESULTS_DIR%\test1_employees.log" (
-    echo "### 1. Сотрудники по департаменту" >> "%RESULTS_DIR%\report.md"
-    echo "\`\`\`text" >> "%RESULTS_DIR%\report.md"
-    type "%RESULTS_DIR%\test1_employees.log" >> "%RESULTS_DIR%\report.md"
-    echo "\`\`\`" >> "%RESULTS_DIR%\report.md"
-    echo. >> "%RESULTS_DIR%\report.md"
-)
-
-if exist "%RESULTS_DIR%\test2_search.log" (
-    echo "### 2. Поиск сотрудников" >> "%RESULTS_DIR%\report.md"
-    echo "\`\`\`text" >> "%RESULTS_DIR%\report.md"
-    type "%RESULTS_DIR%\test2_search.log" >> "%RESULTS_DIR%\report.md"
-    echo "\`\`\`" >> "%RESULTS_DIR%\report.md"
-    echo. >> "%RESULTS_DIR%\report.md"
-)
-
-if exist "%RESULTS_DIR%\test3_departments.log" (
-    echo "### 3. Департаменты" >> "%RESULTS_DIR%\report.md"
-    echo "\`\`\`text" >> "%RESULTS_DIR%\report.md"
-    type "%RESULTS_DIR%\test3_departments.log" >> "%RESULTS_DIR%\report.md"
-    echo "\`\`\`" >> "%RESULTS_DIR%\report.md"
-)
+go run ./cmd/metrics-analyzer -o "%RESULTS_DIR%\report.md" metrics\metrics.log
 
 echo.
 echo  Тестирование завершено!